@@ -2,43 +2,250 @@
 package main
 
 import (
+	"errors"
 	"flag"
 	"fmt"
 	"os"
+	"strings"
 
 	"hufschlaeger.net/gitlab-downloader/internal/adapters/primary/cli"
+	"hufschlaeger.net/gitlab-downloader/internal/adapters/secondary/archive"
+	"hufschlaeger.net/gitlab-downloader/internal/adapters/secondary/cache"
 	"hufschlaeger.net/gitlab-downloader/internal/adapters/secondary/gitlab"
 	"hufschlaeger.net/gitlab-downloader/internal/adapters/secondary/http"
+	"hufschlaeger.net/gitlab-downloader/internal/adapters/secondary/rules"
+	"hufschlaeger.net/gitlab-downloader/internal/core/domain"
+	"hufschlaeger.net/gitlab-downloader/internal/core/ports"
 	"hufschlaeger.net/gitlab-downloader/internal/core/services"
 )
 
+// Exit codes shared by all commands.
+const (
+	exitOK      = 0
+	exitFailure = 1 // the command ran and failed
+	exitUsage   = 2 // unknown command, invalid flags or missing arguments
+)
+
 func main() {
-	// Parse CLI flags
-	config := cli.ParseFlags()
+	args := os.Args[1:]
+	if len(args) == 0 {
+		cli.PrintUsage(os.Stderr)
+		os.Exit(exitUsage)
+	}
+
+	switch args[0] {
+	case "help", "-h", "-help", "--help":
+		cli.PrintUsage(os.Stdout)
+	case "download":
+		runDownload(args[1:])
+	case "releases":
+		runSubcommand(args, "list", runReleasesList)
+	case "release":
+		runSubcommand(args, "show", runReleaseShow)
+	case "assets":
+		runSubcommand(args, "list", runAssetsList)
+	case "artifacts":
+		runArtifacts(args[1:])
+	case "sync":
+		runSync(args[1:])
+	case "verify":
+		runVerify(args[1:])
+	case "install":
+		runInstall(args[1:])
+	case "cache":
+		runCache(args[1:])
+	default:
+		// Flags without a command download, as before commands existed
+		if strings.HasPrefix(args[0], "-") {
+			runDownload(args)
+			return
+		}
+		fmt.Fprintf(os.Stderr, "Error: unknown command %q\n\n", args[0])
+		cli.PrintUsage(os.Stderr)
+		os.Exit(exitUsage)
+	}
+}
+
+// runSubcommand runs a command of a group, e.g. "releases list".
+func runSubcommand(args []string, name string, run func(args []string)) {
+	if len(args) < 2 || args[1] != name {
+		fmt.Fprintf(os.Stderr, "Usage: gitlab-downloader %s %s [flags]\n", args[0], name)
+		os.Exit(exitUsage)
+	}
+	run(args[2:])
+}
 
-	if err := config.Validate(); err != nil {
+// parsed exits after -h or invalid flags, which the flag set has reported.
+func parsed(config *cli.Config, err error) *cli.Config {
+	if errors.Is(err, flag.ErrHelp) {
+		os.Exit(exitOK)
+	}
+	if err != nil {
+		os.Exit(exitUsage)
+	}
+	return config
+}
+
+// validate exits if the flags do not form a valid invocation of command.
+func validate(command string, err error) {
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\nRun \"gitlab-downloader %s -h\" for usage.\n", err, command)
+		os.Exit(exitUsage)
+	}
+}
+
+// check exits if the command failed.
+func check(err error) {
+	if err != nil {
 		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
-		flag.Usage()
-		os.Exit(1)
+		os.Exit(exitFailure)
+	}
+}
+
+func runDownload(args []string) {
+	config := parsed(cli.ParseDownloadFlags(args))
+	validate("download", config.Validate())
+
+	// Primary Adapter (Driver)
+	cliAdapter := cli.NewAdapter(newReleaseService(config))
+
+	// Execute
+	check(cliAdapter.DownloadRelease(config))
+
+	// With -out - stdout carries the download itself
+	switch {
+	case config.Quiet:
+	case config.Output == "-":
+		fmt.Fprintln(os.Stderr, "Download completed successfully")
+	default:
+		fmt.Println("Download completed successfully")
 	}
+}
+
+func runReleasesList(args []string) {
+	config := parsed(cli.ParseReleasesListFlags(args))
+	validate("releases list", config.ValidateQuery())
+
+	check(cli.NewQueryAdapter(newReleaseService(config)).ListReleases(config))
+}
+
+func runReleaseShow(args []string) {
+	config := parsed(cli.ParseReleaseShowFlags(args))
+	validate("release show", config.ValidateQuery())
+
+	check(cli.NewQueryAdapter(newReleaseService(config)).ShowRelease(config))
+}
+
+func runAssetsList(args []string) {
+	config := parsed(cli.ParseAssetsListFlags(args))
+	validate("assets list", config.ValidateQuery())
+
+	check(cli.NewQueryAdapter(newReleaseService(config)).ListAssets(config))
+}
 
+func runArtifacts(args []string) {
+	config := parsed(cli.ParseArtifactsFlags(args))
+	validate("artifacts", config.ValidateArtifacts())
+
+	check(cli.NewArtifactsAdapter(newReleaseService(config)).Download(config))
+
+	switch {
+	case config.Quiet:
+	case config.Output == "-":
+		fmt.Fprintln(os.Stderr, "Download completed successfully")
+	default:
+		fmt.Println("Download completed successfully")
+	}
+}
+
+func runSync(args []string) {
+	config := parsed(cli.ParseSyncFlags(args))
+	validate("sync", config.ValidateSync())
+
+	cliAdapter := cli.NewAdapter(newReleaseService(config))
+
+	check(cliAdapter.Sync(config))
+
+	if !config.Quiet {
+		fmt.Println("Sync completed successfully")
+	}
+}
+
+func runVerify(args []string) {
+	config := parsed(cli.ParseVerifyFlags(args))
+	validate("verify", config.ValidateVerify())
+
+	check(cli.NewVerifyAdapter(newReleaseService(config)).Verify(config))
+}
+
+func runInstall(args []string) {
+	config := parsed(cli.ParseInstallFlags(args))
+	validate("install", config.ValidateInstall())
+
+	installAdapter := cli.NewInstallAdapter(newReleaseService(config))
+
+	check(installAdapter.Install(config))
+}
+
+func runCache(args []string) {
+	if len(args) == 0 || (args[0] != "ls" && args[0] != "prune") {
+		fmt.Fprintln(os.Stderr, "Usage: gitlab-downloader cache <ls|prune> [-cache-dir DIR] [-max-size SIZE]")
+		os.Exit(exitUsage)
+	}
+
+	config := parsed(cli.ParseCacheFlags(args[0], args[1:]))
+	validate("cache "+args[0], config.ValidateCache())
+
+	cacheAdapter := cli.NewCacheAdapter(services.NewCacheService(cache.NewStore(config.CacheDir)))
+
+	if args[0] == "ls" {
+		check(cacheAdapter.List())
+	} else {
+		check(cacheAdapter.Prune(config))
+	}
+}
+
+// newReleaseService wires the secondary adapters into the core service.
+func newReleaseService(config *cli.Config) *services.ReleaseService {
 	// Secondary Adapters (Driven)
 	httpClient := http.NewInsecureClient(config.Proxy)
-	gitlabAdapter := gitlab.NewAdapter(config.GitLabURL, config.Token, httpClient)
-	downloadAdapter := http.NewDownloadAdapter(httpClient)
+	retryPolicy := config.RetryPolicy()
+	gitlabAdapter := gitlab.NewAdapter(config.GitLabURL, config.Token, httpClient).WithRetryPolicy(retryPolicy)
+	downloadAdapter := http.NewDownloadAdapter(httpClient).
+		WithToken(config.GitLabURL, config.Token).
+		WithRetryPolicy(retryPolicy).
+		WithProgress(http.ProgressMode(config.ProgressOutput()))
 	fileAdapter := http.NewFileAdapter()
 
-	// Core Service
-	releaseService := services.NewReleaseService(gitlabAdapter, downloadAdapter, fileAdapter)
-
-	// Primary Adapter (Driver)
-	cliAdapter := cli.NewAdapter(releaseService)
+	// URL resolution rules
+	var resolutionRules []domain.ResolutionRule
+	if config.RulesFile != "" {
+		loaded, err := rules.LoadFile(config.RulesFile)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		resolutionRules = loaded
+	}
 
-	// Execute
-	if err := cliAdapter.DownloadRelease(config); err != nil {
-		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
-		os.Exit(1)
+	// Download cache, also keeping API responses for -offline
+	var gitlabPort ports.GitLabPort = gitlabAdapter
+	var cacheStore ports.CachePort
+	if config.CacheDir != "" {
+		gitlabPort = cache.NewGitLab(gitlabAdapter, config.CacheDir).WithOffline(config.Offline).WithWarnings(os.Stderr)
+		cacheStore = cache.NewStore(config.CacheDir).WithMaxSize(config.CacheMaxSize)
 	}
 
-	fmt.Println("Download completed successfully")
+	// Core Service
+	service := services.NewReleaseService(gitlabPort, downloadAdapter, fileAdapter).
+		WithRules(resolutionRules).
+		WithOffline(config.Offline).
+		WithExtractor(archive.NewExtractor()).
+		WithStreamExtractor(downloadAdapter).
+		WithStdout(os.Stdout).
+		WithWarnings(os.Stderr)
+	if cacheStore != nil {
+		service.WithCache(cacheStore)
+	}
+	return service
 }
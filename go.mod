@@ -2,7 +2,12 @@ module hufschlaeger.net/gitlab-downloader
 
 go 1.23.9
 
-require github.com/schollz/progressbar/v3 v3.19.0
+require (
+	github.com/klauspost/compress v1.18.0
+	github.com/schollz/progressbar/v3 v3.19.0
+	github.com/ulikunitz/xz v0.5.17
+	gopkg.in/yaml.v3 v3.0.1
+)
 
 require (
 	github.com/mitchellh/colorstring v0.0.0-20190213212951-d06e56a500db // indirect
@@ -1,25 +1,154 @@
 package cli
 
 import (
+	"fmt"
+	"io"
+	"os"
+
 	"hufschlaeger.net/gitlab-downloader/internal/core/domain"
 	"hufschlaeger.net/gitlab-downloader/internal/core/ports"
 )
 
 type Adapter struct {
 	service ports.ReleaseDownloadPort
+	out     io.Writer
 }
 
 func NewAdapter(service ports.ReleaseDownloadPort) *Adapter {
-	return &Adapter{service: service}
+	return &Adapter{service: service, out: os.Stdout}
 }
 
 func (a *Adapter) DownloadRelease(config *Config) error {
 	req := domain.DownloadRequest{
-		ProjectName: config.Project,
-		ReleaseTag:  config.Release,
-		OutputPath:  config.Output,
-		ExtIndex:    config.ExtIndex,
+		ProjectName:    config.Project,
+		ReleaseTag:     config.Release,
+		OutputPath:     config.Output,
+		ExtIndex:       config.ExtIndex,
+		All:            config.All,
+		IncludeSources: config.Sources,
+		AssetGlob:      config.Asset,
+		AssetRegex:     config.AssetRegex,
+		SourceFormat:   config.Format,
+		SHA256:         config.SHA256,
+		Continue:       config.Continue,
+		Extract:        extractOptions(config.Extract, config.StripComponents, config.Include, config.Exclude),
+		Overwrite:      config.Overwrite,
+
+		RepositoryArchive: config.RepoArchive,
+		RepositoryPath:    config.RepoPath,
+	}
+
+	if req.All {
+		results, err := a.service.DownloadAllAssets(req)
+		a.printSummary(results)
+		return err
+	}
+
+	result, err := a.service.DownloadRelease(req)
+	if err != nil {
+		return err
+	}
+
+	if result.Skipped {
+		_, _ = fmt.Fprintf(a.out, "Kept existing %s\n", result.Path)
+	}
+	if req.Extract != nil {
+		_, _ = fmt.Fprintf(a.out, "Extracted %d files to %s\n", len(result.Extracted), req.Extract.Dir)
+	}
+	return nil
+}
+
+// extractOptions returns nil without an extraction directory.
+func extractOptions(dir string, strip int, include, exclude []string) *domain.ExtractOptions {
+	if dir == "" {
+		return nil
+	}
+	return &domain.ExtractOptions{Dir: dir, StripComponents: strip, Include: include, Exclude: exclude}
+}
+
+// Sync downloads every entry of the manifest. A failing entry does not stop
+// the remaining ones; an error is returned if any entry failed. With -frozen
+// every entry is pinned to its lockfile entry, with -lock the lockfile is
+// rewritten after a fully successful run.
+func (a *Adapter) Sync(config *Config) error {
+	manifest, err := LoadManifest(config.Manifest)
+	if err != nil {
+		return err
+	}
+
+	var frozen *Lockfile
+	if config.Frozen {
+		if frozen, err = LoadLockfile(lockfilePath(config)); err != nil {
+			return err
+		}
 	}
 
-	return a.service.DownloadRelease(req)
+	lockfile := &Lockfile{}
+	failed := 0
+	for _, entry := range manifest.Downloads {
+		results, err := a.syncEntry(entry, config, frozen)
+		if err != nil {
+			_, _ = fmt.Fprintf(a.out, "FAILED  %s: %v\n", entry, err)
+			failed++
+			continue
+		}
+
+		lockfile.add(entry, results)
+		_, _ = fmt.Fprintf(a.out, "OK      %s -> %s\n", entry, entry.Output)
+	}
+
+	if failed > 0 {
+		return fmt.Errorf("%d of %d entries failed", failed, len(manifest.Downloads))
+	}
+
+	if config.Lock {
+		path := lockfilePath(config)
+		if err := lockfile.Save(path); err != nil {
+			return err
+		}
+		_, _ = fmt.Fprintf(a.out, "Wrote lockfile %s\n", path)
+	}
+	return nil
+}
+
+func (a *Adapter) syncEntry(entry ManifestEntry, config *Config, frozen *Lockfile) ([]domain.AssetResult, error) {
+	req := entry.request(config.Continue)
+	req.Overwrite = config.Overwrite
+
+	if frozen != nil {
+		locked := frozen.find(entry)
+		if locked == nil {
+			return nil, fmt.Errorf("no lockfile entry for %s -> %s", entry.Project, entry.Output)
+		}
+		if err := locked.check(entry); err != nil {
+			return nil, err
+		}
+		req.Lock = locked.lock()
+	}
+
+	if req.All {
+		results, err := a.service.DownloadAllAssets(req)
+		a.printSummary(results)
+		return results, err
+	}
+
+	result, err := a.service.DownloadRelease(req)
+	if err != nil {
+		return nil, err
+	}
+	return []domain.AssetResult{*result}, nil
+}
+
+func (a *Adapter) printSummary(results []domain.AssetResult) {
+	for _, result := range results {
+		if result.Err != nil {
+			_, _ = fmt.Fprintf(a.out, "FAILED  %s: %v\n", result.Name, result.Err)
+			continue
+		}
+		if result.Skipped {
+			_, _ = fmt.Fprintf(a.out, "KEPT    %s -> %s\n", result.Name, result.Path)
+			continue
+		}
+		_, _ = fmt.Fprintf(a.out, "OK      %s -> %s\n", result.Name, result.Path)
+	}
 }
@@ -1,7 +1,11 @@
 package cli
 
 import (
+	"bytes"
 	"errors"
+	"os"
+	"path/filepath"
+	"strings"
 	"testing"
 
 	"hufschlaeger.net/gitlab-downloader/internal/core/domain"
@@ -9,31 +13,52 @@ import (
 )
 
 type mockService struct {
-	received domain.DownloadRequest
+	received []domain.DownloadRequest
+	results  []domain.AssetResult
 	retErr   error
+	failFor  map[string]bool
 }
 
-func (m *mockService) DownloadRelease(req domain.DownloadRequest) error {
-	m.received = req
-	return m.retErr
+func (m *mockService) DownloadRelease(req domain.DownloadRequest) (*domain.AssetResult, error) {
+	m.received = append(m.received, req)
+	if m.failFor[req.ProjectName] {
+		return nil, errors.New("boom")
+	}
+	if m.retErr != nil {
+		return nil, m.retErr
+	}
+	return &domain.AssetResult{
+		ProjectID: 7,
+		Tag:       req.ReleaseTag,
+		URL:       "https://gitlab.example.com/" + req.ProjectName + "/asset",
+		Path:      req.OutputPath,
+		Size:      4,
+		SHA256:    "abcd",
+	}, nil
+}
+
+func (m *mockService) DownloadAllAssets(req domain.DownloadRequest) ([]domain.AssetResult, error) {
+	m.received = append(m.received, req)
+	return m.results, m.retErr
 }
 
 func TestAdapter_DownloadRelease_PassesThroughConfig(t *testing.T) {
 	ms := &mockService{}
 	a := NewAdapter(ms)
 	cfg := &Config{
-		Project:  "group/proj",
-		Release:  "v1.2.3",
-		Output:   "out.zip",
-		ExtIndex: 1,
+		Project:   "group/proj",
+		Release:   "v1.2.3",
+		Output:    "out.zip",
+		ExtIndex:  1,
+		Overwrite: domain.OverwriteNoClobber,
 	}
 
 	if err := a.DownloadRelease(cfg); err != nil {
 		t.Fatalf("unexpected error: %v", err)
 	}
 
-	want := domain.DownloadRequest{ProjectName: "group/proj", ReleaseTag: "v1.2.3", OutputPath: "out.zip", ExtIndex: 1}
-	if ms.received != want {
+	want := domain.DownloadRequest{ProjectName: "group/proj", ReleaseTag: "v1.2.3", OutputPath: "out.zip", ExtIndex: 1, Overwrite: domain.OverwriteNoClobber}
+	if len(ms.received) != 1 || ms.received[0] != want {
 		t.Fatalf("unexpected request: %+v", ms.received)
 	}
 }
@@ -47,5 +72,168 @@ func TestAdapter_DownloadRelease_PropagatesError(t *testing.T) {
 	}
 }
 
+func TestAdapter_DownloadRelease_AllPrintsSummary(t *testing.T) {
+	ms := &mockService{
+		results: []domain.AssetResult{
+			{Name: "app.zip", Path: "dist/app.zip"},
+			{Name: "SHA256SUMS", Err: errors.New("HTTP 404")},
+		},
+		retErr: errors.New("1 of 2 downloads failed"),
+	}
+	var out bytes.Buffer
+	a := NewAdapter(ms)
+	a.out = &out
+
+	err := a.DownloadRelease(&Config{Project: "group/proj", Release: "v1", Output: "dist", All: true, Sources: true})
+	if err == nil {
+		t.Fatalf("expected error to be propagated")
+	}
+	if !ms.received[0].All || !ms.received[0].IncludeSources {
+		t.Fatalf("expected all/sources to be passed through, got %+v", ms.received)
+	}
+	if !strings.Contains(out.String(), "OK      app.zip -> dist/app.zip") || !strings.Contains(out.String(), "FAILED  SHA256SUMS: HTTP 404") {
+		t.Fatalf("unexpected summary: %q", out.String())
+	}
+}
+
+func TestAdapter_Sync_ReportsPerEntry(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "downloads.yaml")
+	content := "downloads:\n" +
+		"  - {project: group/a, release: v1, output: a.zip}\n" +
+		"  - {project: group/b, release: latest, asset: '*.tgz', output: b.tgz}\n" +
+		"  - {project: group/c, release: v3, output: c.zip}\n"
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatalf("setup failed: %v", err)
+	}
+
+	ms := &mockService{failFor: map[string]bool{"group/b": true}}
+	var out bytes.Buffer
+	a := NewAdapter(ms)
+	a.out = &out
+
+	err := a.Sync(&Config{Manifest: path, Continue: true})
+	if err == nil || !strings.Contains(err.Error(), "1 of 3 entries failed") {
+		t.Fatalf("expected summary error, got %v", err)
+	}
+	if len(ms.received) != 3 || ms.received[1].AssetGlob != "*.tgz" || !ms.received[2].Continue {
+		t.Fatalf("unexpected requests: %+v", ms.received)
+	}
+	for _, want := range []string{"OK      group/a@v1 -> a.zip", "FAILED  group/b@latest: boom", "OK      group/c@v3 -> c.zip"} {
+		if !strings.Contains(out.String(), want) {
+			t.Fatalf("expected output to contain %q, got %q", want, out.String())
+		}
+	}
+}
+
+func TestAdapter_Sync_MissingManifest(t *testing.T) {
+	a := NewAdapter(&mockService{})
+	if err := a.Sync(&Config{Manifest: filepath.Join(t.TempDir(), "missing.yaml")}); err == nil {
+		t.Fatalf("expected error for missing manifest")
+	}
+}
+
 // ensure mockService implements the interface
 var _ ports.ReleaseDownloadPort = (*mockService)(nil)
+
+func TestAdapter_Sync_LockAndFrozen(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "downloads.yaml")
+	content := "downloads:\n" +
+		"  - {project: group/a, release: latest, output: a.zip}\n" +
+		"  - {project: group/b, release: v2, output: dist, all: true}\n"
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatalf("setup failed: %v", err)
+	}
+
+	ms := &mockService{results: []domain.AssetResult{{ProjectID: 9, Tag: "v2", Name: "b.tgz", URL: "https://gitlab.example.com/b.tgz", Size: 8, SHA256: "ef01"}}}
+	a := NewAdapter(ms)
+	a.out = &bytes.Buffer{}
+
+	if err := a.Sync(&Config{Manifest: path, Lock: true}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	lockfile, err := LoadLockfile(filepath.Join(dir, "downloads.lock"))
+	if err != nil {
+		t.Fatalf("failed to load lockfile: %v", err)
+	}
+	if len(lockfile.Downloads) != 2 {
+		t.Fatalf("expected 2 lock entries, got %+v", lockfile.Downloads)
+	}
+	first := lockfile.Downloads[0]
+	if first.Project != "group/a" || first.Release != "latest" || first.ProjectID != 7 || first.Tag != "latest" ||
+		len(first.Files) != 1 || first.Files[0].SHA256 != "abcd" || first.Files[0].Size != 4 {
+		t.Fatalf("unexpected lock entry: %+v", first)
+	}
+
+	ms.received = nil
+	if err := a.Sync(&Config{Manifest: path, Frozen: true}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(ms.received) != 2 || ms.received[0].Lock == nil || ms.received[1].Lock == nil {
+		t.Fatalf("expected locked requests, got %+v", ms.received)
+	}
+	if got := ms.received[1].Lock; got.ProjectID != 9 || got.Tag != "v2" || got.File("https://gitlab.example.com/b.tgz") == nil {
+		t.Fatalf("unexpected lock: %+v", got)
+	}
+}
+
+func TestAdapter_Sync_FrozenFailsWithoutLockEntry(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "downloads.yaml")
+	if err := os.WriteFile(path, []byte("downloads:\n  - {project: group/a, release: v1, output: a.zip}\n"), 0o644); err != nil {
+		t.Fatalf("setup failed: %v", err)
+	}
+	lockPath := filepath.Join(dir, "custom.lock")
+	if err := (&Lockfile{}).Save(lockPath); err != nil {
+		t.Fatalf("setup failed: %v", err)
+	}
+
+	ms := &mockService{}
+	var out bytes.Buffer
+	a := NewAdapter(ms)
+	a.out = &out
+
+	if err := a.Sync(&Config{Manifest: path, Frozen: true, LockFile: lockPath}); err == nil {
+		t.Fatalf("expected error")
+	}
+	if len(ms.received) != 0 || !strings.Contains(out.String(), "no lockfile entry for group/a -> a.zip") {
+		t.Fatalf("unexpected result: received=%+v output=%q", ms.received, out.String())
+	}
+}
+
+func TestAdapter_Sync_FrozenFailsOnChangedManifest(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "downloads.yaml")
+	lockfile := &Lockfile{}
+	lockfile.add(ManifestEntry{Project: "group/a", Release: "v1", Asset: "*.zip", Output: "a.zip"}, []domain.AssetResult{{ProjectID: 7, Tag: "v1", URL: "https://gitlab.example.com/a.zip"}})
+	if err := lockfile.Save(filepath.Join(dir, "downloads.lock")); err != nil {
+		t.Fatalf("setup failed: %v", err)
+	}
+
+	for _, tc := range []struct {
+		entry string
+		want  string
+	}{
+		{"{project: group/a, release: v2, asset: '*.zip', output: a.zip}", `release is "v2" in the manifest but "v1"`},
+		{"{project: group/a, release: v1, asset: '*.tgz', output: a.zip}", `asset is "*.tgz" in the manifest but "*.zip"`},
+		{"{project: group/a, release: v1, asset_regex: zip, output: a.zip}", `asset is "" in the manifest`},
+		{"{project: group/a, release: v1, asset: '*.zip', format: zip, output: a.zip}", `format is "zip"`},
+		{"{project: group/a, release: v1, asset: '*.zip', all: true, output: a.zip}", `all is "true"`},
+	} {
+		if err := os.WriteFile(path, []byte("downloads:\n  - "+tc.entry+"\n"), 0o644); err != nil {
+			t.Fatalf("setup failed: %v", err)
+		}
+		ms := &mockService{}
+		var out bytes.Buffer
+		a := NewAdapter(ms)
+		a.out = &out
+
+		if err := a.Sync(&Config{Manifest: path, Frozen: true}); err == nil {
+			t.Fatalf("%s: expected error", tc.entry)
+		}
+		if len(ms.received) != 0 || !strings.Contains(out.String(), "lockfile out of date for group/a -> a.zip") || !strings.Contains(out.String(), tc.want) {
+			t.Fatalf("%s: unexpected result: received=%+v output=%q", tc.entry, ms.received, out.String())
+		}
+	}
+}
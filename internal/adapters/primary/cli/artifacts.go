@@ -0,0 +1,74 @@
+package cli
+
+import (
+	"fmt"
+	"io"
+	"os"
+
+	"hufschlaeger.net/gitlab-downloader/internal/core/domain"
+	"hufschlaeger.net/gitlab-downloader/internal/core/ports"
+)
+
+// ArtifactsAdapter implements "gitlab-downloader artifacts".
+type ArtifactsAdapter struct {
+	service ports.ArtifactsPort
+	out     io.Writer
+}
+
+func NewArtifactsAdapter(service ports.ArtifactsPort) *ArtifactsAdapter {
+	return &ArtifactsAdapter{service: service, out: os.Stdout}
+}
+
+// Download fetches the artifacts of config.Job for config.Ref.
+func (a *ArtifactsAdapter) Download(config *Config) error {
+	req := domain.ArtifactRequest{
+		ProjectName: config.Project,
+		Ref:         config.Ref,
+		Job:         config.Job,
+		Path:        config.ArtifactPath,
+		OutputPath:  config.Output,
+		SHA256:      config.SHA256,
+		Continue:    config.Continue,
+		Extract:     extractOptions(config.Extract, config.StripComponents, config.Include, config.Exclude),
+		Overwrite:   config.Overwrite,
+	}
+
+	result, err := a.service.DownloadArtifacts(req)
+	if err != nil {
+		return err
+	}
+
+	if result.Skipped {
+		_, _ = fmt.Fprintf(a.out, "Kept existing %s\n", result.Path)
+	}
+	if req.Extract != nil {
+		_, _ = fmt.Fprintf(a.out, "Extracted %d files to %s\n", len(result.Extracted), req.Extract.Dir)
+	}
+	return nil
+}
+
+// ParseArtifactsFlags parses the flags of "gitlab-downloader artifacts".
+func ParseArtifactsFlags(args []string) (*Config, error) {
+	config := &Config{}
+
+	fs := newFlagSet("artifacts", "", "Download the artifacts of the latest successful CI job of a branch or tag, e.g. nightly builds without a release.")
+	gitlabURL := bindCommonFlags(fs, config)
+	bindProjectFlags(fs, config)
+	fs.StringVar(&config.Ref, "ref", "", "Branch or tag whose pipeline ran the job (required)")
+	fs.StringVar(&config.Job, "job", "", "Name of the job (required)")
+	fs.StringVar(&config.ArtifactPath, "path", "", "Download only this file of the artifacts archive")
+	fs.StringVar(&config.Output, "out", "", "File, directory or path template to store the artifacts, - for stdout (required unless -extract is given)")
+	fs.StringVar(&config.Output, "o", "", "Path to store the artifacts (short)")
+	fs.StringVar(&config.SHA256, "sha256", "", "Expected SHA-256 of the downloaded file")
+	fs.BoolVar(&config.Continue, "continue", false, "Resume the partial download (<out>.part) of a failed run using HTTP Range requests")
+	bindExtractFlags(fs, config)
+	bindOverwriteFlags(fs, config)
+
+	if err := fs.Parse(args); err != nil {
+		return nil, err
+	}
+
+	config.applyEnv(*gitlabURL)
+
+	return config, nil
+}
@@ -0,0 +1,68 @@
+package cli
+
+import (
+	"bytes"
+	"testing"
+
+	"hufschlaeger.net/gitlab-downloader/internal/core/domain"
+	"hufschlaeger.net/gitlab-downloader/internal/core/ports"
+)
+
+type mockArtifactsService struct {
+	req domain.ArtifactRequest
+}
+
+func (m *mockArtifactsService) DownloadArtifacts(req domain.ArtifactRequest) (*domain.AssetResult, error) {
+	m.req = req
+	return &domain.AssetResult{Name: "artifacts.zip", Tag: req.Ref, Path: req.OutputPath, Extracted: []string{"a", "b"}}, nil
+}
+
+func TestParseArtifactsFlags(t *testing.T) {
+	t.Setenv("GITLAB_TOKEN", "tok")
+
+	cfg, err := ParseArtifactsFlags([]string{"-p", "g/p", "-ref", "main", "-job", "build", "-path", "bin/tool", "-o", "tool", "-no-clobber"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if cfg.Ref != "main" || cfg.Job != "build" || cfg.ArtifactPath != "bin/tool" || cfg.Output != "tool" || cfg.Overwrite != domain.OverwriteNoClobber {
+		t.Fatalf("unexpected config %+v", cfg)
+	}
+	if err := cfg.ValidateArtifacts(); err != nil {
+		t.Fatalf("unexpected validation error: %v", err)
+	}
+
+	cases := []struct {
+		cfg  Config
+		want string
+	}{
+		{Config{Token: "t", Project: "p", GitLabURL: "u", Job: "build", Output: "o"}, "-ref is required"},
+		{Config{Token: "t", Project: "p", GitLabURL: "u", Ref: "main", Output: "o"}, "-job is required"},
+		{Config{Token: "t", Project: "p", GitLabURL: "u", Ref: "main", Job: "build"}, "output path is required"},
+		{Config{Token: "t", Project: "p", GitLabURL: "u", Ref: "main", Job: "build", Output: "-", Extract: "x"}, "-out - cannot be combined"},
+	}
+	for _, tc := range cases {
+		if err := tc.cfg.ValidateArtifacts(); err == nil || !contains(err.Error(), tc.want) {
+			t.Fatalf("expected error containing %q, got %v", tc.want, err)
+		}
+	}
+}
+
+func TestArtifactsAdapter_Download(t *testing.T) {
+	service := &mockArtifactsService{}
+	var out bytes.Buffer
+	a := &ArtifactsAdapter{service: service, out: &out}
+
+	cfg := &Config{Project: "g/p", Ref: "main", Job: "build", Extract: "dist", StripComponents: 1}
+	if err := a.Download(cfg); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if service.req.Job != "build" || service.req.Extract == nil || service.req.Extract.StripComponents != 1 {
+		t.Fatalf("unexpected request %+v", service.req)
+	}
+	if out.String() != "Extracted 2 files to dist\n" {
+		t.Fatalf("unexpected output %q", out.String())
+	}
+}
+
+// ensure the mock implements the interface
+var _ ports.ArtifactsPort = (*mockArtifactsService)(nil)
@@ -0,0 +1,153 @@
+package cli
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+
+	"hufschlaeger.net/gitlab-downloader/internal/core/ports"
+)
+
+// CacheAdapter implements "gitlab-downloader cache ls|prune".
+type CacheAdapter struct {
+	service ports.CacheManagementPort
+	out     io.Writer
+}
+
+func NewCacheAdapter(service ports.CacheManagementPort) *CacheAdapter {
+	return &CacheAdapter{service: service, out: os.Stdout}
+}
+
+// List prints the cached files, most recently used first.
+func (a *CacheAdapter) List() error {
+	entries, err := a.service.ListCache()
+	if err != nil {
+		return err
+	}
+
+	w := tabwriter.NewWriter(a.out, 0, 0, 2, ' ', 0)
+	_, _ = fmt.Fprintln(w, "SIZE\tLAST USED\tSHA256\tURL")
+	seen := make(map[string]bool)
+	var total int64
+	for _, entry := range entries {
+		_, _ = fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", formatSize(entry.Size), entry.LastUsed.Local().Format("2006-01-02 15:04"), shortSHA(entry.SHA256), entry.URL)
+		if !seen[entry.SHA256] {
+			seen[entry.SHA256] = true
+			total += entry.Size
+		}
+	}
+	_ = w.Flush()
+
+	_, _ = fmt.Fprintf(a.out, "%d files, %s\n", len(seen), formatSize(total))
+	return nil
+}
+
+// Prune evicts least recently used files until the cache fits config.CacheMaxSize.
+func (a *CacheAdapter) Prune(config *Config) error {
+	evicted, err := a.service.PruneCache(config.CacheMaxSize)
+	if err != nil {
+		return err
+	}
+
+	var freed int64
+	seen := make(map[string]bool)
+	for _, entry := range evicted {
+		_, _ = fmt.Fprintf(a.out, "REMOVED %s (%s)\n", entry.URL, formatSize(entry.Size))
+		if !seen[entry.SHA256] {
+			seen[entry.SHA256] = true
+			freed += entry.Size
+		}
+	}
+	_, _ = fmt.Fprintf(a.out, "Freed %s\n", formatSize(freed))
+	return nil
+}
+
+// ParseCacheFlags parses the flags of "gitlab-downloader cache <ls|prune>".
+func ParseCacheFlags(command string, args []string) (*Config, error) {
+	config := &Config{}
+
+	description := "List the cached files, most recently used first."
+	if command == "prune" {
+		description = "Evict least recently used files until the cache fits -max-size."
+	}
+	fs := newFlagSet("cache "+command, "", description)
+	fs.StringVar(&config.CacheDir, "cache-dir", "", "Download cache directory (required)")
+	if command == "prune" {
+		config.CacheMaxSize = -1
+		fs.Var((*byteSize)(&config.CacheMaxSize), "max-size", "Evict least recently used files until the cache fits this size, e.g. 500M or 10G (required)")
+	}
+
+	if err := fs.Parse(args); err != nil {
+		return nil, err
+	}
+
+	// Cache-Verzeichnis kann auch aus ENV kommen
+	if config.CacheDir == "" {
+		config.CacheDir = os.Getenv("GITLAB_DOWNLOADER_CACHE")
+	}
+
+	return config, nil
+}
+
+// byteSize is a flag.Value accepting sizes like "500M".
+type byteSize int64
+
+func (b *byteSize) String() string {
+	if b == nil || *b <= 0 {
+		return ""
+	}
+	return formatSize(int64(*b))
+}
+
+func (b *byteSize) Set(value string) error {
+	size, err := parseSize(value)
+	if err != nil {
+		return err
+	}
+	*b = byteSize(size)
+	return nil
+}
+
+// parseSize parses byte sizes like "1024", "500M", "1.5G" or "10GiB" with
+// binary units.
+func parseSize(value string) (int64, error) {
+	s := strings.ToUpper(strings.TrimSpace(value))
+	s = strings.TrimSuffix(strings.TrimSuffix(s, "IB"), "B")
+
+	multiplier := int64(1)
+	if s != "" {
+		if i := strings.IndexByte("KMGT", s[len(s)-1]); i >= 0 {
+			multiplier = int64(1) << (10 * (i + 1))
+			s = s[:len(s)-1]
+		}
+	}
+
+	n, err := strconv.ParseFloat(strings.TrimSpace(s), 64)
+	if err != nil || n < 0 {
+		return 0, fmt.Errorf("invalid size %q", value)
+	}
+	return int64(n * float64(multiplier)), nil
+}
+
+func formatSize(size int64) string {
+	const unit = 1024
+	if size < unit {
+		return fmt.Sprintf("%d B", size)
+	}
+	div, exp := int64(unit), 0
+	for n := size / unit; n >= unit; n /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %ciB", float64(size)/float64(div), "KMGTPE"[exp])
+}
+
+func shortSHA(sha256 string) string {
+	if len(sha256) > 12 {
+		return sha256[:12]
+	}
+	return sha256
+}
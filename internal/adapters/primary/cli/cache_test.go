@@ -0,0 +1,103 @@
+package cli
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"hufschlaeger.net/gitlab-downloader/internal/core/domain"
+	"hufschlaeger.net/gitlab-downloader/internal/core/ports"
+)
+
+type mockCacheService struct {
+	entries []domain.CacheEntry
+	maxSize int64
+}
+
+func (m *mockCacheService) ListCache() ([]domain.CacheEntry, error) {
+	return m.entries, nil
+}
+
+func (m *mockCacheService) PruneCache(maxSize int64) ([]domain.CacheEntry, error) {
+	m.maxSize = maxSize
+	return m.entries, nil
+}
+
+func TestParseSize(t *testing.T) {
+	for input, want := range map[string]int64{
+		"0":     0,
+		"1024":  1024,
+		"500M":  500 << 20,
+		"1.5G":  3 << 29,
+		"10GiB": 10 << 30,
+		"2kb":   2048,
+		"1T":    1 << 40,
+	} {
+		got, err := parseSize(input)
+		if err != nil || got != want {
+			t.Fatalf("parseSize(%q) = %d, %v; want %d", input, got, err, want)
+		}
+	}
+	for _, input := range []string{"", "G", "-1", "10X"} {
+		if _, err := parseSize(input); err == nil {
+			t.Fatalf("expected error for %q", input)
+		}
+	}
+}
+
+func TestParseCacheFlags(t *testing.T) {
+	t.Setenv("GITLAB_DOWNLOADER_CACHE", "/env/cache")
+
+	cfg, err := ParseCacheFlags("prune", []string{"-max-size", "1G"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if cfg.CacheDir != "/env/cache" || cfg.CacheMaxSize != 1<<30 {
+		t.Fatalf("unexpected config: %+v", cfg)
+	}
+	if err := cfg.ValidateCache(); err != nil {
+		t.Fatalf("unexpected validation error: %v", err)
+	}
+
+	cfg, _ = ParseCacheFlags("prune", []string{"-cache-dir", "/flag/cache"})
+	if err := cfg.ValidateCache(); err == nil || !contains(err.Error(), "-max-size is required") {
+		t.Fatalf("expected missing -max-size error, got %v", err)
+	}
+
+	cfg, _ = ParseCacheFlags("ls", []string{"-cache-dir", "/flag/cache"})
+	if cfg.CacheDir != "/flag/cache" || cfg.ValidateCache() != nil {
+		t.Fatalf("unexpected ls config: %+v", cfg)
+	}
+}
+
+func TestCacheAdapter_ListAndPrune(t *testing.T) {
+	sha := strings.Repeat("ab", 32)
+	ms := &mockCacheService{entries: []domain.CacheEntry{
+		{URL: "https://example.com/a.zip", SHA256: sha, Size: 3 << 20, LastUsed: time.Now()},
+		{URL: "https://mirror.example.com/a.zip", SHA256: sha, Size: 3 << 20, LastUsed: time.Now()},
+	}}
+	var out bytes.Buffer
+	a := NewCacheAdapter(ms)
+	a.out = &out
+
+	if err := a.List(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	for _, want := range []string{"3.0 MiB", "abababababab", "https://mirror.example.com/a.zip", "1 files, 3.0 MiB"} {
+		if !strings.Contains(out.String(), want) {
+			t.Fatalf("expected listing to contain %q, got %q", want, out.String())
+		}
+	}
+
+	out.Reset()
+	if err := a.Prune(&Config{CacheMaxSize: 1 << 20}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if ms.maxSize != 1<<20 || !strings.Contains(out.String(), "REMOVED https://example.com/a.zip") || !strings.Contains(out.String(), "Freed 3.0 MiB") {
+		t.Fatalf("unexpected prune output %q", out.String())
+	}
+}
+
+// ensure mockCacheService implements the interface
+var _ ports.CacheManagementPort = (*mockCacheService)(nil)
@@ -0,0 +1,52 @@
+package cli
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"text/tabwriter"
+)
+
+// Commands lists the commands of gitlab-downloader for the help output.
+var Commands = []struct {
+	Name        string
+	Description string
+}{
+	{"download", "Download a release asset or source archive (default without a command)"},
+	{"releases list", "List the releases of a project"},
+	{"release show", "Show a release with its assets and sources"},
+	{"assets list", "List the files of a release with their download URLs"},
+	{"artifacts", "Download the CI job artifacts of a branch or tag"},
+	{"sync", "Download every entry of a manifest file"},
+	{"verify", "Check local files against the checksums published in a release"},
+	{"install", "Install the executable of a release built for this platform"},
+	{"cache ls|prune", "List or prune the download cache"},
+}
+
+// PrintUsage writes the overview of all commands.
+func PrintUsage(w io.Writer) {
+	_, _ = fmt.Fprintln(w, "Usage: gitlab-downloader <command> [flags]")
+	_, _ = fmt.Fprintln(w, "       gitlab-downloader [download flags]")
+	_, _ = fmt.Fprintln(w)
+	_, _ = fmt.Fprintln(w, "Commands:")
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	for _, command := range Commands {
+		_, _ = fmt.Fprintf(tw, "  %s\t%s\n", command.Name, command.Description)
+	}
+	_ = tw.Flush()
+	_, _ = fmt.Fprintln(w)
+	_, _ = fmt.Fprintln(w, "Run \"gitlab-downloader <command> -h\" for the flags of a command.")
+}
+
+// newFlagSet returns the flag set of a command. Its help shows the usage
+// line, with operands describing positional arguments, and the description
+// before the flags.
+func newFlagSet(command, operands, description string) *flag.FlagSet {
+	fs := flag.NewFlagSet(command, flag.ContinueOnError)
+	fs.Usage = func() {
+		out := fs.Output()
+		_, _ = fmt.Fprintf(out, "Usage: gitlab-downloader %s [flags]%s\n\n%s\n\nFlags:\n", command, operands, description)
+		fs.PrintDefaults()
+	}
+	return fs
+}
@@ -5,6 +5,12 @@ import (
 	"flag"
 	"fmt"
 	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"hufschlaeger.net/gitlab-downloader/internal/adapters/secondary/retry"
+	"hufschlaeger.net/gitlab-downloader/internal/core/domain"
 )
 
 const (
@@ -12,50 +18,223 @@ const (
 )
 
 type Config struct {
-	GitLabURL string
-	Token     string
-	Proxy     string
-	ExtIndex  int
-	Output    string
-	Release   string
-	Project   string
+	GitLabURL  string
+	Token      string
+	Proxy      string
+	ExtIndex   int
+	Output     string
+	Release    string
+	Project    string
+	All        bool
+	Sources    bool
+	Asset      string
+	AssetRegex string
+	Format     string
+	SHA256     string
+	Continue   bool
+	Retries    int
+	RetryDelay time.Duration
+	RulesFile  string
+	Manifest   string
+	Lock       bool
+	Frozen     bool
+	LockFile   string
+	// Download cache; CacheMaxSize 0 means unlimited
+	CacheDir     string
+	CacheMaxSize int64
+	Offline      bool
+	// Archive extraction
+	Extract         string
+	StripComponents int
+	Include         stringList
+	Exclude         stringList
+	// Output of progress and status messages
+	Quiet    bool
+	Progress string
+	// Overwrite is empty unless one of the overwrite policy flags is given
+	Overwrite domain.OverwritePolicy
+	// Repository archive of tags without a release
+	RepoArchive domain.RepositoryArchiveMode
+	RepoPath    string
+	// CI job artifacts
+	Ref          string
+	Job          string
+	ArtifactPath string
+	// Files are the arguments of verify
+	Files []string
+	// Release listing and display; OutputFormat is table, json or plain
+	// for releases list and text or json for release show
+	Filter       domain.ReleaseFilter
+	OutputFormat string
+	Template     string
+	// Binary installation
+	BinDir  string
+	BinName string
+	OS      string
+	Arch    string
 }
 
-func ParseFlags() *Config {
+// ParseDownloadFlags parses the flags of "gitlab-downloader download", which
+// are also accepted without the command name.
+func ParseDownloadFlags(args []string) (*Config, error) {
 	config := &Config{}
 
+	fs := newFlagSet("download", "", "Download a release asset or source archive.")
+	gitlabURL := bindCommonFlags(fs, config)
+	fs.IntVar(&config.ExtIndex, "ext", 0, "Source extension index (0=zip, 1=tar.gz, 2=tar.bz2, 3=tar)")
+	fs.StringVar(&config.Output, "out", "", "File, directory or path template to store the release, - for stdout (required unless -extract is given)")
+	fs.StringVar(&config.Output, "o", "", "Path to store the release (short)")
+	bindReleaseFlags(fs, config, "")
+	fs.BoolVar(&config.All, "all", false, "Download every asset of the release into the -out directory")
+	fs.BoolVar(&config.Sources, "sources", false, "With -all, also download the release's source archives")
+	bindAssetFlags(fs, config)
+	fs.StringVar(&config.SHA256, "sha256", "", "Expected SHA-256 of the downloaded file (overrides published checksums)")
+	fs.BoolVar(&config.Continue, "continue", false, "Resume the partial download (<out>.part) of a failed run using HTTP Range requests")
+	bindExtractFlags(fs, config)
+	bindOverwriteFlags(fs, config)
+	config.RepoArchive = domain.RepositoryArchiveAuto
+	fs.Func("repo-archive", "Download the repository archive of the tag: auto (if the tag has no release), always or never (default auto)", func(value string) error {
+		switch mode := domain.RepositoryArchiveMode(value); mode {
+		case domain.RepositoryArchiveAuto, domain.RepositoryArchiveAlways, domain.RepositoryArchiveNever:
+			config.RepoArchive = mode
+			return nil
+		}
+		return fmt.Errorf("must be auto, always or never")
+	})
+	fs.StringVar(&config.RepoPath, "repo-path", "", "Only include this repository directory in repository archives")
+
+	if err := fs.Parse(args); err != nil {
+		return nil, err
+	}
+
+	config.applyEnv(*gitlabURL)
+
+	return config, nil
+}
+
+// bindExtractFlags registers the flags unpacking a downloaded archive.
+func bindExtractFlags(fs *flag.FlagSet, config *Config) {
+	fs.StringVar(&config.Extract, "extract", "", "Unpack the downloaded archive (zip, tar, tar.gz, tar.bz2, tar.xz, tar.zst) into this directory")
+	fs.IntVar(&config.StripComponents, "strip-components", 0, "With -extract, remove this many leading path elements from every entry")
+	fs.Var(&config.Include, "include", "With -extract, only unpack entries matching this glob (repeatable)")
+	fs.Var(&config.Exclude, "exclude", "With -extract, skip entries matching this glob (repeatable)")
+}
+
+// ParseSyncFlags parses the flags of "gitlab-downloader sync".
+func ParseSyncFlags(args []string) (*Config, error) {
+	config := &Config{}
+
+	fs := newFlagSet("sync", "", "Download every entry of a manifest file.")
+	gitlabURL := bindCommonFlags(fs, config)
+	fs.StringVar(&config.Manifest, "file", "", "Manifest file listing the downloads (required)")
+	fs.StringVar(&config.Manifest, "f", "", "Manifest file listing the downloads (short)")
+	fs.BoolVar(&config.Continue, "continue", false, "Resume the partial downloads (<out>.part) of a failed run using HTTP Range requests")
+	fs.BoolVar(&config.Lock, "lock", false, "Write resolved tags, URLs, sizes and SHA-256 digests to the lockfile")
+	fs.BoolVar(&config.Frozen, "frozen", false, "Only download what the lockfile records; fail on any difference")
+	fs.StringVar(&config.LockFile, "lockfile", "", "Lockfile path (default: manifest path with .lock extension)")
+	bindOverwriteFlags(fs, config)
+
+	if err := fs.Parse(args); err != nil {
+		return nil, err
+	}
+
+	config.applyEnv(*gitlabURL)
+
+	return config, nil
+}
+
+// bindCommonFlags registers the connection flags shared by all commands and
+// returns the raw -gitlab-url value for resolveGitLabURL.
+func bindCommonFlags(fs *flag.FlagSet, config *Config) *string {
 	// GitLab URL - Priority: CLI flag -> ENV -> Default
-	gitlabURL := flag.String("gitlab-url", "", "GitLab instance URL")
-	flag.StringVar(&config.Token, "token", "", "Your private GitLab token (required)")
-	flag.StringVar(&config.Token, "t", "", "Your private GitLab token (short)")
-	flag.StringVar(&config.Proxy, "proxy", "", "Proxy URL")
-	flag.IntVar(&config.ExtIndex, "ext", 0, "Source extension index (0=zip, 1=tar.gz, 2=tar.bz2, 3=tar)")
-	flag.StringVar(&config.Output, "out", "", "Path to store the release (required)")
-	flag.StringVar(&config.Output, "o", "", "Path to store the release (short)")
-	flag.StringVar(&config.Release, "release", "", "Version string of release (required)")
-	flag.StringVar(&config.Release, "r", "", "Version string of release (short)")
-	flag.StringVar(&config.Project, "project", "", "Project name with namespace/group (required)")
-	flag.StringVar(&config.Project, "p", "", "Project name with namespace/group (short)")
-
-	flag.Parse()
+	gitlabURL := fs.String("gitlab-url", "", "GitLab instance URL")
+	fs.StringVar(&config.Token, "token", "", "Your private GitLab token (required)")
+	fs.StringVar(&config.Token, "t", "", "Your private GitLab token (short)")
+	fs.StringVar(&config.Proxy, "proxy", "", "Proxy URL")
+	fs.IntVar(&config.Retries, "retries", retry.DefaultMaxRetries, "Number of retries for connection errors and 5xx/429 responses")
+	fs.DurationVar(&config.RetryDelay, "retry-delay", retry.DefaultBaseDelay, "Initial delay between retries, doubled on every attempt")
+	fs.StringVar(&config.RulesFile, "rules", "", "YAML or JSON file with project-specific URL resolution rules")
+	fs.StringVar(&config.CacheDir, "cache-dir", "", "Directory for a local download cache shared between runs")
+	fs.Var((*byteSize)(&config.CacheMaxSize), "cache-max-size", "Evict least recently used cache files above this size, e.g. 10G")
+	fs.BoolVar(&config.Offline, "offline", false, "Resolve projects, releases and files from the cache only, without network access")
+	fs.BoolVar(&config.Quiet, "quiet", false, "Print no progress and no success messages, only results and errors")
+	fs.Func("progress", "Progress output on stderr: auto (bar on a terminal, plain lines otherwise), bar, plain, json or none", func(value string) error {
+		switch value {
+		case "auto", "bar", "plain", "json", "none":
+			config.Progress = value
+			return nil
+		}
+		return fmt.Errorf("must be auto, bar, plain, json or none")
+	})
+	return gitlabURL
+}
+
+// bindReleaseFlags registers the flags selecting project and release. An
+// empty release default makes -release required.
+func bindReleaseFlags(fs *flag.FlagSet, config *Config, release string) {
+	usage := "Release tag, \"latest\", \"latest-stable\" or a semver constraint like \"^2.3\""
+	if release == "" {
+		usage += " (required)"
+	}
+	fs.StringVar(&config.Release, "release", release, usage)
+	fs.StringVar(&config.Release, "r", release, "Release tag or constraint (short)")
+	bindProjectFlags(fs, config)
+}
+
+func bindProjectFlags(fs *flag.FlagSet, config *Config) {
+	fs.StringVar(&config.Project, "project", "", "Project name with namespace/group (required)")
+	fs.StringVar(&config.Project, "p", "", "Project name with namespace/group (short)")
+}
+
+// bindAssetFlags registers the flags selecting assets by name or format.
+func bindAssetFlags(fs *flag.FlagSet, config *Config) {
+	fs.StringVar(&config.Asset, "asset", "", "Glob matched against asset link names and URL file names (e.g. '*linux-amd64.tar.gz')")
+	fs.StringVar(&config.AssetRegex, "asset-regex", "", "Regular expression matched against asset link names and URL file names")
+	fs.StringVar(&config.Format, "format", "", "Source archive format to download (zip, tar.gz, tar.bz2, tar)")
+}
+
+// bindOverwriteFlags registers the mutually exclusive flags choosing what
+// happens to existing output files.
+func bindOverwriteFlags(fs *flag.FlagSet, config *Config) {
+	for _, f := range []struct {
+		policy domain.OverwritePolicy
+		usage  string
+	}{
+		{domain.OverwriteForce, "Replace existing output files (default)"},
+		{domain.OverwriteNoClobber, "Keep existing output files and skip their download"},
+		{domain.OverwriteBackup, "Rename existing output files to .bak (or .~N~) before replacing them"},
+		{domain.OverwriteSkipIfSame, "Skip the download if the output file matches the remote checksum or cached ETag"},
+	} {
+		fs.Var(overwriteFlag{target: &config.Overwrite, policy: f.policy}, string(f.policy), f.usage)
+	}
+}
 
+func (c *Config) applyEnv(gitlabURL string) {
 	// Resolve GitLab URL: CLI flag -> ENV -> Default
-	config.GitLabURL = resolveGitLabURL(*gitlabURL)
+	c.GitLabURL = resolveGitLabURL(gitlabURL)
 
 	// Token kann auch aus ENV kommen, wenn nicht via Flag gesetzt
-	if config.Token == "" {
-		config.Token = os.Getenv("GITLAB_TOKEN")
+	if c.Token == "" {
+		c.Token = os.Getenv("GITLAB_TOKEN")
+	}
+
+	// Regeldatei kann auch aus ENV kommen
+	if c.RulesFile == "" {
+		c.RulesFile = os.Getenv("GITLAB_DOWNLOADER_RULES")
+	}
+
+	// Cache-Verzeichnis kann auch aus ENV kommen
+	if c.CacheDir == "" {
+		c.CacheDir = os.Getenv("GITLAB_DOWNLOADER_CACHE")
 	}
 
 	// Proxy kann auch aus ENV kommen
-	if config.Proxy == "" {
-		config.Proxy = os.Getenv("HTTPS_PROXY")
-		if config.Proxy == "" {
-			config.Proxy = os.Getenv("HTTP_PROXY")
+	if c.Proxy == "" {
+		c.Proxy = os.Getenv("HTTPS_PROXY")
+		if c.Proxy == "" {
+			c.Proxy = os.Getenv("HTTP_PROXY")
 		}
 	}
-
-	return config
 }
 
 func resolveGitLabURL(flagValue string) string {
@@ -74,10 +253,13 @@ func resolveGitLabURL(flagValue string) string {
 }
 
 func (c *Config) Validate() error {
-	if c.Token == "" {
+	if c.Token == "" && !c.Offline {
 		return fmt.Errorf("token is required (use -token flag or GITLAB_TOKEN env)")
 	}
-	if c.Output == "" {
+	if c.Offline && c.CacheDir == "" {
+		return fmt.Errorf("-offline requires a cache directory (use -cache-dir flag or GITLAB_DOWNLOADER_CACHE env)")
+	}
+	if c.Output == "" && c.Extract == "" {
 		return fmt.Errorf("output path is required")
 	}
 	if c.Release == "" {
@@ -89,5 +271,245 @@ func (c *Config) Validate() error {
 	if c.GitLabURL == "" {
 		return fmt.Errorf("GitLab URL is required")
 	}
+	if c.Retries < 0 {
+		return fmt.Errorf("-retries must not be negative")
+	}
+	if err := c.validateOutput(); err != nil {
+		return err
+	}
+	if c.RepoPath != "" && c.RepoArchive == domain.RepositoryArchiveNever {
+		return fmt.Errorf("-repo-path cannot be combined with -repo-archive never")
+	}
+	return nil
+}
+
+// stringList is a repeatable string flag.
+type stringList []string
+
+func (l *stringList) String() string {
+	return strings.Join(*l, ",")
+}
+
+func (l *stringList) Set(value string) error {
+	*l = append(*l, value)
+	return nil
+}
+
+// overwriteFlag is a boolean flag selecting an overwrite policy. Selecting
+// two different policies is an error.
+type overwriteFlag struct {
+	target *domain.OverwritePolicy
+	policy domain.OverwritePolicy
+}
+
+func (f overwriteFlag) IsBoolFlag() bool {
+	return true
+}
+
+func (f overwriteFlag) String() string {
+	if f.target == nil || *f.target != f.policy {
+		return "false"
+	}
+	return "true"
+}
+
+func (f overwriteFlag) Set(value string) error {
+	on, err := strconv.ParseBool(value)
+	if err != nil || !on {
+		return err
+	}
+	if *f.target != "" && *f.target != f.policy {
+		return fmt.Errorf("cannot be combined with -%s", *f.target)
+	}
+	*f.target = f.policy
+	return nil
+}
+
+func isHex(s string, length int) bool {
+	if len(s) != length {
+		return false
+	}
+	for _, r := range s {
+		if !strings.ContainsRune("0123456789abcdefABCDEF", r) {
+			return false
+		}
+	}
+	return true
+}
+
+// validateOutput checks the flags storing, verifying and extracting a
+// download.
+func (c *Config) validateOutput() error {
+	if c.SHA256 != "" && c.All {
+		return fmt.Errorf("-sha256 cannot be combined with -all")
+	}
+	if c.SHA256 != "" && !isHex(c.SHA256, 64) {
+		return fmt.Errorf("-sha256 must be 64 hex characters")
+	}
+	if c.Extract != "" && c.All {
+		return fmt.Errorf("-extract cannot be combined with -all")
+	}
+	if c.Extract == "" && (c.StripComponents != 0 || len(c.Include) > 0 || len(c.Exclude) > 0) {
+		return fmt.Errorf("-strip-components, -include and -exclude require -extract")
+	}
+	if c.StripComponents < 0 {
+		return fmt.Errorf("-strip-components must not be negative")
+	}
+	if c.Output == "-" && (c.All || c.Extract != "" || c.Continue || c.Offline) {
+		return fmt.Errorf("-out - cannot be combined with -all, -extract, -continue or -offline")
+	}
+	// Without -out the archive is extracted from the stream and never stored
+	if c.Output == "" && (c.SHA256 != "" || c.Continue) {
+		return fmt.Errorf("-sha256 and -continue require -out")
+	}
 	return nil
 }
+
+// ValidateSync checks the configuration of the sync command.
+func (c *Config) ValidateSync() error {
+	if c.Token == "" && !c.Offline {
+		return fmt.Errorf("token is required (use -token flag or GITLAB_TOKEN env)")
+	}
+	if c.Offline && c.CacheDir == "" {
+		return fmt.Errorf("-offline requires a cache directory (use -cache-dir flag or GITLAB_DOWNLOADER_CACHE env)")
+	}
+	if c.Manifest == "" {
+		return fmt.Errorf("manifest file is required (use -f)")
+	}
+	if c.Lock && c.Frozen {
+		return fmt.Errorf("-lock cannot be combined with -frozen")
+	}
+	if c.GitLabURL == "" {
+		return fmt.Errorf("GitLab URL is required")
+	}
+	if c.Retries < 0 {
+		return fmt.Errorf("-retries must not be negative")
+	}
+	return nil
+}
+
+// ValidateInstall checks the configuration of the install command.
+func (c *Config) ValidateInstall() error {
+	if c.Token == "" && !c.Offline {
+		return fmt.Errorf("token is required (use -token flag or GITLAB_TOKEN env)")
+	}
+	if c.Offline && c.CacheDir == "" {
+		return fmt.Errorf("-offline requires a cache directory (use -cache-dir flag or GITLAB_DOWNLOADER_CACHE env)")
+	}
+	if c.Release == "" {
+		return fmt.Errorf("release version is required")
+	}
+	if c.Project == "" {
+		return fmt.Errorf("project name is required")
+	}
+	if c.BinDir == "" {
+		return fmt.Errorf("-bin-dir must not be empty")
+	}
+	if c.OS == "" || c.Arch == "" {
+		return fmt.Errorf("-os and -arch must not be empty")
+	}
+	if c.GitLabURL == "" {
+		return fmt.Errorf("GitLab URL is required")
+	}
+	if c.Retries < 0 {
+		return fmt.Errorf("-retries must not be negative")
+	}
+	if c.SHA256 != "" && !isHex(c.SHA256, 64) {
+		return fmt.Errorf("-sha256 must be 64 hex characters")
+	}
+	return nil
+}
+
+// ValidateQuery checks the configuration of the releases, release and
+// assets commands.
+func (c *Config) ValidateQuery() error {
+	if c.Token == "" && !c.Offline {
+		return fmt.Errorf("token is required (use -token flag or GITLAB_TOKEN env)")
+	}
+	if c.Offline && c.CacheDir == "" {
+		return fmt.Errorf("-offline requires a cache directory (use -cache-dir flag or GITLAB_DOWNLOADER_CACHE env)")
+	}
+	if c.Project == "" {
+		return fmt.Errorf("project name is required")
+	}
+	if c.GitLabURL == "" {
+		return fmt.Errorf("GitLab URL is required")
+	}
+	if c.Retries < 0 {
+		return fmt.Errorf("-retries must not be negative")
+	}
+	if c.Filter.Limit < 0 {
+		return fmt.Errorf("-limit must not be negative")
+	}
+	return nil
+}
+
+// ValidateVerify checks the configuration of the verify command.
+func (c *Config) ValidateVerify() error {
+	if err := c.ValidateQuery(); err != nil {
+		return err
+	}
+	if c.Release == "" {
+		return fmt.Errorf("release version is required")
+	}
+	if len(c.Files) == 0 {
+		return fmt.Errorf("at least one file is required")
+	}
+	if c.SHA256 != "" && len(c.Files) > 1 {
+		return fmt.Errorf("-sha256 requires a single file")
+	}
+	if c.SHA256 != "" && !isHex(c.SHA256, 64) {
+		return fmt.Errorf("-sha256 must be 64 hex characters")
+	}
+	return nil
+}
+
+// ValidateArtifacts checks the configuration of the artifacts command.
+func (c *Config) ValidateArtifacts() error {
+	if err := c.ValidateQuery(); err != nil {
+		return err
+	}
+	if c.Ref == "" {
+		return fmt.Errorf("-ref is required")
+	}
+	if c.Job == "" {
+		return fmt.Errorf("-job is required")
+	}
+	if c.Output == "" && c.Extract == "" {
+		return fmt.Errorf("output path is required")
+	}
+	return c.validateOutput()
+}
+
+// ValidateCache checks the configuration of the cache commands.
+func (c *Config) ValidateCache() error {
+	if c.CacheDir == "" {
+		return fmt.Errorf("cache directory is required (use -cache-dir flag or GITLAB_DOWNLOADER_CACHE env)")
+	}
+	if c.CacheMaxSize < 0 {
+		return fmt.Errorf("-max-size is required")
+	}
+	return nil
+}
+
+// ProgressOutput returns the progress mode: an explicit -progress, else
+// none with -quiet, else auto.
+func (c *Config) ProgressOutput() string {
+	switch {
+	case c.Progress != "":
+		return c.Progress
+	case c.Quiet:
+		return "none"
+	default:
+		return "auto"
+	}
+}
+
+// RetryPolicy returns the retry policy configured by -retries and -retry-delay.
+func (c *Config) RetryPolicy() retry.Policy {
+	return retry.Policy{
+		MaxRetries: c.Retries,
+		BaseDelay:  c.RetryDelay,
+		MaxDelay:   retry.DefaultMaxDelay,
+	}
+}
@@ -1,19 +1,17 @@
 package cli
 
 import (
-	"flag"
 	"os"
+	"strings"
 	"testing"
+
+	"hufschlaeger.net/gitlab-downloader/internal/core/domain"
 )
 
-// helper to run ParseFlags with custom args and cleaned env
+// helper to run ParseDownloadFlags with custom args and cleaned env
 func runParseFlags(t *testing.T, args []string, env map[string]string) *Config {
 	t.Helper()
 
-	oldArgs := os.Args
-	os.Args = append([]string{"cmd"}, args...)
-	defer func() { os.Args = oldArgs }()
-
 	// snapshot env and restore after
 	oldEnv := make(map[string]string)
 	for k := range env {
@@ -36,11 +34,11 @@ func runParseFlags(t *testing.T, args []string, env map[string]string) *Config {
 		}
 	}()
 
-	// flag package keeps global state across tests; reset
-	flag.CommandLine = flag.NewFlagSet(os.Args[0], flag.ContinueOnError)
-	flag.CommandLine.SetOutput(nil)
-
-	return ParseFlags()
+	cfg, err := ParseDownloadFlags(args)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	return cfg
 }
 
 func TestResolveGitLabURL_Precedence(t *testing.T) {
@@ -144,6 +142,8 @@ func TestValidateErrors(t *testing.T) {
 		{Config{Token: "t", Output: "o"}, "release version is required"},
 		{Config{Token: "t", Output: "o", Release: "r"}, "project name is required"},
 		{Config{Token: "t", Output: "o", Release: "r", Project: "p"}, "GitLab URL is required"},
+		{Config{Token: "t", Output: "o", Release: "r", Project: "p", GitLabURL: "u", SHA256: "abc"}, "-sha256 must be 64 hex characters"},
+		{Config{Token: "t", Output: "o", Release: "r", Project: "p", GitLabURL: "u", SHA256: "abc", All: true}, "-sha256 cannot be combined with -all"},
 	}
 	for _, tc := range cases {
 		err := tc.cfg.Validate()
@@ -170,3 +170,148 @@ func contains(s, sub string) bool {
 		return false
 	})())
 }
+
+func TestParseSyncFlags(t *testing.T) {
+	cfg, err := ParseSyncFlags([]string{"-f", "downloads.yaml", "-t", "tok", "-continue", "-gitlab-url", "https://x"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if cfg.Manifest != "downloads.yaml" || cfg.Token != "tok" || !cfg.Continue || cfg.GitLabURL != "https://x" {
+		t.Fatalf("unexpected config: %+v", cfg)
+	}
+	if err := cfg.ValidateSync(); err != nil {
+		t.Fatalf("unexpected validation error: %v", err)
+	}
+
+	if err := (&Config{Token: "t", GitLabURL: "u", Manifest: "m", Lock: true, Frozen: true}).ValidateSync(); err == nil || !contains(err.Error(), "cannot be combined") {
+		t.Fatalf("expected -lock/-frozen conflict, got %v", err)
+	}
+	if err := (&Config{Token: "t", GitLabURL: "u"}).ValidateSync(); err == nil || !contains(err.Error(), "manifest file is required") {
+		t.Fatalf("expected manifest error, got %v", err)
+	}
+}
+
+func TestValidateOffline(t *testing.T) {
+	base := Config{Output: "o", Release: "r", Project: "p", GitLabURL: "u", Offline: true}
+
+	cfg := base
+	if err := cfg.Validate(); err == nil || !contains(err.Error(), "-offline requires a cache directory") {
+		t.Fatalf("expected missing cache directory error, got %v", err)
+	}
+
+	cfg.CacheDir = "/cache"
+	if err := cfg.Validate(); err != nil {
+		t.Fatalf("expected offline config without token to be valid, got %v", err)
+	}
+}
+
+func TestValidateExtract(t *testing.T) {
+	base := Config{Token: "t", Output: "o", Release: "r", Project: "p", GitLabURL: "u"}
+
+	for _, tc := range []struct {
+		name string
+		edit func(*Config)
+		want string
+	}{
+		{"with all", func(c *Config) { c.Extract = "dir"; c.All = true }, "-extract cannot be combined with -all"},
+		{"options without extract", func(c *Config) { c.Include = stringList{"config"} }, "require -extract"},
+		{"negative strip", func(c *Config) { c.Extract = "dir"; c.StripComponents = -1 }, "must not be negative"},
+	} {
+		cfg := base
+		tc.edit(&cfg)
+		if err := cfg.Validate(); err == nil || !contains(err.Error(), tc.want) {
+			t.Fatalf("%s: expected %q, got %v", tc.name, tc.want, err)
+		}
+	}
+}
+
+func TestExtractFlags(t *testing.T) {
+	cfg := runParseFlags(t, []string{"-t", "tok", "-p", "g/p", "-r", "v1", "-o", "a.tgz", "-extract", "dist", "--strip-components", "1", "-include", "config", "-include", "*.md", "-exclude", "*.bak"}, nil)
+	if cfg.Extract != "dist" || cfg.StripComponents != 1 || len(cfg.Include) != 2 || cfg.Include[1] != "*.md" || len(cfg.Exclude) != 1 {
+		t.Fatalf("unexpected config: %+v", cfg)
+	}
+}
+
+func TestValidateStdout(t *testing.T) {
+	cfg := Config{Token: "t", Output: "-", Release: "r", Project: "p", GitLabURL: "u", SHA256: strings.Repeat("a", 64)}
+	if err := cfg.Validate(); err != nil {
+		t.Fatalf("expected -out - to be valid, got %v", err)
+	}
+
+	cfg.SHA256, cfg.Continue = "", true
+	if err := cfg.Validate(); err == nil || !contains(err.Error(), "-out - cannot be combined") {
+		t.Fatalf("expected -continue to be rejected, got %v", err)
+	}
+}
+
+func TestValidateExtractWithoutOutput(t *testing.T) {
+	cfg := Config{Token: "t", Release: "r", Project: "p", GitLabURL: "u", Extract: "dir"}
+	if err := cfg.Validate(); err != nil {
+		t.Fatalf("expected -extract without -out to be valid, got %v", err)
+	}
+
+	cfg.Continue = true
+	if err := cfg.Validate(); err == nil || !contains(err.Error(), "require -out") {
+		t.Fatalf("expected -continue to require -out, got %v", err)
+	}
+}
+
+func TestOverwriteFlags(t *testing.T) {
+	cfg := runParseFlags(t, []string{"-t", "tok", "-p", "g/p", "-r", "v1", "-o", "a.tgz", "-backup"}, nil)
+	if cfg.Overwrite != domain.OverwriteBackup {
+		t.Fatalf("expected backup policy, got %q", cfg.Overwrite)
+	}
+
+	cfg, err := ParseSyncFlags([]string{"-f", "m.yaml", "-skip-if-same", "-skip-if-same=true", "-force=false"})
+	if err != nil || cfg.Overwrite != domain.OverwriteSkipIfSame {
+		t.Fatalf("expected skip-if-same policy, got %q, %v", cfg.Overwrite, err)
+	}
+
+	if _, err := ParseSyncFlags([]string{"-f", "m.yaml", "-no-clobber", "-force"}); err == nil || !contains(err.Error(), "cannot be combined with -no-clobber") {
+		t.Fatalf("expected conflict error, got %v", err)
+	}
+}
+
+func TestProgressFlags(t *testing.T) {
+	cfg, err := ParseSyncFlags([]string{"-f", "m.yaml", "-quiet"})
+	if err != nil || !cfg.Quiet || cfg.ProgressOutput() != "none" {
+		t.Fatalf("expected quiet without progress, got %+v, %v", cfg, err)
+	}
+
+	cfg, err = ParseSyncFlags([]string{"-f", "m.yaml", "-quiet", "-progress=json"})
+	if err != nil || cfg.ProgressOutput() != "json" {
+		t.Fatalf("expected explicit JSON progress, got %+v, %v", cfg, err)
+	}
+
+	if cfg := (&Config{}); cfg.ProgressOutput() != "auto" {
+		t.Fatalf("expected auto progress by default, got %s", cfg.ProgressOutput())
+	}
+
+	if _, err := ParseSyncFlags([]string{"-f", "m.yaml", "-progress", "fancy"}); err == nil || !contains(err.Error(), "must be auto, bar, plain, json or none") {
+		t.Fatalf("expected invalid progress error, got %v", err)
+	}
+}
+
+func TestRepoArchiveFlags(t *testing.T) {
+	cfg := runParseFlags(t, []string{"-t", "tok", "-p", "g/p", "-r", "v0.9", "-o", "src.zip"}, nil)
+	if cfg.RepoArchive != domain.RepositoryArchiveAuto {
+		t.Fatalf("expected auto by default, got %q", cfg.RepoArchive)
+	}
+
+	cfg = runParseFlags(t, []string{"-t", "tok", "-p", "g/p", "-r", "main", "-o", "docs.zip", "-repo-archive", "always", "-repo-path", "docs"}, nil)
+	if cfg.RepoArchive != domain.RepositoryArchiveAlways || cfg.RepoPath != "docs" {
+		t.Fatalf("unexpected config %+v", cfg)
+	}
+	if err := cfg.Validate(); err != nil {
+		t.Fatalf("unexpected validation error: %v", err)
+	}
+
+	cfg.RepoArchive = domain.RepositoryArchiveNever
+	if err := cfg.Validate(); err == nil || !contains(err.Error(), "-repo-path cannot be combined with -repo-archive never") {
+		t.Fatalf("expected repo path error, got %v", err)
+	}
+
+	if _, err := ParseDownloadFlags([]string{"-repo-archive", "sometimes"}); err == nil || !contains(err.Error(), "must be auto, always or never") {
+		t.Fatalf("expected invalid mode error, got %v", err)
+	}
+}
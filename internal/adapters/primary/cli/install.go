@@ -0,0 +1,66 @@
+package cli
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"runtime"
+
+	"hufschlaeger.net/gitlab-downloader/internal/core/domain"
+	"hufschlaeger.net/gitlab-downloader/internal/core/ports"
+)
+
+// DefaultBinDir is where "gitlab-downloader install" places executables.
+const DefaultBinDir = "/usr/local/bin"
+
+// InstallAdapter implements "gitlab-downloader install".
+type InstallAdapter struct {
+	service ports.InstallPort
+	out     io.Writer
+}
+
+func NewInstallAdapter(service ports.InstallPort) *InstallAdapter {
+	return &InstallAdapter{service: service, out: os.Stdout}
+}
+
+func (a *InstallAdapter) Install(config *Config) error {
+	result, err := a.service.InstallBinary(domain.InstallRequest{
+		ProjectName: config.Project,
+		ReleaseTag:  config.Release,
+		BinDir:      config.BinDir,
+		Name:        config.BinName,
+		OS:          config.OS,
+		Arch:        config.Arch,
+		AssetGlob:   config.Asset,
+		SHA256:      config.SHA256,
+	})
+	if err != nil {
+		return err
+	}
+
+	_, _ = fmt.Fprintf(a.out, "Installed %s from %s %s\n", result.Path, result.Name, result.Tag)
+	return nil
+}
+
+// ParseInstallFlags parses the flags of "gitlab-downloader install".
+func ParseInstallFlags(args []string) (*Config, error) {
+	config := &Config{}
+
+	fs := newFlagSet("install", "", "Install the executable of a release built for this platform.")
+	gitlabURL := bindCommonFlags(fs, config)
+	bindReleaseFlags(fs, config, "")
+	fs.StringVar(&config.BinDir, "bin-dir", DefaultBinDir, "Directory to install the executable into")
+	fs.StringVar(&config.BinName, "name", "", "Name of the executable (default: last element of the project path)")
+	fs.StringVar(&config.OS, "os", runtime.GOOS, "Operating system to select the asset for")
+	fs.StringVar(&config.Arch, "arch", runtime.GOARCH, "Architecture to select the asset for")
+	fs.StringVar(&config.Asset, "asset", "", "Glob selecting the asset by name instead of by -os and -arch")
+	fs.StringVar(&config.SHA256, "sha256", "", "Expected SHA-256 of the asset (overrides published checksums)")
+
+	if err := fs.Parse(args); err != nil {
+		return nil, err
+	}
+
+	config.applyEnv(*gitlabURL)
+
+	return config, nil
+}
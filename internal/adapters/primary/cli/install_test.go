@@ -0,0 +1,61 @@
+package cli
+
+import (
+	"bytes"
+	"runtime"
+	"strings"
+	"testing"
+
+	"hufschlaeger.net/gitlab-downloader/internal/core/domain"
+	"hufschlaeger.net/gitlab-downloader/internal/core/ports"
+)
+
+type mockInstallService struct {
+	req domain.InstallRequest
+}
+
+func (m *mockInstallService) InstallBinary(req domain.InstallRequest) (*domain.AssetResult, error) {
+	m.req = req
+	return &domain.AssetResult{Name: "tool_Linux_x86_64.tar.gz", Tag: "v1.2.0", Path: req.BinDir + "/tool"}, nil
+}
+
+func TestParseInstallFlags(t *testing.T) {
+	t.Setenv("GITLAB_TOKEN", "tok")
+
+	cfg, err := ParseInstallFlags([]string{"-p", "group/tool", "-r", "latest-stable"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if cfg.BinDir != DefaultBinDir || cfg.OS != runtime.GOOS || cfg.Arch != runtime.GOARCH || cfg.Token != "tok" {
+		t.Fatalf("unexpected defaults: %+v", cfg)
+	}
+	if err := cfg.ValidateInstall(); err != nil {
+		t.Fatalf("unexpected validation error: %v", err)
+	}
+
+	cfg.Project = ""
+	if err := cfg.ValidateInstall(); err == nil || !strings.Contains(err.Error(), "project name is required") {
+		t.Fatalf("expected missing project error, got %v", err)
+	}
+}
+
+func TestInstallAdapter_Install(t *testing.T) {
+	service := &mockInstallService{}
+	var out bytes.Buffer
+	a := &InstallAdapter{service: service, out: &out}
+
+	cfg := &Config{Project: "group/tool", Release: "v1.2.0", BinDir: "/opt/bin", BinName: "tl", OS: "linux", Arch: "amd64", Asset: "*musl*"}
+	if err := a.Install(cfg); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want := domain.InstallRequest{ProjectName: "group/tool", ReleaseTag: "v1.2.0", BinDir: "/opt/bin", Name: "tl", OS: "linux", Arch: "amd64", AssetGlob: "*musl*"}
+	if service.req != want {
+		t.Fatalf("unexpected request %+v", service.req)
+	}
+	if !strings.Contains(out.String(), "Installed /opt/bin/tool from tool_Linux_x86_64.tar.gz v1.2.0") {
+		t.Fatalf("unexpected output %q", out.String())
+	}
+}
+
+// ensure the mock implements the interface
+var _ ports.InstallPort = (*mockInstallService)(nil)
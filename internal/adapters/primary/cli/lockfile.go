@@ -0,0 +1,140 @@
+package cli
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+
+	"hufschlaeger.net/gitlab-downloader/internal/core/domain"
+)
+
+const lockfileHeader = "# Generated by gitlab-downloader sync -lock. Do not edit.\n"
+
+// Lockfile records what each manifest entry resolved to, so that a -frozen
+// sync downloads exactly the same files again.
+type Lockfile struct {
+	Downloads []LockEntry `yaml:"downloads"`
+}
+
+// LockEntry is the resolved state of one manifest entry, identified by its
+// project and output path. The release and asset selection of the entry are
+// kept to detect manifest changes the lock no longer reflects.
+type LockEntry struct {
+	Project    string       `yaml:"project"`
+	Release    string       `yaml:"release"`
+	Asset      string       `yaml:"asset,omitempty"`
+	AssetRegex string       `yaml:"asset_regex,omitempty"`
+	Format     string       `yaml:"format,omitempty"`
+	All        bool         `yaml:"all,omitempty"`
+	Sources    bool         `yaml:"sources,omitempty"`
+	Output     string       `yaml:"output"`
+	ProjectID  int          `yaml:"project_id"`
+	Tag        string       `yaml:"tag"`
+	Files      []LockedFile `yaml:"files"`
+}
+
+type LockedFile struct {
+	URL    string `yaml:"url"`
+	Size   int64  `yaml:"size"`
+	SHA256 string `yaml:"sha256"`
+}
+
+// lockfilePath returns the -lockfile path or derives it from the manifest
+// ("downloads.yaml" -> "downloads.lock").
+func lockfilePath(config *Config) string {
+	if config.LockFile != "" {
+		return config.LockFile
+	}
+	return strings.TrimSuffix(config.Manifest, filepath.Ext(config.Manifest)) + ".lock"
+}
+
+func LoadLockfile(path string) (*Lockfile, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read lockfile: %w", err)
+	}
+
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	decoder.KnownFields(true)
+
+	var lockfile Lockfile
+	if err := decoder.Decode(&lockfile); err != nil && !errors.Is(err, io.EOF) {
+		return nil, fmt.Errorf("%s: failed to parse lockfile: %w", path, err)
+	}
+	return &lockfile, nil
+}
+
+func (l *Lockfile) Save(path string) error {
+	data, err := yaml.Marshal(l)
+	if err != nil {
+		return fmt.Errorf("failed to encode lockfile: %w", err)
+	}
+	if err := os.WriteFile(path, append([]byte(lockfileHeader), data...), 0o644); err != nil {
+		return fmt.Errorf("failed to write lockfile: %w", err)
+	}
+	return nil
+}
+
+// find returns the lock entry for a manifest entry, or nil.
+func (l *Lockfile) find(entry ManifestEntry) *LockEntry {
+	for i := range l.Downloads {
+		if l.Downloads[i].Project == entry.Project && l.Downloads[i].Output == entry.Output {
+			return &l.Downloads[i]
+		}
+	}
+	return nil
+}
+
+// check fails if the manifest entry selects a different release or other
+// assets than it did when the lock entry was written.
+func (e *LockEntry) check(entry ManifestEntry) error {
+	for _, field := range []struct{ name, locked, current string }{
+		{"release", e.Release, entry.Release},
+		{"asset", e.Asset, entry.Asset},
+		{"asset_regex", e.AssetRegex, entry.AssetRegex},
+		{"format", e.Format, entry.Format},
+		{"all", strconv.FormatBool(e.All), strconv.FormatBool(entry.All)},
+		{"sources", strconv.FormatBool(e.Sources), strconv.FormatBool(entry.Sources)},
+	} {
+		if field.locked != field.current {
+			return fmt.Errorf("lockfile out of date for %s -> %s: %s is %q in the manifest but %q in the lockfile; run sync -lock to update it",
+				entry.Project, entry.Output, field.name, field.current, field.locked)
+		}
+	}
+	return nil
+}
+
+// add records the results of a successfully downloaded manifest entry.
+func (l *Lockfile) add(entry ManifestEntry, results []domain.AssetResult) {
+	locked := LockEntry{
+		Project:    entry.Project,
+		Release:    entry.Release,
+		Asset:      entry.Asset,
+		AssetRegex: entry.AssetRegex,
+		Format:     entry.Format,
+		All:        entry.All,
+		Sources:    entry.Sources,
+		Output:     entry.Output,
+	}
+	for _, result := range results {
+		locked.ProjectID = result.ProjectID
+		locked.Tag = result.Tag
+		locked.Files = append(locked.Files, LockedFile{URL: result.URL, Size: result.Size, SHA256: result.SHA256})
+	}
+	l.Downloads = append(l.Downloads, locked)
+}
+
+func (e *LockEntry) lock() *domain.Lock {
+	lock := &domain.Lock{ProjectID: e.ProjectID, Tag: e.Tag}
+	for _, file := range e.Files {
+		lock.Files = append(lock.Files, domain.LockedFile{URL: file.URL, Size: file.Size, SHA256: file.SHA256})
+	}
+	return lock
+}
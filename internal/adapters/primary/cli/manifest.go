@@ -0,0 +1,120 @@
+package cli
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+
+	"gopkg.in/yaml.v3"
+
+	"hufschlaeger.net/gitlab-downloader/internal/core/domain"
+)
+
+// Manifest lists the downloads performed by the sync command.
+type Manifest struct {
+	Downloads []ManifestEntry `yaml:"downloads"`
+}
+
+// ManifestEntry is a single download of a sync manifest. Release accepts the
+// same specs as -release and Output the same paths as -out.
+type ManifestEntry struct {
+	Project    string `yaml:"project"`
+	Release    string `yaml:"release"`
+	Asset      string `yaml:"asset,omitempty"`
+	AssetRegex string `yaml:"asset_regex,omitempty"`
+	Format     string `yaml:"format,omitempty"`
+	SHA256     string `yaml:"sha256,omitempty"`
+	Output     string `yaml:"output"`
+	All        bool   `yaml:"all,omitempty"`
+	Sources    bool   `yaml:"sources,omitempty"`
+
+	Extract         string   `yaml:"extract,omitempty"`
+	StripComponents int      `yaml:"strip_components,omitempty"`
+	Include         []string `yaml:"include,omitempty"`
+	Exclude         []string `yaml:"exclude,omitempty"`
+}
+
+// LoadManifest reads a YAML (or JSON) sync manifest.
+func LoadManifest(path string) (*Manifest, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read manifest: %w", err)
+	}
+
+	manifest, err := ParseManifest(data)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return manifest, nil
+}
+
+// ParseManifest decodes and validates manifest content.
+func ParseManifest(data []byte) (*Manifest, error) {
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	decoder.KnownFields(true)
+
+	var manifest Manifest
+	if err := decoder.Decode(&manifest); err != nil && !errors.Is(err, io.EOF) {
+		return nil, fmt.Errorf("failed to parse manifest: %w", err)
+	}
+
+	if len(manifest.Downloads) == 0 {
+		return nil, fmt.Errorf("manifest contains no downloads")
+	}
+	for i, entry := range manifest.Downloads {
+		if err := entry.Validate(); err != nil {
+			return nil, fmt.Errorf("entry %d: %w", i+1, err)
+		}
+	}
+	return &manifest, nil
+}
+
+func (e ManifestEntry) Validate() error {
+	if e.Project == "" {
+		return fmt.Errorf("project is required")
+	}
+	if e.Release == "" {
+		return fmt.Errorf("release is required")
+	}
+	if e.Output == "" {
+		return fmt.Errorf("output is required")
+	}
+	if e.Output == "-" {
+		return fmt.Errorf("output - (stdout) is not supported by sync")
+	}
+	if e.SHA256 != "" && !isHex(e.SHA256, 64) {
+		return fmt.Errorf("sha256 must be 64 hex characters")
+	}
+	if e.Extract != "" && e.All {
+		return fmt.Errorf("extract cannot be combined with all")
+	}
+	if e.Extract == "" && (e.StripComponents != 0 || len(e.Include) > 0 || len(e.Exclude) > 0) {
+		return fmt.Errorf("strip_components, include and exclude require extract")
+	}
+	if e.StripComponents < 0 {
+		return fmt.Errorf("strip_components must not be negative")
+	}
+	return nil
+}
+
+func (e ManifestEntry) String() string {
+	return fmt.Sprintf("%s@%s", e.Project, e.Release)
+}
+
+func (e ManifestEntry) request(continueDownload bool) domain.DownloadRequest {
+	return domain.DownloadRequest{
+		ProjectName:    e.Project,
+		ReleaseTag:     e.Release,
+		OutputPath:     e.Output,
+		All:            e.All,
+		IncludeSources: e.Sources,
+		AssetGlob:      e.Asset,
+		AssetRegex:     e.AssetRegex,
+		SourceFormat:   e.Format,
+		SHA256:         e.SHA256,
+		Continue:       continueDownload,
+		Extract:        extractOptions(e.Extract, e.StripComponents, e.Include, e.Exclude),
+	}
+}
@@ -0,0 +1,70 @@
+package cli
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestParseManifest(t *testing.T) {
+	m, err := ParseManifest([]byte(`
+downloads:
+  - project: group/app
+    release: latest-stable
+    asset: "*linux-amd64.tar.gz"
+    output: dist/app.tar.gz
+  - project: group/docs
+    release: v1.0.0
+    all: true
+    output: dist/docs
+`))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(m.Downloads) != 2 || m.Downloads[0].Asset != "*linux-amd64.tar.gz" || !m.Downloads[1].All {
+		t.Fatalf("unexpected manifest: %+v", m)
+	}
+
+	req := m.Downloads[0].request(true)
+	if req.ProjectName != "group/app" || req.ReleaseTag != "latest-stable" || req.OutputPath != "dist/app.tar.gz" || !req.Continue {
+		t.Fatalf("unexpected request: %+v", req)
+	}
+}
+
+func TestParseManifest_Extract(t *testing.T) {
+	m, err := ParseManifest([]byte(`
+downloads:
+  - project: group/app
+    release: v1.0.0
+    output: dist/app.tar.gz
+    extract: dist/app
+    strip_components: 1
+    include: [bin]
+`))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	req := m.Downloads[0].request(false)
+	if req.Extract == nil || req.Extract.Dir != "dist/app" || req.Extract.StripComponents != 1 || len(req.Extract.Include) != 1 {
+		t.Fatalf("unexpected extract options: %+v", req.Extract)
+	}
+}
+
+func TestParseManifest_Errors(t *testing.T) {
+	cases := map[string]string{
+		"downloads: []\n": "no downloads",
+		"downloads:\n  - release: v1\n    output: o\n":                                   "entry 1: project is required",
+		"downloads:\n  - project: p\n    output: o\n":                                    "entry 1: release is required",
+		"downloads:\n  - project: p\n    release: v1\n":                                  "entry 1: output is required",
+		"downloads:\n  - project: p\n    release: v1\n    output: o\n    sha256: x\n":    "sha256 must be 64 hex characters",
+		"downloads:\n  - project: p\n    releas: v1\n":                                   "failed to parse manifest",
+		"downloads:\n  - project: p\n    release: v1\n    output: o\n    include: [x]\n": "require extract",
+		"downloads:\n  - project: p\n    release: v1\n    output: \"-\"\n":               "not supported by sync",
+	}
+	for content, want := range cases {
+		_, err := ParseManifest([]byte(content))
+		if err == nil || !strings.Contains(err.Error(), want) {
+			t.Fatalf("expected error containing %q, got %v", want, err)
+		}
+	}
+}
@@ -0,0 +1,334 @@
+package cli
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"text/template"
+	"time"
+
+	"hufschlaeger.net/gitlab-downloader/internal/core/domain"
+	"hufschlaeger.net/gitlab-downloader/internal/core/ports"
+)
+
+// QueryAdapter implements "gitlab-downloader releases list", "release show"
+// and "assets list".
+type QueryAdapter struct {
+	service ports.ReleaseQueryPort
+	out     io.Writer
+}
+
+func NewQueryAdapter(service ports.ReleaseQueryPort) *QueryAdapter {
+	return &QueryAdapter{service: service, out: os.Stdout}
+}
+
+// ListReleases prints the project's releases selected by config.Filter as
+// a table, JSON array or plain tags, one per line.
+func (a *QueryAdapter) ListReleases(config *Config) error {
+	releases, err := a.service.ListReleases(config.Project, config.Filter)
+	if err != nil {
+		return err
+	}
+
+	switch config.OutputFormat {
+	case "json":
+		list := make([]releaseJSON, 0, len(releases))
+		for _, release := range releases {
+			list = append(list, newReleaseJSON(release))
+		}
+		enc := json.NewEncoder(a.out)
+		enc.SetIndent("", "  ")
+		return enc.Encode(list)
+	case "plain":
+		for _, release := range releases {
+			_, _ = fmt.Fprintln(a.out, release.Tag)
+		}
+		return nil
+	}
+
+	w := tabwriter.NewWriter(a.out, 0, 0, 2, ' ', 0)
+	_, _ = fmt.Fprintln(w, "TAG\tNAME\tRELEASED\tASSETS")
+	for _, release := range releases {
+		released := "-"
+		if !release.ReleasedAt.IsZero() {
+			released = release.ReleasedAt.Local().Format("2006-01-02 15:04")
+		}
+		_, _ = fmt.Fprintf(w, "%s\t%s\t%s\t%d\n", release.Tag, release.Name, released, len(release.Assets.Links))
+	}
+	return w.Flush()
+}
+
+// releaseJSON is a release in JSON output.
+type releaseJSON struct {
+	ProjectID       int          `json:"project_id"`
+	Tag             string       `json:"tag"`
+	Name            string       `json:"name"`
+	Description     string       `json:"description"`
+	ReleasedAt      *time.Time   `json:"released_at,omitempty"`
+	UpcomingRelease bool         `json:"upcoming_release"`
+	CommitSHA       string       `json:"commit_sha,omitempty"`
+	Milestones      []string     `json:"milestones"`
+	Links           []linkJSON   `json:"links"`
+	Sources         []sourceJSON `json:"sources"`
+}
+
+type linkJSON struct {
+	ID             int    `json:"id,omitempty"`
+	Name           string `json:"name"`
+	URL            string `json:"url"`
+	DirectAssetURL string `json:"direct_asset_url,omitempty"`
+	LinkType       string `json:"link_type,omitempty"`
+}
+
+type sourceJSON struct {
+	Format string `json:"format"`
+	URL    string `json:"url"`
+}
+
+func newReleaseJSON(release domain.Release) releaseJSON {
+	r := releaseJSON{
+		ProjectID:       release.ProjectID,
+		Tag:             release.Tag,
+		Name:            release.Name,
+		Description:     release.Description,
+		UpcomingRelease: release.Upcoming,
+		CommitSHA:       release.CommitSHA,
+		Milestones:      append([]string{}, release.Milestones...),
+		Links:           []linkJSON{},
+		Sources:         []sourceJSON{},
+	}
+	if !release.ReleasedAt.IsZero() {
+		r.ReleasedAt = &release.ReleasedAt
+	}
+	for _, link := range release.Assets.Links {
+		r.Links = append(r.Links, linkJSON{ID: link.ID, Name: link.Name, URL: link.URL, DirectAssetURL: link.DirectAssetURL, LinkType: link.LinkType})
+	}
+	for _, source := range release.Assets.Sources {
+		r.Sources = append(r.Sources, sourceJSON{Format: source.Format, URL: source.URL})
+	}
+	return r
+}
+
+// ShowRelease prints the selected release with its metadata, asset links
+// and source archives as text, JSON or through config.Template.
+func (a *QueryAdapter) ShowRelease(config *Config) error {
+	release, err := a.service.GetRelease(config.Project, config.Release)
+	if err != nil {
+		return err
+	}
+
+	switch {
+	case config.Template != "":
+		tmpl, err := parseReleaseTemplate(config.Template)
+		if err != nil {
+			return err
+		}
+		var buf bytes.Buffer
+		if err := tmpl.Execute(&buf, release); err != nil {
+			return fmt.Errorf("failed to render template: %w", err)
+		}
+		if buf.Len() > 0 && !bytes.HasSuffix(buf.Bytes(), []byte("\n")) {
+			buf.WriteByte('\n')
+		}
+		_, err = a.out.Write(buf.Bytes())
+		return err
+	case config.OutputFormat == "json":
+		enc := json.NewEncoder(a.out)
+		enc.SetIndent("", "  ")
+		return enc.Encode(newReleaseJSON(*release))
+	}
+
+	w := tabwriter.NewWriter(a.out, 0, 0, 2, ' ', 0)
+	_, _ = fmt.Fprintf(w, "Tag:\t%s\n", release.Tag)
+	if release.Name != "" {
+		_, _ = fmt.Fprintf(w, "Name:\t%s\n", release.Name)
+	}
+	_, _ = fmt.Fprintf(w, "Project:\t%d\n", release.ProjectID)
+	if !release.ReleasedAt.IsZero() {
+		released := release.ReleasedAt.Local().Format("2006-01-02 15:04")
+		if release.Upcoming {
+			released += " (upcoming)"
+		}
+		_, _ = fmt.Fprintf(w, "Released:\t%s\n", released)
+	}
+	if release.CommitSHA != "" {
+		_, _ = fmt.Fprintf(w, "Commit:\t%s\n", release.CommitSHA)
+	}
+	if len(release.Milestones) > 0 {
+		_, _ = fmt.Fprintf(w, "Milestones:\t%s\n", strings.Join(release.Milestones, ", "))
+	}
+	if err := w.Flush(); err != nil {
+		return err
+	}
+
+	if description := strings.TrimSpace(release.Description); description != "" {
+		_, _ = fmt.Fprintln(a.out, "Description:")
+		for _, line := range strings.Split(description, "\n") {
+			_, _ = fmt.Fprintln(a.out, strings.TrimRight("  "+line, " \r"))
+		}
+	}
+
+	_, _ = fmt.Fprintln(a.out, "Assets:")
+	for _, link := range release.Assets.Links {
+		title := link.Name
+		if link.LinkType != "" {
+			title += " (" + link.LinkType + ")"
+		}
+		_, _ = fmt.Fprintf(a.out, "  %s\n", title)
+		_, _ = fmt.Fprintf(w, "    URL:\t%s\n", link.URL)
+		if link.DirectAssetURL != "" {
+			_, _ = fmt.Fprintf(w, "    Direct URL:\t%s\n", link.DirectAssetURL)
+		}
+		if link.ID != 0 {
+			_, _ = fmt.Fprintf(w, "    ID:\t%d\n", link.ID)
+		}
+		if err := w.Flush(); err != nil {
+			return err
+		}
+	}
+
+	_, _ = fmt.Fprintln(a.out, "Sources:")
+	for _, source := range release.Assets.Sources {
+		_, _ = fmt.Fprintf(w, "  %s\t%s\n", source.Format, source.URL)
+	}
+	return w.Flush()
+}
+
+// releaseTemplateFuncs are available in -template besides the builtins.
+var releaseTemplateFuncs = template.FuncMap{
+	"join": strings.Join,
+}
+
+// parseReleaseTemplate parses a -template, which is executed with a
+// domain.Release as data.
+func parseReleaseTemplate(text string) (*template.Template, error) {
+	tmpl, err := template.New("release").Funcs(releaseTemplateFuncs).Parse(text)
+	if err != nil {
+		return nil, fmt.Errorf("invalid template: %w", err)
+	}
+	return tmpl, nil
+}
+
+// ListAssets prints the files "download -all" would store, with their
+// download URLs.
+func (a *QueryAdapter) ListAssets(config *Config) error {
+	results, err := a.service.ListAssets(domain.DownloadRequest{
+		ProjectName:    config.Project,
+		ReleaseTag:     config.Release,
+		IncludeSources: config.Sources,
+		AssetGlob:      config.Asset,
+		AssetRegex:     config.AssetRegex,
+		SourceFormat:   config.Format,
+	})
+	if err != nil {
+		return err
+	}
+
+	w := tabwriter.NewWriter(a.out, 0, 0, 2, ' ', 0)
+	_, _ = fmt.Fprintln(w, "NAME\tURL")
+	for _, result := range results {
+		_, _ = fmt.Fprintf(w, "%s\t%s\n", result.Name, result.URL)
+	}
+	return w.Flush()
+}
+
+// ParseReleasesListFlags parses the flags of "gitlab-downloader releases list".
+func ParseReleasesListFlags(args []string) (*Config, error) {
+	config := &Config{}
+
+	fs := newFlagSet("releases list", "", "List the releases of a project, newest first. Pre-releases are left out unless -prerelease is given.")
+	gitlabURL := bindCommonFlags(fs, config)
+	bindProjectFlags(fs, config)
+	fs.Func("since", "Only list releases published on or after this date (2006-01-02 or RFC 3339)", func(value string) error {
+		since, err := parseSince(value)
+		config.Filter.Since = since
+		return err
+	})
+	fs.BoolVar(&config.Filter.Prerelease, "prerelease", false, "Also list pre-releases such as v2.0.0-rc.1")
+	fs.StringVar(&config.Filter.Match, "match", "", "Only list tags matching this glob, e.g. \"v2.*\"")
+	fs.IntVar(&config.Filter.Limit, "limit", 0, "List at most this many releases (0 lists all)")
+	config.OutputFormat = "table"
+	fs.Func("output", "Output format: table, json or plain (tags only) (default table)", func(value string) error {
+		switch value {
+		case "table", "json", "plain":
+			config.OutputFormat = value
+			return nil
+		}
+		return fmt.Errorf("must be table, json or plain")
+	})
+
+	if err := fs.Parse(args); err != nil {
+		return nil, err
+	}
+
+	config.applyEnv(*gitlabURL)
+
+	return config, nil
+}
+
+// parseSince parses a date or an RFC 3339 timestamp. Dates are midnight in
+// the local time zone.
+func parseSince(value string) (time.Time, error) {
+	if t, err := time.ParseInLocation("2006-01-02", value, time.Local); err == nil {
+		return t, nil
+	}
+	t, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("must be a date like 2006-01-02 or an RFC 3339 timestamp")
+	}
+	return t, nil
+}
+
+// ParseReleaseShowFlags parses the flags of "gitlab-downloader release show".
+func ParseReleaseShowFlags(args []string) (*Config, error) {
+	config := &Config{}
+
+	fs := newFlagSet("release show", "", "Show a release with its metadata, asset links and source archives.")
+	gitlabURL := bindCommonFlags(fs, config)
+	bindReleaseFlags(fs, config, "latest")
+	config.OutputFormat = "text"
+	fs.Func("output", "Output format: text or json (default text)", func(value string) error {
+		switch value {
+		case "text", "json":
+			config.OutputFormat = value
+			return nil
+		}
+		return fmt.Errorf("must be text or json")
+	})
+	fs.Func("template", "Go text/template rendered with the release instead of -output, e.g. '{{.Tag}} {{.CommitSHA}}'", func(value string) error {
+		_, err := parseReleaseTemplate(value)
+		config.Template = value
+		return err
+	})
+
+	if err := fs.Parse(args); err != nil {
+		return nil, err
+	}
+
+	config.applyEnv(*gitlabURL)
+
+	return config, nil
+}
+
+// ParseAssetsListFlags parses the flags of "gitlab-downloader assets list".
+func ParseAssetsListFlags(args []string) (*Config, error) {
+	config := &Config{}
+
+	fs := newFlagSet("assets list", "", "List the files of a release with the names and URLs \"download -all\" uses.")
+	gitlabURL := bindCommonFlags(fs, config)
+	bindReleaseFlags(fs, config, "latest")
+	fs.BoolVar(&config.Sources, "sources", false, "Also list the release's source archives")
+	bindAssetFlags(fs, config)
+
+	if err := fs.Parse(args); err != nil {
+		return nil, err
+	}
+
+	config.applyEnv(*gitlabURL)
+
+	return config, nil
+}
@@ -0,0 +1,234 @@
+package cli
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"hufschlaeger.net/gitlab-downloader/internal/core/domain"
+	"hufschlaeger.net/gitlab-downloader/internal/core/ports"
+)
+
+type mockQueryService struct {
+	req    domain.DownloadRequest
+	filter domain.ReleaseFilter
+	paths  []string
+}
+
+func (m *mockQueryService) ListReleases(projectName string, filter domain.ReleaseFilter) ([]domain.Release, error) {
+	m.filter = filter
+	return []domain.Release{
+		{Tag: "v2.0.0", Name: "Second", ReleasedAt: time.Date(2024, 3, 10, 12, 0, 0, 0, time.UTC), Assets: domain.Assets{Links: []domain.Link{{Name: "app.zip", URL: "https://example.com/app.zip"}}}},
+		{Tag: "v1.0.0"},
+	}, nil
+}
+
+func (m *mockQueryService) GetRelease(projectName, releaseSpec string) (*domain.Release, error) {
+	return &domain.Release{
+		ProjectID:   7,
+		Tag:         "v2.0.0",
+		Name:        "Second",
+		Description: "Changes:\n- faster",
+		CommitSHA:   "0123abcd",
+		Milestones:  []string{"2.0", "Q1"},
+		Assets: domain.Assets{
+			Links:   []domain.Link{{ID: 5, Name: "app.zip", URL: "https://example.com/app.zip", DirectAssetURL: "https://gitlab.example.com/g/p/-/releases/v2.0.0/downloads/app.zip", LinkType: "package"}},
+			Sources: []domain.Source{{Format: "tar.gz", URL: "https://example.com/src.tar.gz"}},
+		},
+	}, nil
+}
+
+func (m *mockQueryService) ListAssets(req domain.DownloadRequest) ([]domain.AssetResult, error) {
+	m.req = req
+	return []domain.AssetResult{{Name: "app.zip", URL: "https://example.com/app.zip"}}, nil
+}
+
+func (m *mockQueryService) VerifyFiles(req domain.DownloadRequest, paths []string) ([]domain.AssetResult, error) {
+	m.req, m.paths = req, paths
+	return []domain.AssetResult{
+		{Path: "dist/app.zip"},
+		{Path: "dist/other.zip", Err: errors.New("release v1 publishes no checksum for other.zip")},
+	}, errors.New("1 of 2 files failed verification")
+}
+
+func TestQueryAdapter(t *testing.T) {
+	service := &mockQueryService{}
+	var out bytes.Buffer
+	a := &QueryAdapter{service: service, out: &out}
+
+	if err := a.ListReleases(&Config{Project: "g/p", OutputFormat: "plain"}); err != nil || out.String() != "v2.0.0\nv1.0.0\n" {
+		t.Fatalf("unexpected release list %q, err=%v", out.String(), err)
+	}
+
+	out.Reset()
+	if err := a.ShowRelease(&Config{Project: "g/p", Release: "latest"}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	for _, want := range []string{
+		"Tag:         v2.0.0\n",
+		"Milestones:  2.0, Q1\n",
+		"Description:\n  Changes:\n  - faster\n",
+		"  app.zip (package)\n    URL:         https://example.com/app.zip\n    Direct URL:  https://gitlab.example.com/g/p/-/releases/v2.0.0/downloads/app.zip\n    ID:          5\n",
+		"tar.gz  https://example.com/src.tar.gz",
+	} {
+		if !strings.Contains(out.String(), want) {
+			t.Fatalf("expected %q in %q", want, out.String())
+		}
+	}
+
+	out.Reset()
+	if err := a.ListAssets(&Config{Project: "g/p", Release: "v1", Asset: "*.zip", Sources: true}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if service.req.AssetGlob != "*.zip" || !service.req.IncludeSources || !strings.Contains(out.String(), "app.zip  https://example.com/app.zip") {
+		t.Fatalf("unexpected request %+v or output %q", service.req, out.String())
+	}
+}
+
+func TestQueryAdapter_ListReleasesFormats(t *testing.T) {
+	var out bytes.Buffer
+	a := &QueryAdapter{service: &mockQueryService{}, out: &out}
+
+	if err := a.ListReleases(&Config{Project: "g/p", OutputFormat: "table"}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	lines := strings.Split(out.String(), "\n")
+	if strings.Join(strings.Fields(lines[0]), " ") != "TAG NAME RELEASED ASSETS" || !strings.Contains(lines[1], "Second") || strings.Join(strings.Fields(lines[2]), " ") != "v1.0.0 - 0" {
+		t.Fatalf("unexpected table %q", out.String())
+	}
+
+	out.Reset()
+	if err := a.ListReleases(&Config{Project: "g/p", OutputFormat: "json"}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	var list []map[string]any
+	if err := json.Unmarshal(out.Bytes(), &list); err != nil {
+		t.Fatalf("invalid JSON %q: %v", out.String(), err)
+	}
+	if len(list) != 2 || list[0]["released_at"] != "2024-03-10T12:00:00Z" || list[0]["links"].([]any)[0].(map[string]any)["name"] != "app.zip" {
+		t.Fatalf("unexpected JSON %v", list)
+	}
+	if _, ok := list[1]["released_at"]; ok {
+		t.Fatalf("expected no release date for v1.0.0, got %v", list[1])
+	}
+}
+
+func TestQueryAdapter_ShowReleaseFormats(t *testing.T) {
+	var out bytes.Buffer
+	a := &QueryAdapter{service: &mockQueryService{}, out: &out}
+
+	if err := a.ShowRelease(&Config{Project: "g/p", OutputFormat: "json"}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	var release map[string]any
+	if err := json.Unmarshal(out.Bytes(), &release); err != nil {
+		t.Fatalf("invalid JSON %q: %v", out.String(), err)
+	}
+	link := release["links"].([]any)[0].(map[string]any)
+	if release["commit_sha"] != "0123abcd" || release["upcoming_release"] != false || link["link_type"] != "package" || link["id"] != float64(5) {
+		t.Fatalf("unexpected JSON %v", release)
+	}
+
+	out.Reset()
+	tmpl := `{{.Tag}} {{join .Milestones ","}}{{range .Assets.Links}} {{.DirectAssetURL}}{{end}}`
+	if err := a.ShowRelease(&Config{Project: "g/p", OutputFormat: "json", Template: tmpl}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if want := "v2.0.0 2.0,Q1 https://gitlab.example.com/g/p/-/releases/v2.0.0/downloads/app.zip\n"; out.String() != want {
+		t.Fatalf("expected %q, got %q", want, out.String())
+	}
+
+	if err := a.ShowRelease(&Config{Project: "g/p", Template: "{{.Missing}}"}); err == nil || !strings.Contains(err.Error(), "failed to render template") {
+		t.Fatalf("expected template error, got %v", err)
+	}
+}
+
+func TestParseReleaseShowFlags(t *testing.T) {
+	cfg, err := ParseReleaseShowFlags([]string{"-p", "g/p", "-output", "json", "-template", "{{.Tag}}"})
+	if err != nil || cfg.OutputFormat != "json" || cfg.Template != "{{.Tag}}" {
+		t.Fatalf("unexpected config %+v, err=%v", cfg, err)
+	}
+	if cfg, _ := ParseReleaseShowFlags(nil); cfg.OutputFormat != "text" {
+		t.Fatalf("expected text output by default, got %q", cfg.OutputFormat)
+	}
+	for _, args := range [][]string{{"-output", "table"}, {"-template", "{{.Tag"}} {
+		if _, err := ParseReleaseShowFlags(args); err == nil {
+			t.Fatalf("expected %v to be rejected", args)
+		}
+	}
+}
+
+func TestParseReleasesListFlags(t *testing.T) {
+	cfg, err := ParseReleasesListFlags([]string{"-p", "g/p", "-since", "2024-03-01", "-prerelease", "-match", "v2.*", "-limit", "5", "-output", "json"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want := domain.ReleaseFilter{Since: time.Date(2024, 3, 1, 0, 0, 0, 0, time.Local), Prerelease: true, Match: "v2.*", Limit: 5}
+	if cfg.Filter != want || cfg.OutputFormat != "json" {
+		t.Fatalf("unexpected config %+v", cfg)
+	}
+
+	cfg, err = ParseReleasesListFlags([]string{"-since", "2024-03-01T10:00:00+02:00"})
+	if err != nil || !cfg.Filter.Since.Equal(time.Date(2024, 3, 1, 8, 0, 0, 0, time.UTC)) || cfg.OutputFormat != "table" {
+		t.Fatalf("unexpected config %+v, err=%v", cfg, err)
+	}
+
+	for _, args := range [][]string{{"-since", "yesterday"}, {"-output", "yaml"}} {
+		if _, err := ParseReleasesListFlags(args); err == nil {
+			t.Fatalf("expected %v to be rejected", args)
+		}
+	}
+
+	cfg = &Config{Token: "t", Project: "p", GitLabURL: "u", Filter: domain.ReleaseFilter{Limit: -1}}
+	if err := cfg.ValidateQuery(); err == nil || !contains(err.Error(), "-limit must not be negative") {
+		t.Fatalf("expected limit error, got %v", err)
+	}
+}
+
+func TestParseQueryFlags(t *testing.T) {
+	t.Setenv("GITLAB_TOKEN", "tok")
+
+	cfg, err := ParseReleaseShowFlags([]string{"-p", "g/p"})
+	if err != nil || cfg.Release != "latest" || cfg.Token != "tok" {
+		t.Fatalf("unexpected config %+v, err=%v", cfg, err)
+	}
+	if err := cfg.ValidateQuery(); err != nil {
+		t.Fatalf("unexpected validation error: %v", err)
+	}
+
+	cfg, err = ParseAssetsListFlags([]string{"-p", "g/p", "-r", "v1", "-sources", "-asset-regex", "linux"})
+	if err != nil || cfg.Release != "v1" || !cfg.Sources || cfg.AssetRegex != "linux" {
+		t.Fatalf("unexpected config %+v, err=%v", cfg, err)
+	}
+
+	cfg, err = ParseReleasesListFlags(nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := cfg.ValidateQuery(); err == nil || !contains(err.Error(), "project name is required") {
+		t.Fatalf("expected missing project error, got %v", err)
+	}
+
+	if _, err := ParseReleasesListFlags([]string{"-out", "x"}); err == nil {
+		t.Fatalf("expected download flags to be rejected")
+	}
+}
+
+func TestPrintUsage(t *testing.T) {
+	var out bytes.Buffer
+	PrintUsage(&out)
+	for _, command := range Commands {
+		if !strings.Contains(out.String(), command.Name) {
+			t.Fatalf("expected %q in usage %q", command.Name, out.String())
+		}
+	}
+}
+
+// ensure the mock implements the interfaces
+var (
+	_ ports.ReleaseQueryPort = (*mockQueryService)(nil)
+	_ ports.VerifyPort       = (*mockQueryService)(nil)
+)
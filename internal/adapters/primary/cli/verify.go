@@ -0,0 +1,59 @@
+package cli
+
+import (
+	"fmt"
+	"io"
+	"os"
+
+	"hufschlaeger.net/gitlab-downloader/internal/core/domain"
+	"hufschlaeger.net/gitlab-downloader/internal/core/ports"
+)
+
+// VerifyAdapter implements "gitlab-downloader verify".
+type VerifyAdapter struct {
+	service ports.VerifyPort
+	out     io.Writer
+}
+
+func NewVerifyAdapter(service ports.VerifyPort) *VerifyAdapter {
+	return &VerifyAdapter{service: service, out: os.Stdout}
+}
+
+// Verify checks config.Files and prints the outcome per file. An error is
+// returned if any file failed.
+func (a *VerifyAdapter) Verify(config *Config) error {
+	results, err := a.service.VerifyFiles(domain.DownloadRequest{
+		ProjectName: config.Project,
+		ReleaseTag:  config.Release,
+		SHA256:      config.SHA256,
+	}, config.Files)
+
+	for _, result := range results {
+		if result.Err != nil {
+			_, _ = fmt.Fprintf(a.out, "FAILED  %s: %v\n", result.Path, result.Err)
+			continue
+		}
+		_, _ = fmt.Fprintf(a.out, "OK      %s\n", result.Path)
+	}
+	return err
+}
+
+// ParseVerifyFlags parses the flags and file arguments of
+// "gitlab-downloader verify".
+func ParseVerifyFlags(args []string) (*Config, error) {
+	config := &Config{}
+
+	fs := newFlagSet("verify", " <file>...", "Check local files against the checksums the release publishes for files of the same name.")
+	gitlabURL := bindCommonFlags(fs, config)
+	bindReleaseFlags(fs, config, "")
+	fs.StringVar(&config.SHA256, "sha256", "", "Expected SHA-256 of a single file (instead of published checksums)")
+
+	if err := fs.Parse(args); err != nil {
+		return nil, err
+	}
+	config.Files = fs.Args()
+
+	config.applyEnv(*gitlabURL)
+
+	return config, nil
+}
@@ -0,0 +1,56 @@
+package cli
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestParseVerifyFlags(t *testing.T) {
+	t.Setenv("GITLAB_TOKEN", "tok")
+
+	cfg, err := ParseVerifyFlags([]string{"-p", "g/p", "-r", "v1", "dist/a.zip", "dist/b.zip"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(cfg.Files) != 2 || cfg.Files[1] != "dist/b.zip" {
+		t.Fatalf("unexpected files %v", cfg.Files)
+	}
+	if err := cfg.ValidateVerify(); err != nil {
+		t.Fatalf("unexpected validation error: %v", err)
+	}
+
+	cases := []struct {
+		cfg  Config
+		want string
+	}{
+		{Config{Token: "t", Project: "p", GitLabURL: "u", Files: []string{"a"}}, "release version is required"},
+		{Config{Token: "t", Project: "p", GitLabURL: "u", Release: "v1"}, "at least one file is required"},
+		{Config{Token: "t", Project: "p", GitLabURL: "u", Release: "v1", Files: []string{"a", "b"}, SHA256: "abc"}, "-sha256 requires a single file"},
+		{Config{Token: "t", Project: "p", GitLabURL: "u", Release: "v1", Files: []string{"a"}, SHA256: "abc"}, "-sha256 must be 64 hex characters"},
+	}
+	for _, tc := range cases {
+		if err := tc.cfg.ValidateVerify(); err == nil || !contains(err.Error(), tc.want) {
+			t.Fatalf("expected error containing %q, got %v", tc.want, err)
+		}
+	}
+}
+
+func TestVerifyAdapter_Verify(t *testing.T) {
+	service := &mockQueryService{}
+	var out bytes.Buffer
+	a := &VerifyAdapter{service: service, out: &out}
+
+	err := a.Verify(&Config{Project: "g/p", Release: "v1", Files: []string{"dist/app.zip", "dist/other.zip"}})
+	if err == nil || err.Error() != "1 of 2 files failed verification" {
+		t.Fatalf("expected verification error, got %v", err)
+	}
+	if service.req.ReleaseTag != "v1" || len(service.paths) != 2 {
+		t.Fatalf("unexpected request %+v, %v", service.req, service.paths)
+	}
+	for _, want := range []string{"OK      dist/app.zip\n", "FAILED  dist/other.zip: release v1 publishes no checksum for other.zip\n"} {
+		if !strings.Contains(out.String(), want) {
+			t.Fatalf("expected %q in %q", want, out.String())
+		}
+	}
+}
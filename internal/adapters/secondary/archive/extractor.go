@@ -0,0 +1,240 @@
+package archive
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bufio"
+	"bytes"
+	"compress/bzip2"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/klauspost/compress/zstd"
+	"github.com/ulikunitz/xz"
+
+	"hufschlaeger.net/gitlab-downloader/internal/core/domain"
+	"hufschlaeger.net/gitlab-downloader/internal/core/ports"
+)
+
+// Magic numbers of the supported formats.
+var (
+	magicZip   = []byte("PK\x03\x04")
+	magicGzip  = []byte{0x1f, 0x8b}
+	magicBzip2 = []byte("BZh")
+	magicXz    = []byte{0xfd, '7', 'z', 'X', 'Z', 0x00}
+	magicZstd  = []byte{0x28, 0xb5, 0x2f, 0xfd}
+)
+
+// compressionSuffixes are removed from the asset name to name the output of
+// a single compressed file.
+var compressionSuffixes = []string{".gz", ".tgz", ".bz2", ".tbz2", ".xz", ".txz", ".zst", ".tzst"}
+
+// Extractor unpacks zip and tar archives, plain or compressed with gzip,
+// bzip2, xz or zstd. Formats are detected by content, not by file name.
+type Extractor struct{}
+
+func NewExtractor() *Extractor {
+	return &Extractor{}
+}
+
+func (e *Extractor) Extract(path, name string, opts domain.ExtractOptions) ([]string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open archive: %w", err)
+	}
+	defer func(file *os.File) {
+		_ = file.Close()
+	}(file)
+
+	head := make([]byte, len(magicZip))
+	if _, err := io.ReadFull(file, head); err == nil && bytes.Equal(head, magicZip) {
+		info, err := file.Stat()
+		if err != nil {
+			return nil, fmt.Errorf("failed to stat archive: %w", err)
+		}
+		return ExtractZip(file, info.Size(), opts)
+	}
+
+	if _, err := file.Seek(0, io.SeekStart); err != nil {
+		return nil, fmt.Errorf("failed to read archive: %w", err)
+	}
+	return ExtractStream(file, name, opts)
+}
+
+// ExtractStream unpacks a tar archive, optionally compressed, or a single
+// compressed file read sequentially from r. Zip archives need random access
+// and are rejected.
+func ExtractStream(r io.Reader, name string, opts domain.ExtractOptions) ([]string, error) {
+	t, err := newTarget(opts)
+	if err != nil {
+		return nil, err
+	}
+
+	br := bufio.NewReaderSize(r, 64*1024)
+	head, _ := br.Peek(len(magicXz))
+
+	var content io.Reader = br
+	compressed := true
+	switch {
+	case bytes.HasPrefix(head, magicZip):
+		return nil, fmt.Errorf("zip archives cannot be extracted from a stream")
+	case bytes.HasPrefix(head, magicGzip):
+		zr, err := gzip.NewReader(br)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read gzip stream: %w", err)
+		}
+		content = zr
+	case bytes.HasPrefix(head, magicBzip2):
+		content = bzip2.NewReader(br)
+	case bytes.HasPrefix(head, magicXz):
+		xr, err := xz.NewReader(br)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read xz stream: %w", err)
+		}
+		content = xr
+	case bytes.HasPrefix(head, magicZstd):
+		zr, err := zstd.NewReader(br)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read zstd stream: %w", err)
+		}
+		defer zr.Close()
+		content = zr
+	default:
+		compressed = false
+	}
+
+	cr := bufio.NewReaderSize(content, 64*1024)
+	block, _ := cr.Peek(512)
+	switch {
+	case isTar(block) || (!compressed && strings.HasSuffix(strings.ToLower(name), ".tar")):
+		if err := t.extractTar(tar.NewReader(cr)); err != nil {
+			return t.files, err
+		}
+	case compressed:
+		if err := t.extractSingle(cr, decompressedName(name)); err != nil {
+			return t.files, err
+		}
+	default:
+		return nil, ports.ErrUnsupportedArchive
+	}
+	return t.files, nil
+}
+
+// ExtractZip unpacks the zip archive of the given size read from r.
+func ExtractZip(r io.ReaderAt, size int64, opts domain.ExtractOptions) ([]string, error) {
+	t, err := newTarget(opts)
+	if err != nil {
+		return nil, err
+	}
+
+	zr, err := zip.NewReader(r, size)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read zip archive: %w", err)
+	}
+	for _, f := range zr.File {
+		if err := t.extractZipEntry(f); err != nil {
+			return t.files, err
+		}
+	}
+	return t.files, nil
+}
+
+// isTar checks the ustar magic of the first header block.
+func isTar(block []byte) bool {
+	return len(block) >= 262 && string(block[257:262]) == "ustar"
+}
+
+func decompressedName(name string) string {
+	lower := strings.ToLower(name)
+	for _, suffix := range compressionSuffixes {
+		if strings.HasSuffix(lower, suffix) && len(name) > len(suffix) {
+			return name[:len(name)-len(suffix)]
+		}
+	}
+	return name + ".out"
+}
+
+func (t *target) extractTar(tr *tar.Reader) error {
+	for {
+		header, err := tr.Next()
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("failed to read tar archive: %w", err)
+		}
+
+		rel, ok, err := t.entryPath(header.Name)
+		if err != nil || !ok {
+			if err != nil {
+				return err
+			}
+			continue
+		}
+
+		switch header.Typeflag {
+		case tar.TypeDir:
+			err = t.dir(rel)
+		case tar.TypeReg:
+			err = t.file(rel, header.FileInfo().Mode(), tr)
+		case tar.TypeSymlink:
+			err = t.symlink(rel, header.Linkname)
+		case tar.TypeLink:
+			err = t.hardlink(rel, header.Linkname)
+		default:
+			// Devices, FIFOs and other special files are never extracted
+			continue
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
+
+func (t *target) extractZipEntry(f *zip.File) error {
+	rel, ok, err := t.entryPath(f.Name)
+	if err != nil || !ok {
+		return err
+	}
+
+	mode := f.Mode()
+	switch {
+	case mode.IsDir():
+		return t.dir(rel)
+	case mode&os.ModeSymlink != 0:
+		rc, err := f.Open()
+		if err != nil {
+			return fmt.Errorf("failed to read %s: %w", f.Name, err)
+		}
+		linkname, err := io.ReadAll(io.LimitReader(rc, 4096))
+		_ = rc.Close()
+		if err != nil {
+			return fmt.Errorf("failed to read %s: %w", f.Name, err)
+		}
+		return t.symlink(rel, string(linkname))
+	case mode.IsRegular():
+		rc, err := f.Open()
+		if err != nil {
+			return fmt.Errorf("failed to read %s: %w", f.Name, err)
+		}
+		defer func(rc io.ReadCloser) {
+			_ = rc.Close()
+		}(rc)
+		return t.file(rel, mode, rc)
+	}
+	return nil
+}
+
+func (t *target) extractSingle(r io.Reader, name string) error {
+	if strings.ContainsAny(name, `/\`) {
+		name = name[strings.LastIndexAny(name, `/\`)+1:]
+	}
+	if !t.filter.match(name) {
+		return nil
+	}
+	return t.file(name, 0o644, r)
+}
@@ -0,0 +1,279 @@
+package archive
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/klauspost/compress/zstd"
+	"github.com/ulikunitz/xz"
+
+	"hufschlaeger.net/gitlab-downloader/internal/core/domain"
+)
+
+type entry struct {
+	name     string
+	body     string
+	mode     int64
+	typeflag byte
+	linkname string
+}
+
+func buildTar(t *testing.T, entries []entry) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	tw := tar.NewWriter(&buf)
+	for _, e := range entries {
+		hdr := &tar.Header{Name: e.name, Mode: e.mode, Size: int64(len(e.body)), Typeflag: e.typeflag, Linkname: e.linkname, Format: tar.FormatUSTAR}
+		if hdr.Typeflag == 0 {
+			hdr.Typeflag = tar.TypeReg
+		}
+		if hdr.Mode == 0 {
+			hdr.Mode = 0o644
+		}
+		if hdr.Typeflag != tar.TypeReg {
+			hdr.Size = 0
+		}
+		if err := tw.WriteHeader(hdr); err != nil {
+			t.Fatalf("tar header: %v", err)
+		}
+		if hdr.Typeflag == tar.TypeReg {
+			_, _ = tw.Write([]byte(e.body))
+		}
+	}
+	_ = tw.Close()
+	return buf.Bytes()
+}
+
+func buildZip(t *testing.T, entries []entry) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	for _, e := range entries {
+		hdr := &zip.FileHeader{Name: e.name, Method: zip.Deflate}
+		body := e.body
+		switch e.typeflag {
+		case tar.TypeSymlink:
+			hdr.SetMode(os.ModeSymlink | 0o777)
+			body = e.linkname
+		default:
+			hdr.SetMode(os.FileMode(e.mode) | 0o644)
+		}
+		w, err := zw.CreateHeader(hdr)
+		if err != nil {
+			t.Fatalf("zip header: %v", err)
+		}
+		_, _ = io.WriteString(w, body)
+	}
+	_ = zw.Close()
+	return buf.Bytes()
+}
+
+func compress(t *testing.T, format string, data []byte) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	var w io.WriteCloser
+	var err error
+	switch format {
+	case "gz":
+		w = gzip.NewWriter(&buf)
+	case "xz":
+		w, err = xz.NewWriter(&buf)
+	case "zst":
+		w, err = zstd.NewWriter(&buf)
+	}
+	if err != nil {
+		t.Fatalf("compressor: %v", err)
+	}
+	_, _ = w.Write(data)
+	_ = w.Close()
+	return buf.Bytes()
+}
+
+func extractBytes(t *testing.T, name string, data []byte, opts domain.ExtractOptions) ([]string, error) {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, data, 0o644); err != nil {
+		t.Fatalf("setup failed: %v", err)
+	}
+	files, err := NewExtractor().Extract(path, name, opts)
+	sort.Strings(files)
+	return files, err
+}
+
+func readFile(t *testing.T, path string) string {
+	t.Helper()
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("expected %s to exist: %v", path, err)
+	}
+	return string(data)
+}
+
+var sampleEntries = []entry{
+	{name: "app-1.0/", typeflag: tar.TypeDir, mode: 0o755},
+	{name: "app-1.0/README", body: "readme\n"},
+	{name: "app-1.0/bin/app", body: "#!/bin/sh\n", mode: 0o755},
+	{name: "app-1.0/config/app.yaml", body: "key: value\n"},
+}
+
+func TestExtract_Formats(t *testing.T) {
+	tarball := buildTar(t, sampleEntries)
+	bz2, err := os.ReadFile(filepath.Join("testdata", "sample.tar.bz2"))
+	if err != nil {
+		t.Fatalf("missing fixture: %v", err)
+	}
+
+	for name, data := range map[string][]byte{
+		"app.zip":     buildZip(t, sampleEntries),
+		"app.tar":     tarball,
+		"app.tar.gz":  compress(t, "gz", tarball),
+		"app.tar.xz":  compress(t, "xz", tarball),
+		"app.tar.zst": compress(t, "zst", tarball),
+		"app.tar.bz2": bz2,
+		"renamed.bin": compress(t, "gz", tarball),
+	} {
+		t.Run(name, func(t *testing.T) {
+			dir := t.TempDir()
+			files, err := extractBytes(t, name, data, domain.ExtractOptions{Dir: dir, StripComponents: 1})
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if len(files) == 0 || files[0] != "README" {
+				t.Fatalf("unexpected files %v", files)
+			}
+			if got := readFile(t, filepath.Join(dir, "config", "app.yaml")); got != "key: value\n" {
+				t.Fatalf("unexpected content %q", got)
+			}
+		})
+	}
+}
+
+func TestExtract_KeepsExecutableBit(t *testing.T) {
+	dir := t.TempDir()
+	if _, err := extractBytes(t, "app.tar", buildTar(t, sampleEntries), domain.ExtractOptions{Dir: dir}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	info, err := os.Stat(filepath.Join(dir, "app-1.0", "bin", "app"))
+	if err != nil || info.Mode().Perm()&0o100 == 0 {
+		t.Fatalf("expected executable file, got %v, %v", info, err)
+	}
+}
+
+func TestExtract_IncludeExclude(t *testing.T) {
+	tarball := buildTar(t, sampleEntries)
+	for _, tc := range []struct {
+		name             string
+		include, exclude []string
+		want             string
+	}{
+		{"directory include", []string{"config"}, nil, "config/app.yaml"},
+		{"glob include", []string{"config/*.yaml"}, nil, "config/app.yaml"},
+		{"base name include", []string{"*.yaml", "README"}, nil, "README,config/app.yaml"},
+		{"exclude wins", []string{"config", "README"}, []string{"*.yaml"}, "README"},
+		{"exclude directory", nil, []string{"bin", "config/"}, "README"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			files, err := extractBytes(t, "app.tar", tarball, domain.ExtractOptions{Dir: t.TempDir(), StripComponents: 1, Include: tc.include, Exclude: tc.exclude})
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got := strings.Join(files, ","); got != tc.want {
+				t.Fatalf("expected %s, got %s", tc.want, got)
+			}
+		})
+	}
+}
+
+func TestExtract_SingleCompressedFile(t *testing.T) {
+	dir := t.TempDir()
+	files, err := extractBytes(t, "tool-linux.gz", compress(t, "gz", []byte("binary")), domain.ExtractOptions{Dir: dir})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(files) != 1 || files[0] != "tool-linux" || readFile(t, filepath.Join(dir, "tool-linux")) != "binary" {
+		t.Fatalf("unexpected result %v", files)
+	}
+}
+
+func TestExtract_RejectsUnsafeArchives(t *testing.T) {
+	for _, tc := range []struct {
+		name    string
+		entries []entry
+		want    string
+	}{
+		{"parent traversal", []entry{{name: "../evil", body: "x"}}, "leaves the target directory"},
+		{"nested traversal", []entry{{name: "a/../../evil", body: "x"}}, "leaves the target directory"},
+		{"absolute path", []entry{{name: "/etc/evil", body: "x"}}, "is absolute"},
+		{"absolute symlink", []entry{{name: "link", typeflag: tar.TypeSymlink, linkname: "/etc/passwd"}}, "is absolute"},
+		{"escaping symlink", []entry{{name: "a/link", typeflag: tar.TypeSymlink, linkname: "../../outside"}}, "leaves the target directory"},
+		{"chained symlinks", []entry{
+			{name: "sub/up", typeflag: tar.TypeSymlink, linkname: ".."},
+			{name: "sub/esc", typeflag: tar.TypeSymlink, linkname: "up/../.."},
+		}, "climbs out of sub/up"},
+		{"symlink through later link", []entry{
+			{name: "sub/esc", typeflag: tar.TypeSymlink, linkname: "up/../.."},
+			{name: "sub/up", typeflag: tar.TypeSymlink, linkname: ".."},
+		}, "climbs out of sub/up"},
+		{"write through symlink", []entry{
+			{name: "dir", typeflag: tar.TypeSymlink, linkname: "."},
+			{name: "dir/file", body: "x"},
+		}, "written through symlink"},
+		{"hard link outside", []entry{{name: "link", typeflag: tar.TypeLink, linkname: "../../etc/passwd"}}, "leaves the target directory"},
+	} {
+		for _, format := range []string{"tar", "zip"} {
+			if format == "zip" && tc.entries[0].typeflag == tar.TypeLink {
+				continue
+			}
+			t.Run(tc.name+" "+format, func(t *testing.T) {
+				data := buildTar(t, tc.entries)
+				if format == "zip" {
+					data = buildZip(t, tc.entries)
+				}
+				outer := t.TempDir()
+				dir := filepath.Join(outer, "target")
+				_, err := extractBytes(t, "evil."+format, data, domain.ExtractOptions{Dir: dir})
+				if err == nil || !strings.Contains(err.Error(), tc.want) {
+					t.Fatalf("expected error containing %q, got %v", tc.want, err)
+				}
+				if _, err := os.Stat(filepath.Join(outer, "evil")); !os.IsNotExist(err) {
+					t.Fatalf("file was written outside the target directory")
+				}
+			})
+		}
+	}
+}
+
+func TestExtract_AllowsInternalLinks(t *testing.T) {
+	dir := t.TempDir()
+	files, err := extractBytes(t, "app.tar", buildTar(t, []entry{
+		{name: "lib/libapp.so.1", body: "lib"},
+		{name: "lib/libapp.so", typeflag: tar.TypeSymlink, linkname: "libapp.so.1"},
+		{name: "bin/copy", typeflag: tar.TypeLink, linkname: "lib/libapp.so.1"},
+	}), domain.ExtractOptions{Dir: dir})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(files) != 3 || readFile(t, filepath.Join(dir, "lib", "libapp.so")) != "lib" || readFile(t, filepath.Join(dir, "bin", "copy")) != "lib" {
+		t.Fatalf("unexpected result %v", files)
+	}
+}
+
+func TestExtract_UnsupportedFormat(t *testing.T) {
+	if _, err := extractBytes(t, "notes.txt", []byte("plain text"), domain.ExtractOptions{Dir: t.TempDir()}); err == nil || !strings.Contains(err.Error(), "unsupported archive format") {
+		t.Fatalf("expected unsupported format error, got %v", err)
+	}
+}
+
+func TestExtractStream_RejectsZip(t *testing.T) {
+	if _, err := ExtractStream(bytes.NewReader(buildZip(t, sampleEntries)), "app.zip", domain.ExtractOptions{Dir: t.TempDir()}); err == nil {
+		t.Fatalf("expected error for zip stream")
+	}
+}
@@ -0,0 +1,315 @@
+package archive
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"hufschlaeger.net/gitlab-downloader/internal/core/domain"
+)
+
+// target writes archive entries below a root directory. Entry names which
+// are absolute or contain "..", symlinks pointing outside the root and
+// writes through existing symlinks are rejected.
+type target struct {
+	root   string
+	strip  int
+	filter *filter
+	files  []string
+	// extracted maps archive entry names to their relative output path for
+	// hard links
+	extracted map[string]string
+}
+
+func newTarget(opts domain.ExtractOptions) (*target, error) {
+	if opts.Dir == "" {
+		return nil, fmt.Errorf("extraction directory is required")
+	}
+	if opts.StripComponents < 0 {
+		return nil, fmt.Errorf("strip components must not be negative")
+	}
+
+	f, err := newFilter(opts.Include, opts.Exclude)
+	if err != nil {
+		return nil, err
+	}
+
+	root, err := filepath.Abs(opts.Dir)
+	if err != nil {
+		return nil, fmt.Errorf("invalid extraction directory: %w", err)
+	}
+	if err := os.MkdirAll(root, 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create extraction directory: %w", err)
+	}
+	// Resolve symlinks in the root itself, which the user chose
+	if resolved, err := filepath.EvalSymlinks(root); err == nil {
+		root = resolved
+	}
+
+	return &target{root: root, strip: opts.StripComponents, filter: f, extracted: make(map[string]string)}, nil
+}
+
+// entryPath validates an entry name and returns its output path relative to
+// the root. ok is false for entries removed by stripping or filtering.
+func (t *target) entryPath(name string) (rel string, ok bool, err error) {
+	clean, err := cleanEntryName(name)
+	if err != nil {
+		return "", false, err
+	}
+	if clean == "" {
+		return "", false, nil
+	}
+
+	parts := strings.Split(clean, "/")
+	if len(parts) <= t.strip {
+		return "", false, nil
+	}
+	rel = strings.Join(parts[t.strip:], "/")
+
+	if !t.filter.match(rel) {
+		return "", false, nil
+	}
+	t.extracted[clean] = rel
+	return rel, true, nil
+}
+
+// cleanEntryName normalises separators and rejects names escaping the root.
+func cleanEntryName(name string) (string, error) {
+	normalized := strings.ReplaceAll(name, `\`, "/")
+	if path.IsAbs(normalized) || (len(normalized) > 1 && normalized[1] == ':') {
+		return "", fmt.Errorf("unsafe path in archive: %q is absolute", name)
+	}
+
+	var parts []string
+	for _, part := range strings.Split(normalized, "/") {
+		switch part {
+		case "", ".":
+			continue
+		case "..":
+			return "", fmt.Errorf("unsafe path in archive: %q leaves the target directory", name)
+		}
+		parts = append(parts, part)
+	}
+	return strings.Join(parts, "/"), nil
+}
+
+// prepare returns the absolute path for rel after creating its parent
+// directories. It refuses to follow symlinks below the root and removes an
+// existing entry at the path itself.
+func (t *target) prepare(rel string) (string, error) {
+	parts := strings.Split(rel, "/")
+	dir := t.root
+	for _, part := range parts[:len(parts)-1] {
+		dir = filepath.Join(dir, part)
+		info, err := os.Lstat(dir)
+		switch {
+		case os.IsNotExist(err):
+			if err := os.Mkdir(dir, 0o755); err != nil {
+				return "", fmt.Errorf("failed to create directory: %w", err)
+			}
+		case err != nil:
+			return "", fmt.Errorf("failed to inspect %s: %w", dir, err)
+		case info.Mode()&os.ModeSymlink != 0:
+			return "", fmt.Errorf("unsafe path in archive: %q would be written through symlink %s", rel, dir)
+		case !info.IsDir():
+			return "", fmt.Errorf("cannot extract %q: %s is not a directory", rel, dir)
+		}
+	}
+
+	abs := filepath.Join(dir, parts[len(parts)-1])
+	if info, err := os.Lstat(abs); err == nil && !info.IsDir() {
+		if err := os.Remove(abs); err != nil {
+			return "", fmt.Errorf("failed to replace %s: %w", abs, err)
+		}
+	}
+	return abs, nil
+}
+
+func (t *target) dir(rel string) error {
+	abs, err := t.prepare(rel)
+	if err != nil {
+		return err
+	}
+	if info, err := os.Lstat(abs); err == nil && info.IsDir() {
+		return nil
+	}
+	if err := os.Mkdir(abs, 0o755); err != nil {
+		return fmt.Errorf("failed to create directory: %w", err)
+	}
+	return nil
+}
+
+func (t *target) file(rel string, mode os.FileMode, r io.Reader) error {
+	abs, err := t.prepare(rel)
+	if err != nil {
+		return err
+	}
+
+	// Keep the permission bits only, never setuid/setgid/sticky
+	perm := mode.Perm()
+	if perm == 0 {
+		perm = 0o644
+	}
+	out, err := os.OpenFile(abs, os.O_WRONLY|os.O_CREATE|os.O_EXCL, perm)
+	if err != nil {
+		return fmt.Errorf("failed to create %s: %w", rel, err)
+	}
+	_, err = io.Copy(out, r)
+	if closeErr := out.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return fmt.Errorf("failed to extract %s: %w", rel, err)
+	}
+
+	t.files = append(t.files, rel)
+	return nil
+}
+
+// symlink creates a relative symlink whose target stays below the root.
+func (t *target) symlink(rel, linkname string) error {
+	linkname = strings.ReplaceAll(linkname, `\`, "/")
+	if linkname == "" || path.IsAbs(linkname) {
+		return fmt.Errorf("unsafe symlink in archive: %q -> %q is absolute", rel, linkname)
+	}
+
+	abs, err := t.prepare(rel)
+	if err != nil {
+		return err
+	}
+	if err := t.checkLinkTarget(rel, linkname); err != nil {
+		return err
+	}
+	if err := os.Symlink(filepath.FromSlash(linkname), abs); err != nil {
+		return fmt.Errorf("failed to create symlink %s: %w", rel, err)
+	}
+
+	t.files = append(t.files, rel)
+	return nil
+}
+
+// checkLinkTarget walks linkname from the directory of rel the way the
+// kernel resolves it. A ".." may only leave a directory that exists as a
+// real directory: after a symlink, or a name an entry could still turn into
+// one, it would climb relative to wherever that link points.
+func (t *target) checkLinkTarget(rel, linkname string) error {
+	// The parents of rel were just created or verified by prepare
+	var dirs []string
+	if dir := path.Dir(rel); dir != "." {
+		dirs = strings.Split(dir, "/")
+	}
+	verified := len(dirs)
+
+	for _, part := range strings.Split(linkname, "/") {
+		switch part {
+		case "", ".":
+			continue
+		case "..":
+			if len(dirs) == 0 {
+				return fmt.Errorf("unsafe symlink in archive: %q -> %q leaves the target directory", rel, linkname)
+			}
+			if verified < len(dirs) {
+				return fmt.Errorf("unsafe symlink in archive: %q -> %q climbs out of %s, which is not a directory", rel, linkname, path.Join(dirs...))
+			}
+			dirs = dirs[:len(dirs)-1]
+			verified = len(dirs)
+			continue
+		}
+
+		dirs = append(dirs, part)
+		if verified == len(dirs)-1 {
+			info, err := os.Lstat(filepath.Join(t.root, filepath.Join(dirs...)))
+			if err == nil && info.IsDir() {
+				verified = len(dirs)
+			}
+		}
+	}
+	return nil
+}
+
+// hardlink links rel to a previously extracted entry of the same archive.
+func (t *target) hardlink(rel, linkname string) error {
+	clean, err := cleanEntryName(linkname)
+	if err != nil {
+		return err
+	}
+	source, ok := t.extracted[clean]
+	if !ok || source == rel {
+		return fmt.Errorf("cannot extract hard link %q: target %q was not extracted", rel, linkname)
+	}
+
+	abs, err := t.prepare(rel)
+	if err != nil {
+		return err
+	}
+	src := filepath.Join(t.root, filepath.FromSlash(source))
+	if info, err := os.Lstat(src); err != nil || !info.Mode().IsRegular() {
+		return fmt.Errorf("cannot extract hard link %q: target %q is not a regular file", rel, linkname)
+	}
+	if err := os.Link(src, abs); err != nil {
+		return fmt.Errorf("failed to create hard link %s: %w", rel, err)
+	}
+
+	t.files = append(t.files, rel)
+	return nil
+}
+
+// filter selects entries by include and exclude globs. A pattern matches the
+// entry path, any of its parent directories, or, without a slash, the base
+// name. Excludes win over includes. Parent directories of included files are
+// created as needed.
+type filter struct {
+	include []string
+	exclude []string
+}
+
+func newFilter(include, exclude []string) (*filter, error) {
+	f := &filter{}
+	for _, list := range []struct {
+		in  []string
+		out *[]string
+	}{{include, &f.include}, {exclude, &f.exclude}} {
+		for _, pattern := range list.in {
+			pattern = strings.Trim(strings.TrimSpace(pattern), "/")
+			if _, err := path.Match(pattern, ""); err != nil || pattern == "" {
+				return nil, fmt.Errorf("invalid extract pattern %q", pattern)
+			}
+			*list.out = append(*list.out, pattern)
+		}
+	}
+	return f, nil
+}
+
+func (f *filter) match(rel string) bool {
+	for _, pattern := range f.exclude {
+		if matchPattern(pattern, rel) {
+			return false
+		}
+	}
+	if len(f.include) == 0 {
+		return true
+	}
+	for _, pattern := range f.include {
+		if matchPattern(pattern, rel) {
+			return true
+		}
+	}
+	return false
+}
+
+func matchPattern(pattern, rel string) bool {
+	if !strings.Contains(pattern, "/") {
+		if ok, _ := path.Match(pattern, path.Base(rel)); ok {
+			return true
+		}
+	}
+	for p := rel; p != "." && p != "/"; p = path.Dir(p) {
+		if ok, _ := path.Match(pattern, p); ok {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,159 @@
+package cache
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"hufschlaeger.net/gitlab-downloader/internal/core/domain"
+	"hufschlaeger.net/gitlab-downloader/internal/core/ports"
+)
+
+// GitLab wraps a GitLabPort and keeps its responses in the cache directory
+// under api/<host>/. Offline, responses are served from there only.
+type GitLab struct {
+	upstream ports.GitLabPort
+	dir      string
+	offline  bool
+	warnings io.Writer
+}
+
+func NewGitLab(upstream ports.GitLabPort, dir string) *GitLab {
+	return &GitLab{upstream: upstream, dir: dir}
+}
+
+// WithOffline stops all API calls: responses which have not been cached
+// fail with ports.ErrOffline.
+func (g *GitLab) WithOffline(offline bool) *GitLab {
+	g.offline = offline
+	return g
+}
+
+// WithWarnings reports responses that could not be cached to w. They are
+// missing from a later -offline run.
+func (g *GitLab) WithWarnings(w io.Writer) *GitLab {
+	g.warnings = w
+	return g
+}
+
+func (g *GitLab) BaseURL() string {
+	return g.upstream.BaseURL()
+}
+
+func (g *GitLab) GetProject(name string) (*domain.Project, error) {
+	return cached(g, fmt.Sprintf("project %s", name), func() (*domain.Project, error) {
+		return g.upstream.GetProject(name)
+	}, "projects", url.PathEscape(name)+".json")
+}
+
+func (g *GitLab) GetRelease(projectID int, tag string) (*domain.Release, error) {
+	return cached(g, fmt.Sprintf("release %s of project %d", tag, projectID), func() (*domain.Release, error) {
+		return g.upstream.GetRelease(projectID, tag)
+	}, "projects", strconv.Itoa(projectID), "releases", url.PathEscape(tag)+".json")
+}
+
+// ListReleases passes the upstream releases on and caches the list once all
+// of them were visited; a walk stopped early keeps the previously cached
+// list. Offline, the cached list is walked.
+func (g *GitLab) ListReleases(projectID int, visit func(domain.Release) bool) error {
+	what := fmt.Sprintf("release list of project %d", projectID)
+	path := g.path("projects", strconv.Itoa(projectID), "releases.json")
+
+	if g.offline {
+		var releases []domain.Release
+		if err := load(path, what, &releases); err != nil {
+			return err
+		}
+		for _, release := range releases {
+			if !visit(release) {
+				break
+			}
+		}
+		return nil
+	}
+
+	var releases []domain.Release
+	complete := true
+	err := g.upstream.ListReleases(projectID, func(release domain.Release) bool {
+		releases = append(releases, release)
+		complete = visit(release)
+		return complete
+	})
+	if err == nil && complete {
+		g.save(path, what, releases)
+	}
+	return err
+}
+
+func (g *GitLab) TagExists(projectID int, tag string) (bool, error) {
+	return cached(g, fmt.Sprintf("tag %s of project %d", tag, projectID), func() (bool, error) {
+		return g.upstream.TagExists(projectID, tag)
+	}, "projects", strconv.Itoa(projectID), "tags", url.PathEscape(tag)+".json")
+}
+
+// cached returns the response of fetch and stores it under elem. Offline,
+// the stored response is returned instead. A failing cache write does not
+// fail the call and is reported as a warning.
+func cached[T any](g *GitLab, what string, fetch func() (T, error), elem ...string) (T, error) {
+	path := g.path(elem...)
+
+	var result T
+	if !g.offline {
+		result, err := fetch()
+		if err != nil {
+			return result, err
+		}
+		g.save(path, what, result)
+		return result, nil
+	}
+
+	err := load(path, what, &result)
+	return result, err
+}
+
+// path returns the location of a cached response below api/<host>/.
+func (g *GitLab) path(elem ...string) string {
+	return filepath.Join(append([]string{g.dir, "api", hostKey(g.BaseURL())}, elem...)...)
+}
+
+// save stores a response, reporting a failure as a warning only.
+func (g *GitLab) save(path, what string, value any) {
+	data, err := json.Marshal(value)
+	if err == nil {
+		err = writeAtomic(path, data)
+	}
+	if err != nil && g.warnings != nil {
+		_, _ = fmt.Fprintf(g.warnings, "Warning: failed to cache %s: %v\n", what, err)
+	}
+}
+
+// load reads a cached response into result.
+func load(path, what string, result any) error {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("%w: %s is not in the cache", ports.ErrOffline, what)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to read cached %s: %w", what, err)
+	}
+	if err := json.Unmarshal(data, result); err != nil {
+		return fmt.Errorf("failed to parse cached %s: %w", what, err)
+	}
+	return nil
+}
+
+// hostKey turns a base URL into a directory name, e.g.
+// "https://gitlab.example.com:8443/sub" -> "gitlab.example.com_8443_sub".
+func hostKey(baseURL string) string {
+	key := baseURL
+	if i := strings.Index(key, "://"); i >= 0 {
+		key = key[i+3:]
+	}
+	return strings.NewReplacer("/", "_", ":", "_").Replace(strings.Trim(key, "/"))
+}
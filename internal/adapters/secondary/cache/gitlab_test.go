@@ -0,0 +1,155 @@
+package cache
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"hufschlaeger.net/gitlab-downloader/internal/core/domain"
+	"hufschlaeger.net/gitlab-downloader/internal/core/ports"
+)
+
+type fakeGitLab struct {
+	calls int
+	err   error
+}
+
+func (f *fakeGitLab) BaseURL() string { return "https://gitlab.example.com:8443" }
+
+func (f *fakeGitLab) GetProject(name string) (*domain.Project, error) {
+	f.calls++
+	return &domain.Project{ID: 42, Name: name}, f.err
+}
+
+func (f *fakeGitLab) GetRelease(projectID int, tag string) (*domain.Release, error) {
+	f.calls++
+	return &domain.Release{ProjectID: projectID, Tag: tag, Assets: domain.Assets{Links: []domain.Link{{Name: "app.zip", URL: "https://example.com/app.zip"}}}}, f.err
+}
+
+func (f *fakeGitLab) ListReleases(projectID int, visit func(domain.Release) bool) error {
+	f.calls++
+	for _, release := range []domain.Release{{ProjectID: projectID, Tag: "v1"}, {ProjectID: projectID, Tag: "v2"}} {
+		if !visit(release) {
+			break
+		}
+	}
+	return f.err
+}
+
+func (f *fakeGitLab) TagExists(projectID int, tag string) (bool, error) {
+	f.calls++
+	return tag == "v1", f.err
+}
+
+func listAll(g *GitLab, projectID int) ([]domain.Release, error) {
+	var releases []domain.Release
+	err := g.ListReleases(projectID, func(release domain.Release) bool {
+		releases = append(releases, release)
+		return true
+	})
+	return releases, err
+}
+
+func TestGitLab_OfflineServesCachedResponses(t *testing.T) {
+	dir := t.TempDir()
+	upstream := &fakeGitLab{}
+	online := NewGitLab(upstream, dir)
+	if _, err := online.GetProject("group/app"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, err := online.GetRelease(42, "v1"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, err := listAll(online, 42); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	offline := NewGitLab(upstream, dir).WithOffline(true)
+	project, err := offline.GetProject("group/app")
+	if err != nil || project.ID != 42 {
+		t.Fatalf("unexpected project %+v, err %v", project, err)
+	}
+	release, err := offline.GetRelease(42, "v1")
+	if err != nil || len(release.Assets.Links) != 1 || release.Assets.Links[0].URL != "https://example.com/app.zip" {
+		t.Fatalf("unexpected release %+v, err %v", release, err)
+	}
+	releases, err := listAll(offline, 42)
+	if err != nil || len(releases) != 2 {
+		t.Fatalf("unexpected releases %+v, err %v", releases, err)
+	}
+	if upstream.calls != 3 {
+		t.Fatalf("expected no API calls offline, got %d calls in total", upstream.calls)
+	}
+
+	_, err = offline.GetRelease(42, "v3")
+	if !errors.Is(err, ports.ErrOffline) || !strings.Contains(err.Error(), "release v3 of project 42 is not in the cache") {
+		t.Fatalf("expected offline miss, got %v", err)
+	}
+}
+
+func TestGitLab_OnlineErrorsAreNotCached(t *testing.T) {
+	dir := t.TempDir()
+	upstream := &fakeGitLab{err: errors.New("HTTP 404")}
+	if _, err := NewGitLab(upstream, dir).GetProject("group/app"); err == nil {
+		t.Fatalf("expected upstream error")
+	}
+	if _, err := NewGitLab(upstream, dir).WithOffline(true).GetProject("group/app"); !errors.Is(err, ports.ErrOffline) {
+		t.Fatalf("expected offline miss, got %v", err)
+	}
+}
+
+func TestGitLab_StoppedReleaseListIsNotCached(t *testing.T) {
+	dir := t.TempDir()
+	online := NewGitLab(&fakeGitLab{}, dir)
+	if err := online.ListReleases(42, func(domain.Release) bool { return false }); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	// A partial list would hide older releases from offline resolution
+	if _, err := listAll(NewGitLab(&fakeGitLab{}, dir).WithOffline(true), 42); !errors.Is(err, ports.ErrOffline) {
+		t.Fatalf("expected the partial list not to be cached, got %v", err)
+	}
+
+	if _, err := listAll(online, 42); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	var first []string
+	err := NewGitLab(&fakeGitLab{}, dir).WithOffline(true).ListReleases(42, func(release domain.Release) bool {
+		first = append(first, release.Tag)
+		return false
+	})
+	if err != nil || len(first) != 1 || first[0] != "v1" {
+		t.Fatalf("expected the offline walk to stop after v1, got %v, err %v", first, err)
+	}
+}
+
+func TestGitLab_CacheWriteFailureWarns(t *testing.T) {
+	// A file where the cache directory should be makes every write fail
+	dir := filepath.Join(t.TempDir(), "cache")
+	if err := os.WriteFile(dir, nil, 0o644); err != nil {
+		t.Fatalf("setup failed: %v", err)
+	}
+	var warnings bytes.Buffer
+	g := NewGitLab(&fakeGitLab{}, dir).WithWarnings(&warnings)
+
+	if _, err := g.GetProject("group/app"); err != nil {
+		t.Fatalf("expected the call to succeed, got %v", err)
+	}
+	if !strings.HasPrefix(warnings.String(), "Warning: failed to cache project group/app: ") {
+		t.Fatalf("unexpected warnings %q", warnings.String())
+	}
+}
+
+func TestHostKey(t *testing.T) {
+	for input, want := range map[string]string{
+		"https://gitlab.com":                   "gitlab.com",
+		"https://gitlab.example.com:8443/sub/": "gitlab.example.com_8443_sub",
+		"http://localhost:8080":                "localhost_8080",
+	} {
+		if got := hostKey(input); got != want {
+			t.Fatalf("hostKey(%q) = %q, want %q", input, got, want)
+		}
+	}
+}
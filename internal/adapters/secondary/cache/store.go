@@ -0,0 +1,381 @@
+package cache
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"hufschlaeger.net/gitlab-downloader/internal/core/domain"
+)
+
+const (
+	indexFile = "index.json"
+	// lockFile is held while a run reads, changes and writes the index, so
+	// concurrent runs sharing the cache do not lose each other's entries
+	lockFile = "index.lock"
+)
+
+const (
+	lockRetryInterval = 10 * time.Millisecond
+	lockTimeout       = 30 * time.Second
+	// staleLockAge is when a lock is considered left behind by a killed run;
+	// holding it never takes more than a few index writes
+	staleLockAge = 2 * time.Minute
+)
+
+// Store is a download cache in a local directory. Files live under
+// blobs/<sha256[:2]>/<sha256>; index.json maps download URLs and validators
+// to them and records when each file was last used.
+type Store struct {
+	dir     string
+	maxSize int64
+	now     func() time.Time
+}
+
+func NewStore(dir string) *Store {
+	return &Store{dir: dir, now: time.Now}
+}
+
+// WithMaxSize evicts least recently used files after every Store so that
+// the cache stays below maxSize bytes. Zero disables the limit.
+func (s *Store) WithMaxSize(maxSize int64) *Store {
+	s.maxSize = maxSize
+	return s
+}
+
+func (s *Store) blobPath(sha256 string) string {
+	return filepath.Join(s.dir, "blobs", sha256[:2], sha256)
+}
+
+func (s *Store) Lookup(url, validator, sha256 string) (*domain.CacheEntry, error) {
+	entries, err := s.load()
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range entries {
+		entry := &entries[i]
+		switch {
+		case sha256 != "":
+			if entry.SHA256 != sha256 {
+				continue
+			}
+		case entry.URL != url || (validator != "" && entry.Validator != validator):
+			continue
+		}
+
+		if _, err := os.Stat(s.blobPath(entry.SHA256)); err != nil {
+			continue
+		}
+		return entry, nil
+	}
+	return nil, nil
+}
+
+func (s *Store) Materialize(entry *domain.CacheEntry, path string) error {
+	if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to replace %s: %w", path, err)
+	}
+	if err := linkOrCopy(s.blobPath(entry.SHA256), path); err != nil {
+		return fmt.Errorf("failed to copy cached file: %w", err)
+	}
+
+	return s.update(func(entries []domain.CacheEntry) ([]domain.CacheEntry, error) {
+		for i := range entries {
+			if entries[i].SHA256 == entry.SHA256 {
+				entries[i].LastUsed = s.now()
+			}
+		}
+		return entries, nil
+	})
+}
+
+func (s *Store) Store(entry domain.CacheEntry, path string) error {
+	blob := s.blobPath(entry.SHA256)
+	if _, err := os.Stat(blob); os.IsNotExist(err) {
+		if err := os.MkdirAll(filepath.Dir(blob), 0o755); err != nil {
+			return fmt.Errorf("failed to create cache directory: %w", err)
+		}
+		if err := linkOrCopy(path, blob); err != nil {
+			return fmt.Errorf("failed to add %s to cache: %w", path, err)
+		}
+	}
+
+	err := s.update(func(entries []domain.CacheEntry) ([]domain.CacheEntry, error) {
+		// One entry per URL: a changed file replaces the previous one
+		entry.LastUsed = s.now()
+		replaced := false
+		for i := range entries {
+			if entries[i].URL == entry.URL {
+				entries[i] = entry
+				replaced = true
+			}
+		}
+		if !replaced {
+			entries = append(entries, entry)
+		}
+		return entries, nil
+	})
+	if err != nil {
+		return err
+	}
+
+	if s.maxSize > 0 {
+		_, err = s.Prune(s.maxSize)
+	}
+	return err
+}
+
+func (s *Store) Evict(sha256 string) error {
+	return s.update(func(entries []domain.CacheEntry) ([]domain.CacheEntry, error) {
+		kept := entries[:0]
+		for _, entry := range entries {
+			if entry.SHA256 != sha256 {
+				kept = append(kept, entry)
+			}
+		}
+		if err := os.Remove(s.blobPath(sha256)); err != nil && !os.IsNotExist(err) {
+			return nil, fmt.Errorf("failed to remove cached file: %w", err)
+		}
+		return kept, nil
+	})
+}
+
+func (s *Store) List() ([]domain.CacheEntry, error) {
+	return s.load()
+}
+
+// Prune evicts files in least recently used order until at most maxSize
+// bytes remain. A file referenced by several URLs counts once. Files missing
+// from the index are removed as well.
+func (s *Store) Prune(maxSize int64) ([]domain.CacheEntry, error) {
+	var kept, evicted []domain.CacheEntry
+	err := s.update(func(entries []domain.CacheEntry) ([]domain.CacheEntry, error) {
+		var blobs []*blob
+		bySHA := make(map[string]*blob)
+		var total int64
+		for _, entry := range entries {
+			b, ok := bySHA[entry.SHA256]
+			if !ok {
+				b = &blob{sha256: entry.SHA256, size: entry.Size}
+				bySHA[entry.SHA256] = b
+				blobs = append(blobs, b)
+				total += entry.Size
+			}
+			if entry.LastUsed.After(b.lastUsed) {
+				b.lastUsed = entry.LastUsed
+			}
+		}
+
+		sort.SliceStable(blobs, func(i, j int) bool {
+			return blobs[i].lastUsed.Before(blobs[j].lastUsed)
+		})
+
+		evict := make(map[string]bool)
+		for _, b := range blobs {
+			if total <= maxSize {
+				break
+			}
+			if err := os.Remove(s.blobPath(b.sha256)); err != nil && !os.IsNotExist(err) {
+				return nil, fmt.Errorf("failed to remove cached file: %w", err)
+			}
+			evict[b.sha256] = true
+			total -= b.size
+		}
+
+		for _, entry := range entries {
+			if evict[entry.SHA256] {
+				evicted = append(evicted, entry)
+			} else {
+				kept = append(kept, entry)
+			}
+		}
+		return kept, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return evicted, s.removeOrphans(kept)
+}
+
+type blob struct {
+	sha256   string
+	size     int64
+	lastUsed time.Time
+}
+
+// orphanGracePeriod protects files a concurrent run has stored but not yet
+// indexed.
+const orphanGracePeriod = time.Hour
+
+// removeOrphans deletes blobs which no index entry refers to, left behind by
+// replaced entries or interrupted runs.
+func (s *Store) removeOrphans(entries []domain.CacheEntry) error {
+	known := make(map[string]bool)
+	for _, entry := range entries {
+		known[entry.SHA256] = true
+	}
+
+	root := filepath.Join(s.dir, "blobs")
+	err := filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() || known[d.Name()] {
+			return err
+		}
+		info, err := d.Info()
+		if err != nil || s.now().Sub(info.ModTime()) < orphanGracePeriod {
+			return nil
+		}
+		return os.Remove(path)
+	})
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return fmt.Errorf("failed to remove orphaned files: %w", err)
+	}
+	return nil
+}
+
+// update applies change to the index while holding the index lock.
+func (s *Store) update(change func([]domain.CacheEntry) ([]domain.CacheEntry, error)) error {
+	unlock, err := s.lock()
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	entries, err := s.load()
+	if err != nil {
+		return err
+	}
+	if entries, err = change(entries); err != nil {
+		return err
+	}
+	return s.save(entries)
+}
+
+// lock creates the lock file exclusively, waiting while another run holds
+// it. A lock older than staleLockAge is removed first.
+func (s *Store) lock() (unlock func(), err error) {
+	if err := os.MkdirAll(s.dir, 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create cache directory: %w", err)
+	}
+	path := filepath.Join(s.dir, lockFile)
+	deadline := time.Now().Add(lockTimeout)
+	for {
+		file, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o644)
+		if err == nil {
+			_ = file.Close()
+			return func() { _ = os.Remove(path) }, nil
+		}
+		if !errors.Is(err, fs.ErrExist) {
+			return nil, fmt.Errorf("failed to lock cache index: %w", err)
+		}
+
+		if info, err := os.Stat(path); err == nil && time.Since(info.ModTime()) > staleLockAge {
+			_ = os.Remove(path)
+			continue
+		}
+		if time.Now().After(deadline) {
+			return nil, fmt.Errorf("failed to lock cache index: %s is held by another run", path)
+		}
+		time.Sleep(lockRetryInterval)
+	}
+}
+
+func (s *Store) load() ([]domain.CacheEntry, error) {
+	data, err := os.ReadFile(filepath.Join(s.dir, indexFile))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read cache index: %w", err)
+	}
+
+	var entries []domain.CacheEntry
+	if err := json.Unmarshal(data, &entries); err != nil {
+		return nil, fmt.Errorf("failed to parse cache index: %w", err)
+	}
+	return entries, nil
+}
+
+// save replaces the index atomically so concurrent readers never see a
+// truncated file. Writers hold the index lock.
+func (s *Store) save(entries []domain.CacheEntry) error {
+	if entries == nil {
+		entries = []domain.CacheEntry{}
+	}
+	data, err := json.MarshalIndent(entries, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode cache index: %w", err)
+	}
+
+	if err := writeAtomic(filepath.Join(s.dir, indexFile), data); err != nil {
+		return fmt.Errorf("failed to write cache index: %w", err)
+	}
+	return nil
+}
+
+// writeAtomic writes data to a temporary file and renames it to path.
+func writeAtomic(path string, data []byte) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".*")
+	if err != nil {
+		return err
+	}
+	_, err = tmp.Write(data)
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Chmod(tmp.Name(), 0o644)
+	}
+	if err == nil {
+		err = os.Rename(tmp.Name(), path)
+	}
+	if err != nil {
+		_ = os.Remove(tmp.Name())
+	}
+	return err
+}
+
+// linkOrCopy hard-links src to dst, falling back to a copy across file
+// systems. Copies are renamed into place once complete.
+func linkOrCopy(src, dst string) error {
+	if err := os.Link(src, dst); err == nil {
+		return nil
+	}
+
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer func(in *os.File) {
+		_ = in.Close()
+	}(in)
+
+	out, err := os.CreateTemp(filepath.Dir(dst), "."+filepath.Base(dst)+".*")
+	if err != nil {
+		return err
+	}
+	_, err = io.Copy(out, in)
+	if closeErr := out.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Chmod(out.Name(), 0o644)
+	}
+	if err == nil {
+		err = os.Rename(out.Name(), dst)
+	}
+	if err != nil {
+		_ = os.Remove(out.Name())
+	}
+	return err
+}
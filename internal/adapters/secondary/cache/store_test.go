@@ -0,0 +1,220 @@
+package cache
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"hufschlaeger.net/gitlab-downloader/internal/core/domain"
+)
+
+const (
+	shaA = "aaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa"
+	shaB = "bbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbb"
+	shaC = "cccccccccccccccccccccccccccccccccccccccccccccccccccccccccccccccc"
+)
+
+// newTestStore returns a store whose clock advances by a minute per call.
+func newTestStore(t *testing.T) *Store {
+	t.Helper()
+	clock := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	s := NewStore(t.TempDir())
+	s.now = func() time.Time {
+		clock = clock.Add(time.Minute)
+		return clock
+	}
+	return s
+}
+
+func writeFile(t *testing.T, dir, name, content string) string {
+	t.Helper()
+	path := filepath.Join(dir, name)
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatalf("setup failed: %v", err)
+	}
+	return path
+}
+
+func TestStore_StoreLookupMaterialize(t *testing.T) {
+	s := newTestStore(t)
+	work := t.TempDir()
+	src := writeFile(t, work, "app.zip", "DATA")
+
+	entry := domain.CacheEntry{URL: "https://example.com/app.zip", Validator: `"v1"`, SHA256: shaA, Size: 4}
+	if err := s.Store(entry, src); err != nil {
+		t.Fatalf("store failed: %v", err)
+	}
+
+	for _, tc := range []struct {
+		name                   string
+		url, validator, sha256 string
+		hit                    bool
+	}{
+		{"by sha256", "https://other.example.com/x", "", shaA, true},
+		{"by url and validator", entry.URL, `"v1"`, "", true},
+		{"changed validator", entry.URL, `"v2"`, "", false},
+		{"any validator", entry.URL, "", "", true},
+		{"unknown sha256", entry.URL, `"v1"`, shaB, false},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := s.Lookup(tc.url, tc.validator, tc.sha256)
+			if err != nil {
+				t.Fatalf("lookup failed: %v", err)
+			}
+			if (got != nil) != tc.hit {
+				t.Fatalf("expected hit=%v, got %+v", tc.hit, got)
+			}
+		})
+	}
+
+	hit, _ := s.Lookup("", "", shaA)
+	dst := filepath.Join(work, "out", "copy.zip")
+	_ = os.MkdirAll(filepath.Dir(dst), 0o755)
+	writeFile(t, filepath.Dir(dst), "copy.zip", "OLD")
+	if err := s.Materialize(hit, dst); err != nil {
+		t.Fatalf("materialize failed: %v", err)
+	}
+	if data, _ := os.ReadFile(dst); string(data) != "DATA" {
+		t.Fatalf("expected cached content, got %q", data)
+	}
+
+	entries, _ := s.List()
+	if len(entries) != 1 || !entries[0].LastUsed.After(hit.LastUsed) {
+		t.Fatalf("expected last use to be updated, got %+v", entries)
+	}
+}
+
+func TestStore_LookupIgnoresMissingBlob(t *testing.T) {
+	s := newTestStore(t)
+	src := writeFile(t, t.TempDir(), "app.zip", "DATA")
+	if err := s.Store(domain.CacheEntry{URL: "u", SHA256: shaA, Size: 4}, src); err != nil {
+		t.Fatalf("store failed: %v", err)
+	}
+	_ = os.Remove(s.blobPath(shaA))
+
+	if got, _ := s.Lookup("u", "", shaA); got != nil {
+		t.Fatalf("expected miss, got %+v", got)
+	}
+}
+
+func TestStore_StoreReplacesEntryPerURL(t *testing.T) {
+	s := newTestStore(t)
+	work := t.TempDir()
+	_ = s.Store(domain.CacheEntry{URL: "u", Validator: "1", SHA256: shaA, Size: 1}, writeFile(t, work, "a", "A"))
+	_ = s.Store(domain.CacheEntry{URL: "u", Validator: "2", SHA256: shaB, Size: 1}, writeFile(t, work, "b", "B"))
+
+	entries, _ := s.List()
+	if len(entries) != 1 || entries[0].SHA256 != shaB {
+		t.Fatalf("expected a single updated entry, got %+v", entries)
+	}
+}
+
+func TestStore_PruneEvictsLeastRecentlyUsed(t *testing.T) {
+	s := newTestStore(t)
+	work := t.TempDir()
+	_ = s.Store(domain.CacheEntry{URL: "a", SHA256: shaA, Size: 4}, writeFile(t, work, "a", "AAAA"))
+	_ = s.Store(domain.CacheEntry{URL: "b", SHA256: shaB, Size: 4}, writeFile(t, work, "b", "BBBB"))
+	_ = s.Store(domain.CacheEntry{URL: "c", SHA256: shaC, Size: 4}, writeFile(t, work, "c", "CCCC"))
+	_ = s.Store(domain.CacheEntry{URL: "a-mirror", SHA256: shaA, Size: 4}, writeFile(t, work, "a2", "AAAA"))
+
+	// a is the most recently used, b the least
+	evicted, err := s.Prune(5)
+	if err != nil {
+		t.Fatalf("prune failed: %v", err)
+	}
+	if len(evicted) != 2 || evicted[0].URL != "b" || evicted[1].URL != "c" {
+		t.Fatalf("expected b and c to be evicted, got %+v", evicted)
+	}
+	for sha, exists := range map[string]bool{shaA: true, shaB: false, shaC: false} {
+		if _, err := os.Stat(s.blobPath(sha)); (err == nil) != exists {
+			t.Fatalf("blob %s: expected exists=%v, got err=%v", sha[:1], exists, err)
+		}
+	}
+
+	entries, _ := s.List()
+	if len(entries) != 2 {
+		t.Fatalf("expected both entries of a to remain, got %+v", entries)
+	}
+}
+
+func TestStore_WithMaxSizeEvictsOnStore(t *testing.T) {
+	s := newTestStore(t).WithMaxSize(6)
+	work := t.TempDir()
+	_ = s.Store(domain.CacheEntry{URL: "a", SHA256: shaA, Size: 4}, writeFile(t, work, "a", "AAAA"))
+	_ = s.Store(domain.CacheEntry{URL: "b", SHA256: shaB, Size: 4}, writeFile(t, work, "b", "BBBB"))
+
+	entries, _ := s.List()
+	if len(entries) != 1 || entries[0].URL != "b" {
+		t.Fatalf("expected only b to remain, got %+v", entries)
+	}
+}
+
+func TestStore_PruneRemovesOldOrphans(t *testing.T) {
+	s := newTestStore(t)
+	orphan := s.blobPath(shaC)
+	_ = os.MkdirAll(filepath.Dir(orphan), 0o755)
+	writeFile(t, filepath.Dir(orphan), shaC, "CCCC")
+	old := time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)
+	_ = os.Chtimes(orphan, old, old)
+
+	if _, err := s.Prune(1 << 20); err != nil {
+		t.Fatalf("prune failed: %v", err)
+	}
+	if _, err := os.Stat(orphan); !os.IsNotExist(err) {
+		t.Fatalf("expected orphaned blob to be removed, got %v", err)
+	}
+}
+
+func TestStore_ConcurrentStoresKeepEveryEntry(t *testing.T) {
+	dir := t.TempDir()
+	work := t.TempDir()
+	src := writeFile(t, work, "app.zip", "DATA")
+
+	const runs = 20
+	var wg sync.WaitGroup
+	errs := make(chan error, runs)
+	for i := 0; i < runs; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			// Separate stores, like separate CI jobs sharing the directory
+			entry := domain.CacheEntry{URL: fmt.Sprintf("https://example.com/%d.zip", i), SHA256: shaA, Size: 4}
+			errs <- NewStore(dir).Store(entry, src)
+		}(i)
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		if err != nil {
+			t.Fatalf("store failed: %v", err)
+		}
+	}
+
+	entries, err := NewStore(dir).List()
+	if err != nil {
+		t.Fatalf("list failed: %v", err)
+	}
+	if len(entries) != runs {
+		t.Fatalf("expected %d entries, got %d", runs, len(entries))
+	}
+	if _, err := os.Stat(filepath.Join(dir, lockFile)); !os.IsNotExist(err) {
+		t.Fatalf("expected the lock to be released, got %v", err)
+	}
+}
+
+func TestStore_BreaksStaleLock(t *testing.T) {
+	s := newTestStore(t)
+	lock := writeFile(t, s.dir, lockFile, "")
+	old := time.Now().Add(-2 * staleLockAge)
+	if err := os.Chtimes(lock, old, old); err != nil {
+		t.Fatalf("setup failed: %v", err)
+	}
+
+	src := writeFile(t, t.TempDir(), "app.zip", "DATA")
+	if err := s.Store(domain.CacheEntry{URL: "https://example.com/app.zip", SHA256: shaA, Size: 4}, src); err != nil {
+		t.Fatalf("expected stale lock to be broken, got %v", err)
+	}
+}
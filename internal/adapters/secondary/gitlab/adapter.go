@@ -2,28 +2,46 @@ package gitlab
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
 	"net/url"
+	"strings"
+	"time"
 
+	"hufschlaeger.net/gitlab-downloader/internal/adapters/secondary/retry"
 	"hufschlaeger.net/gitlab-downloader/internal/core/domain"
+	"hufschlaeger.net/gitlab-downloader/internal/core/ports"
 )
 
 type Adapter struct {
 	baseURL    string
 	token      string
 	httpClient *http.Client
+	retry      retry.Policy
 }
 
 func NewAdapter(baseURL, token string, httpClient *http.Client) *Adapter {
 	return &Adapter{
-		baseURL:    baseURL,
+		baseURL:    strings.TrimSuffix(baseURL, "/"),
 		token:      token,
 		httpClient: httpClient,
 	}
 }
 
+// WithRetryPolicy enables retries of API calls failing with connection
+// errors or transient HTTP status codes.
+func (a *Adapter) WithRetryPolicy(policy retry.Policy) *Adapter {
+	a.retry = policy
+	return a
+}
+
+// BaseURL returns the GitLab instance URL without trailing slash.
+func (a *Adapter) BaseURL() string {
+	return a.baseURL
+}
+
 func (a *Adapter) GetProject(name string) (*domain.Project, error) {
 	encodedName := url.PathEscape(name)
 	url := fmt.Sprintf("%s/api/v4/projects/%s", a.baseURL, encodedName)
@@ -40,7 +58,7 @@ func (a *Adapter) GetProject(name string) (*domain.Project, error) {
 }
 
 func (a *Adapter) GetRelease(projectID int, tag string) (*domain.Release, error) {
-	url := fmt.Sprintf("%s/api/v4/projects/%d/releases/%s", a.baseURL, projectID, tag)
+	url := fmt.Sprintf("%s/api/v4/projects/%d/releases/%s", a.baseURL, projectID, url.PathEscape(tag))
 
 	var response releaseResponse
 	if err := a.doRequest(url, &response); err != nil {
@@ -50,17 +68,90 @@ func (a *Adapter) GetRelease(projectID int, tag string) (*domain.Release, error)
 	return a.mapToRelease(projectID, &response), nil
 }
 
+// TagExists reports whether the project's repository has tag.
+func (a *Adapter) TagExists(projectID int, tag string) (bool, error) {
+	url := fmt.Sprintf("%s/api/v4/projects/%d/repository/tags/%s", a.baseURL, projectID, url.PathEscape(tag))
+
+	var response struct {
+		Name string `json:"name"`
+	}
+	err := a.doRequest(url, &response)
+	if errors.Is(err, ports.ErrNotFound) {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+// ListReleases visits the releases of a project, newest release date first,
+// following GitLab's pagination until visit returns false.
+func (a *Adapter) ListReleases(projectID int, visit func(domain.Release) bool) error {
+	next := fmt.Sprintf("%s/api/v4/projects/%d/releases?order_by=released_at&sort=desc&per_page=100", a.baseURL, projectID)
+	for next != "" {
+		var response []releaseResponse
+		header, err := a.get(next, &response)
+		if err != nil {
+			return err
+		}
+
+		for i := range response {
+			if !visit(*a.mapToRelease(projectID, &response[i])) {
+				return nil
+			}
+		}
+		next = nextPageURL(next, header)
+	}
+
+	return nil
+}
+
+// nextPageURL returns the URL of the page after current, taken from the
+// Link header or, without one, from the X-Next-Page header. It returns an
+// empty string on the last page.
+func nextPageURL(current string, header http.Header) string {
+	for _, link := range strings.Split(header.Get("Link"), ",") {
+		target, params, ok := strings.Cut(link, ";")
+		if !ok {
+			continue
+		}
+		for _, param := range strings.Split(params, ";") {
+			if strings.TrimSpace(param) == `rel="next"` {
+				return strings.Trim(strings.TrimSpace(target), "<>")
+			}
+		}
+	}
+
+	page := header.Get("X-Next-Page")
+	if page == "" {
+		return ""
+	}
+	u, err := url.Parse(current)
+	if err != nil {
+		return ""
+	}
+	query := u.Query()
+	query.Set("page", page)
+	u.RawQuery = query.Encode()
+	return u.String()
+}
+
 func (a *Adapter) doRequest(url string, result interface{}) error {
+	_, err := a.get(url, result)
+	return err
+}
+
+// get decodes the JSON response of url into result and returns the
+// response headers.
+func (a *Adapter) get(url string, result interface{}) (http.Header, error) {
 	req, err := http.NewRequest("GET", url, nil)
 	if err != nil {
-		return fmt.Errorf("failed to create request: %w", err)
+		return nil, fmt.Errorf("failed to create request: %w", err)
 	}
 
 	req.Header.Set("PRIVATE-TOKEN", a.token)
 
-	resp, err := a.httpClient.Do(req)
+	resp, err := a.doWithRetry(req)
 	if err != nil {
-		return fmt.Errorf("request failed: %w", err)
+		return nil, fmt.Errorf("request failed: %w", err)
 	}
 	defer func(Body io.ReadCloser) {
 		err := Body.Close()
@@ -70,26 +161,74 @@ func (a *Adapter) doRequest(url string, result interface{}) error {
 	}(resp.Body)
 
 	if resp.StatusCode != http.StatusOK {
-		return fmt.Errorf("HTTP %d: %s", resp.StatusCode, resp.Status)
+		return nil, &statusError{code: resp.StatusCode, status: resp.Status}
 	}
 
 	if err := json.NewDecoder(resp.Body).Decode(result); err != nil {
-		return fmt.Errorf("failed to decode response: %w", err)
+		return nil, fmt.Errorf("failed to decode response: %w", err)
 	}
 
-	return nil
+	return resp.Header, nil
+}
+
+// doWithRetry sends req, retrying connection errors and retryable status
+// codes according to the adapter's retry policy. The last response or error
+// is returned once the retries are exhausted.
+func (a *Adapter) doWithRetry(req *http.Request) (*http.Response, error) {
+	for attempt := 0; ; attempt++ {
+		resp, err := a.httpClient.Do(req)
+		if err == nil && !retry.RetryableStatus(resp.StatusCode) {
+			return resp, nil
+		}
+		if !a.retry.ShouldRetry(attempt) {
+			return resp, err
+		}
+
+		if resp != nil {
+			_, _ = io.Copy(io.Discard, resp.Body)
+			_ = resp.Body.Close()
+		}
+		a.retry.Wait(attempt, resp)
+	}
+}
+
+// statusError reports an unexpected HTTP status. A 404 matches
+// ports.ErrNotFound.
+type statusError struct {
+	code   int
+	status string
+}
+
+func (e *statusError) Error() string {
+	return fmt.Sprintf("HTTP %d: %s", e.code, e.status)
+}
+
+func (e *statusError) Is(target error) bool {
+	return target == ports.ErrNotFound && e.code == http.StatusNotFound
 }
 
 func (a *Adapter) mapToRelease(projectID int, response *releaseResponse) *domain.Release {
 	release := &domain.Release{
-		ProjectID: projectID,
-		Tag:       response.TagName,
+		ProjectID:   projectID,
+		Tag:         response.TagName,
+		Name:        response.Name,
+		Description: response.Description,
+		ReleasedAt:  response.ReleasedAt,
+		Upcoming:    response.UpcomingRelease,
+		CommitSHA:   response.Commit.ID,
+	}
+
+	for _, milestone := range response.Milestones {
+		release.Milestones = append(release.Milestones, milestone.Title)
 	}
 
 	for _, link := range response.Assets.Links {
 		release.Assets.Links = append(release.Assets.Links, domain.Link{
-			Name: link.Name,
-			URL:  link.URL,
+			ID:             link.ID,
+			Name:           link.Name,
+			URL:            link.URL,
+			DirectAssetURL: link.DirectAssetURL,
+			LinkType:       link.LinkType,
 		})
 	}
 
@@ -110,11 +249,24 @@ type projectResponse struct {
 }
 
 type releaseResponse struct {
-	TagName string `json:"tag_name"`
-	Assets  struct {
+	TagName         string    `json:"tag_name"`
+	Name            string    `json:"name"`
+	Description     string    `json:"description"`
+	ReleasedAt      time.Time `json:"released_at"`
+	UpcomingRelease bool      `json:"upcoming_release"`
+	Commit          struct {
+		ID string `json:"id"`
+	} `json:"commit"`
+	Milestones []struct {
+		Title string `json:"title"`
+	} `json:"milestones"`
+	Assets struct {
 		Links []struct {
-			Name string `json:"name"`
-			URL  string `json:"url"`
+			ID             int    `json:"id"`
+			Name           string `json:"name"`
+			URL            string `json:"url"`
+			DirectAssetURL string `json:"direct_asset_url"`
+			LinkType       string `json:"link_type"`
 		} `json:"links"`
 		Sources []struct {
 			Format string `json:"format"`
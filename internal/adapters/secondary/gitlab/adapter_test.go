@@ -2,10 +2,16 @@ package gitlab
 
 import (
 	"encoding/json"
+	"errors"
 	"net/http"
 	"net/http/httptest"
 	"strings"
 	"testing"
+	"time"
+
+	"hufschlaeger.net/gitlab-downloader/internal/adapters/secondary/retry"
+	"hufschlaeger.net/gitlab-downloader/internal/core/domain"
+	"hufschlaeger.net/gitlab-downloader/internal/core/ports"
 )
 
 func TestGetProject_Success(t *testing.T) {
@@ -69,18 +75,19 @@ func TestGetRelease_Success(t *testing.T) {
 		if !strings.Contains(r.URL.Path, "/api/v4/projects/77/releases/v1.2.3") {
 			t.Fatalf("unexpected path: %s", r.URL.Path)
 		}
-		resp := releaseResponse{
-			TagName: "v1.2.3",
-		}
-		resp.Assets.Links = append(resp.Assets.Links, struct {
-			Name string `json:"name"`
-			URL  string `json:"url"`
-		}{Name: "bin", URL: "https://example.com/bin.zip"})
-		resp.Assets.Sources = append(resp.Assets.Sources, struct {
-			Format string `json:"format"`
-			URL    string `json:"url"`
-		}{Format: "zip", URL: "https://example.com/src.zip"})
-		_ = json.NewEncoder(w).Encode(resp)
+		_, _ = w.Write([]byte(`{
+			"tag_name": "v1.2.3",
+			"name": "Release 1.2.3",
+			"description": "Fixes",
+			"released_at": "2024-03-10T12:00:00.000Z",
+			"upcoming_release": true,
+			"commit": {"id": "0123abcd", "short_id": "0123abc"},
+			"milestones": [{"title": "1.2"}, {"title": "Q1"}],
+			"assets": {
+				"links": [{"id": 5, "name": "bin", "url": "https://example.com/bin.zip", "direct_asset_url": "https://gitlab.example.com/g/p/-/releases/v1.2.3/downloads/bin.zip", "link_type": "package"}],
+				"sources": [{"format": "zip", "url": "https://example.com/src.zip"}]
+			}
+		}`))
 	}))
 	defer ts.Close()
 
@@ -98,6 +105,12 @@ func TestGetRelease_Success(t *testing.T) {
 	if len(rel.Assets.Sources) != 1 || rel.Assets.Sources[0].Format != "zip" || rel.Assets.Sources[0].URL != "https://example.com/src.zip" {
 		t.Fatalf("unexpected sources: %+v", rel.Assets.Sources)
 	}
+	if rel.Name != "Release 1.2.3" || rel.Description != "Fixes" || !rel.Upcoming || rel.CommitSHA != "0123abcd" || strings.Join(rel.Milestones, ",") != "1.2,Q1" {
+		t.Fatalf("unexpected metadata: %+v", rel)
+	}
+	if link := rel.Assets.Links[0]; link.ID != 5 || link.LinkType != "package" || !strings.HasSuffix(link.DirectAssetURL, "/downloads/bin.zip") {
+		t.Fatalf("unexpected link metadata: %+v", link)
+	}
 }
 
 func TestGetRelease_HTTPError(t *testing.T) {
@@ -114,6 +127,64 @@ func TestGetRelease_HTTPError(t *testing.T) {
 	}
 }
 
+func TestGetRelease_NotFound(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+	}))
+	defer ts.Close()
+
+	a := NewAdapter(ts.URL, "tok", ts.Client())
+	_, err := a.GetRelease(77, "v1.2.3")
+	if !errors.Is(err, ports.ErrNotFound) || !strings.Contains(err.Error(), "HTTP 404") {
+		t.Fatalf("expected not found error, got %v", err)
+	}
+}
+
+func TestGetRelease_EscapesSlashTag(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.EscapedPath() != "/api/v4/projects/1/releases/release%2F1.0" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		_, _ = w.Write([]byte(`{"name": "Release 1.0", "tag_name": "release/1.0"}`))
+	}))
+	defer ts.Close()
+
+	a := NewAdapter(ts.URL, "tok", ts.Client())
+	rel, err := a.GetRelease(1, "release/1.0")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if rel.Tag != "release/1.0" {
+		t.Fatalf("unexpected tag %q", rel.Tag)
+	}
+}
+
+func TestTagExists(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.EscapedPath() {
+		case "/api/v4/projects/77/repository/tags/release%2F1.0":
+			_, _ = w.Write([]byte(`{"name": "release/1.0"}`))
+		case "/api/v4/projects/77/repository/tags/v9":
+			w.WriteHeader(http.StatusNotFound)
+		default:
+			w.WriteHeader(http.StatusForbidden)
+		}
+	}))
+	defer ts.Close()
+
+	a := NewAdapter(ts.URL, "tok", ts.Client())
+	if ok, err := a.TagExists(77, "release/1.0"); !ok || err != nil {
+		t.Fatalf("expected existing tag, got %v, %v", ok, err)
+	}
+	if ok, err := a.TagExists(77, "v9"); ok || err != nil {
+		t.Fatalf("expected missing tag, got %v, %v", ok, err)
+	}
+	if _, err := a.TagExists(1, "v1"); err == nil || errors.Is(err, ports.ErrNotFound) {
+		t.Fatalf("expected HTTP 403 error, got %v", err)
+	}
+}
+
 func TestGetRelease_BadJSON(t *testing.T) {
 	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		w.WriteHeader(200)
@@ -127,3 +198,168 @@ func TestGetRelease_BadJSON(t *testing.T) {
 		t.Fatalf("expected decode error, got %v", err)
 	}
 }
+
+func listAll(a *Adapter, projectID int) ([]domain.Release, error) {
+	var releases []domain.Release
+	err := a.ListReleases(projectID, func(release domain.Release) bool {
+		releases = append(releases, release)
+		return true
+	})
+	return releases, err
+}
+
+func TestListReleases_Success(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/v4/projects/77/releases" {
+			t.Fatalf("unexpected path: %s", r.URL.Path)
+		}
+		_, _ = w.Write([]byte(`[
+			{"tag_name": "v1.1.0", "assets": {"links": [{"name": "bin", "url": "https://example.com/bin.zip"}]}},
+			{"tag_name": "v1.0.0"}
+		]`))
+	}))
+	defer ts.Close()
+
+	a := NewAdapter(ts.URL, "tok", ts.Client())
+	releases, err := listAll(a, 77)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(releases) != 2 || releases[0].Tag != "v1.1.0" || releases[1].Tag != "v1.0.0" {
+		t.Fatalf("unexpected releases: %+v", releases)
+	}
+	if releases[0].ProjectID != 77 || len(releases[0].Assets.Links) != 1 {
+		t.Fatalf("unexpected mapping: %+v", releases[0])
+	}
+}
+
+func TestListReleases_HTTPError(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(403)
+	}))
+	defer ts.Close()
+
+	a := NewAdapter(ts.URL, "tok", ts.Client())
+	_, err := listAll(a, 1)
+	if err == nil || !strings.Contains(err.Error(), "HTTP 403") {
+		t.Fatalf("expected HTTP 403 error, got %v", err)
+	}
+}
+
+func TestGetProject_RetriesTransientErrors(t *testing.T) {
+	calls := 0
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		switch calls {
+		case 1:
+			w.WriteHeader(http.StatusBadGateway)
+		case 2:
+			w.Header().Set("Retry-After", "0")
+			w.WriteHeader(http.StatusTooManyRequests)
+		default:
+			_ = json.NewEncoder(w).Encode(projectResponse{ID: 5, Name: "proj"})
+		}
+	}))
+	defer ts.Close()
+
+	var waits []time.Duration
+	policy := retry.Policy{MaxRetries: 3, BaseDelay: time.Millisecond, Sleep: func(d time.Duration) { waits = append(waits, d) }}
+	a := NewAdapter(ts.URL, "tok", ts.Client()).WithRetryPolicy(policy)
+	proj, err := a.GetProject("group/proj")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if proj.ID != 5 || calls != 3 || len(waits) != 2 {
+		t.Fatalf("unexpected result: proj=%+v calls=%d waits=%v", proj, calls, waits)
+	}
+	if waits[1] != 0 {
+		t.Fatalf("expected Retry-After to be honored, got %v", waits[1])
+	}
+}
+
+func TestGetProject_GivesUpAfterMaxRetries(t *testing.T) {
+	calls := 0
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer ts.Close()
+
+	policy := retry.Policy{MaxRetries: 2, Sleep: func(time.Duration) {}}
+	a := NewAdapter(ts.URL, "tok", ts.Client()).WithRetryPolicy(policy)
+	_, err := a.GetProject("group/proj")
+	if err == nil || !strings.Contains(err.Error(), "HTTP 503") {
+		t.Fatalf("expected HTTP 503 error, got %v", err)
+	}
+	if calls != 3 {
+		t.Fatalf("expected 3 attempts, got %d", calls)
+	}
+}
+
+func TestBaseURL_TrimsTrailingSlash(t *testing.T) {
+	a := NewAdapter("https://gitlab.example.com/", "tok", http.DefaultClient)
+	if a.BaseURL() != "https://gitlab.example.com" {
+		t.Fatalf("unexpected base URL: %q", a.BaseURL())
+	}
+}
+
+func TestListReleases_FollowsPagination(t *testing.T) {
+	var ts *httptest.Server
+	ts = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Query().Get("page") {
+		case "":
+			w.Header().Set("X-Next-Page", "2")
+			_, _ = w.Write([]byte(`[{"tag_name": "v3.0.0", "name": "Three", "released_at": "2024-03-10T12:00:00.000Z"}]`))
+		case "2":
+			if r.URL.Query().Get("per_page") != "100" {
+				t.Fatalf("expected per_page to be kept, got %s", r.URL.RawQuery)
+			}
+			w.Header().Set("Link", `<`+ts.URL+`/api/v4/projects/77/releases?page=3&per_page=100>; rel="next", <`+ts.URL+`/api/v4/projects/77/releases?page=1&per_page=100>; rel="first"`)
+			_, _ = w.Write([]byte(`[{"tag_name": "v2.0.0"}]`))
+		case "3":
+			w.Header().Set("X-Next-Page", "")
+			_, _ = w.Write([]byte(`[{"tag_name": "v1.0.0"}]`))
+		default:
+			t.Fatalf("unexpected page %s", r.URL.RawQuery)
+		}
+	}))
+	defer ts.Close()
+
+	a := NewAdapter(ts.URL, "tok", ts.Client())
+	releases, err := listAll(a, 77)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(releases) != 3 || releases[0].Tag != "v3.0.0" || releases[2].Tag != "v1.0.0" {
+		t.Fatalf("unexpected releases: %+v", releases)
+	}
+	if releases[0].Name != "Three" || !releases[0].ReleasedAt.Equal(time.Date(2024, 3, 10, 12, 0, 0, 0, time.UTC)) {
+		t.Fatalf("unexpected mapping: %+v", releases[0])
+	}
+}
+
+func TestListReleases_StopsFetchingPages(t *testing.T) {
+	var pages []string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("order_by") != "released_at" || r.URL.Query().Get("sort") != "desc" {
+			t.Fatalf("expected releases ordered by release date, got %s", r.URL.RawQuery)
+		}
+		pages = append(pages, r.URL.Query().Get("page"))
+		w.Header().Set("X-Next-Page", "2")
+		_, _ = w.Write([]byte(`[{"tag_name": "v3.0.0"}, {"tag_name": "v2.0.0"}]`))
+	}))
+	defer ts.Close()
+
+	a := NewAdapter(ts.URL, "tok", ts.Client())
+	var tags []string
+	err := a.ListReleases(77, func(release domain.Release) bool {
+		tags = append(tags, release.Tag)
+		return len(tags) < 2
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(tags) != 2 || len(pages) != 1 {
+		t.Fatalf("expected one page and two releases, got pages %v, releases %v", pages, tags)
+	}
+}
@@ -1,30 +1,242 @@
 package http
 
 import (
+	"errors"
 	"fmt"
 	"io"
+	"mime"
 	"net/http"
+	"os"
+	"path"
+	"strings"
 
-	"github.com/schollz/progressbar/v3"
+	"hufschlaeger.net/gitlab-downloader/internal/adapters/secondary/retry"
+	"hufschlaeger.net/gitlab-downloader/internal/core/domain"
+	"hufschlaeger.net/gitlab-downloader/internal/core/ports"
 )
 
 type DownloadAdapter struct {
-	client *http.Client
+	client   *http.Client
+	retry    retry.Policy
+	progress progress
+	// apiPrefix and token authenticate requests to the GitLab API
+	apiPrefix string
+	token     string
 }
 
 func NewDownloadAdapter(client *http.Client) *DownloadAdapter {
-	return &DownloadAdapter{client: client}
+	return &DownloadAdapter{client: client, progress: newProgress(ProgressAuto, os.Stderr)}
+}
+
+// WithProgress selects how transfers report their progress on stderr.
+func (a *DownloadAdapter) WithProgress(mode ProgressMode) *DownloadAdapter {
+	a.progress = newProgress(mode, os.Stderr)
+	return a
+}
+
+// WithRetryPolicy enables retries of downloads failing with connection
+// errors, transient HTTP status codes or an interrupted body. Interrupted
+// downloads continue with a Range request guarded by If-Range, or start over
+// if the server sent no validator and the writer is a
+// ports.RestartableWriter.
+func (a *DownloadAdapter) WithRetryPolicy(policy retry.Policy) *DownloadAdapter {
+	a.retry = policy
+	return a
+}
+
+// WithToken sends token as PRIVATE-TOKEN with requests to the API of the
+// GitLab instance at baseURL, such as job artifact and repository archive
+// downloads. Other hosts never receive the token, also not when the API
+// redirects to them, e.g. to object storage.
+func (a *DownloadAdapter) WithToken(baseURL, token string) *DownloadAdapter {
+	a.apiPrefix = strings.TrimSuffix(baseURL, "/") + "/api/v4/"
+	a.token = token
+
+	client := *a.client
+	checkRedirect := client.CheckRedirect
+	client.CheckRedirect = func(req *http.Request, via []*http.Request) error {
+		if !strings.HasPrefix(req.URL.String(), a.apiPrefix) {
+			req.Header.Del("PRIVATE-TOKEN")
+		}
+		if checkRedirect != nil {
+			return checkRedirect(req, via)
+		}
+		if len(via) >= 10 {
+			return errors.New("stopped after 10 redirects")
+		}
+		return nil
+	}
+	a.client = &client
+	return a
+}
+
+// newRequest creates a request for url, authenticated if url belongs to
+// the GitLab API.
+func (a *DownloadAdapter) newRequest(method, url string) (*http.Request, error) {
+	req, err := http.NewRequest(method, url, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	if a.token != "" && strings.HasPrefix(url, a.apiPrefix) {
+		req.Header.Set("PRIVATE-TOKEN", a.token)
+	}
+	return req, nil
 }
 
 func (a *DownloadAdapter) DownloadFromURL(url string, writer io.Writer) error {
-	req, err := http.NewRequest("GET", url, nil)
+	_, err := a.DownloadRange(url, 0, "", writer, nil)
+	return err
+}
+
+// DownloadRange downloads url starting at offset. For offset > 0 a Range
+// request is sent, guarded by If-Range when ifRange is set; if the server does
+// not answer with the requested partial content, ports.ErrRangeNotHonored is
+// returned. started learns the validator before the body is written, so it
+// can be stored even if the process dies during the download.
+func (a *DownloadAdapter) DownloadRange(url string, offset int64, ifRange string, writer io.Writer, started func(*domain.DownloadInfo)) (*domain.DownloadInfo, error) {
+	t := &transfer{
+		url:     url,
+		offset:  offset,
+		ifRange: ifRange,
+		writer:  &countingWriter{w: writer},
+		info:    &domain.DownloadInfo{},
+		started: started,
+	}
+
+	for attempt := 0; ; attempt++ {
+		resp, retryable, err := a.fetch(t)
+		if err == nil {
+			if t.meter != nil {
+				t.meter.Done()
+			}
+			return t.info, nil
+		}
+		if !retryable || !a.retry.ShouldRetry(attempt) {
+			if t.meter != nil {
+				t.meter.Fail(err)
+			}
+			return t.info, err
+		}
+		a.retry.Wait(attempt, resp)
+	}
+}
+
+// Validator returns the current ETag or Last-Modified of url using a HEAD
+// request, so cached copies can be revalidated without downloading them.
+func (a *DownloadAdapter) Validator(url string) (string, error) {
+	resp, err := a.head(url)
 	if err != nil {
-		return fmt.Errorf("failed to create request: %w", err)
+		return "", err
+	}
+	return validator(resp), nil
+}
+
+// FileName returns the file name suggested by the Content-Disposition header
+// of url using a HEAD request. Directories in the suggested name are dropped.
+func (a *DownloadAdapter) FileName(url string) (string, error) {
+	resp, err := a.head(url)
+	if err != nil {
+		return "", err
+	}
+
+	_, params, err := mime.ParseMediaType(resp.Header.Get("Content-Disposition"))
+	if err != nil || params["filename"] == "" {
+		return "", nil
+	}
+	return path.Base(strings.ReplaceAll(params["filename"], `\`, "/")), nil
+}
+
+// head sends a HEAD request for url, retrying transient failures. The body
+// of the returned response is closed.
+func (a *DownloadAdapter) head(url string) (*http.Response, error) {
+	for attempt := 0; ; attempt++ {
+		req, err := a.newRequest("HEAD", url)
+		if err != nil {
+			return nil, err
+		}
+
+		resp, err := a.client.Do(req)
+		if err == nil {
+			_ = resp.Body.Close()
+			if resp.StatusCode == http.StatusOK {
+				return resp, nil
+			}
+			err = fmt.Errorf("HTTP %d: %s", resp.StatusCode, resp.Status)
+			if !retry.RetryableStatus(resp.StatusCode) {
+				return nil, err
+			}
+		}
+		if !a.retry.ShouldRetry(attempt) {
+			return nil, err
+		}
+		a.retry.Wait(attempt, resp)
+	}
+}
+
+// transfer holds the state of one download across retry attempts.
+type transfer struct {
+	url     string
+	offset  int64
+	ifRange string
+	writer  *countingWriter
+	info    *domain.DownloadInfo
+	started func(*domain.DownloadInfo)
+	meter   meter
+}
+
+// restart discards the bytes written by previous attempts, so the download
+// starts over. This is only possible for writers implementing
+// ports.RestartableWriter and downloads starting at offset 0.
+func (t *transfer) restart() error {
+	w, ok := t.writer.w.(ports.RestartableWriter)
+	if !ok || t.offset > 0 {
+		return fmt.Errorf("download interrupted and cannot be resumed safely: server sent no ETag or Last-Modified")
+	}
+	if err := w.Restart(); err != nil {
+		return fmt.Errorf("failed to restart download: %w", err)
+	}
+	if t.meter != nil {
+		t.meter.Fail(errors.New("restarting"))
+		t.meter = nil
+	}
+	t.writer.n = 0
+	t.writer.err = nil
+	return nil
+}
+
+// fetch performs a single attempt, continuing after the bytes already
+// written by previous attempts. It reports whether a failure is worth
+// retrying.
+func (a *DownloadAdapter) fetch(t *transfer) (*http.Response, bool, error) {
+	start := t.offset + t.writer.n
+	ifRange := t.ifRange
+	if t.writer.n > 0 {
+		// Continuing an interrupted body: only safe if the content is unchanged
+		if t.info.Validator == "" {
+			if err := t.restart(); err != nil {
+				return nil, false, err
+			}
+			start = t.offset
+		} else {
+			ifRange = t.info.Validator
+		}
+	}
+
+	req, err := a.newRequest("GET", t.url)
+	if err != nil {
+		return nil, false, err
+	}
+
+	if start > 0 {
+		req.Header.Set("Range", fmt.Sprintf("bytes=%d-", start))
+		if ifRange != "" {
+			req.Header.Set("If-Range", ifRange)
+		}
 	}
 
 	resp, err := a.client.Do(req)
 	if err != nil {
-		return fmt.Errorf("request failed: %w", err)
+		return nil, true, fmt.Errorf("request failed: %w", err)
 	}
 	defer func(Body io.ReadCloser) {
 		err := Body.Close()
@@ -33,19 +245,73 @@ func (a *DownloadAdapter) DownloadFromURL(url string, writer io.Writer) error {
 		}
 	}(resp.Body)
 
-	if resp.StatusCode != http.StatusOK {
-		return fmt.Errorf("HTTP %d: %s", resp.StatusCode, resp.Status)
+	if t.writer.n == 0 {
+		t.info.Validator = validator(resp)
+	}
+
+	partial := false
+	if start > 0 {
+		switch {
+		case resp.StatusCode == http.StatusPartialContent:
+			if !strings.HasPrefix(resp.Header.Get("Content-Range"), fmt.Sprintf("bytes %d-", start)) {
+				return resp, false, ports.ErrRangeNotHonored
+			}
+			partial = true
+		case resp.StatusCode == http.StatusOK || resp.StatusCode == http.StatusRequestedRangeNotSatisfiable:
+			return resp, false, ports.ErrRangeNotHonored
+		}
+	}
+
+	if resp.StatusCode != http.StatusOK && !partial {
+		return resp, retry.RetryableStatus(resp.StatusCode), fmt.Errorf("HTTP %d: %s", resp.StatusCode, resp.Status)
+	}
+
+	if t.writer.n == 0 {
+		t.info.Resumed = partial
+		if t.started != nil {
+			t.started(t.info)
+		}
 	}
 
-	bar := progressbar.DefaultBytes(
-		resp.ContentLength,
-		"downloading",
-	)
+	if t.meter == nil {
+		total := resp.ContentLength
+		if total >= 0 {
+			total += start
+		}
+		t.meter = a.progress.start("downloading", t.url, total, start)
+	}
 
-	_, err = io.Copy(io.MultiWriter(writer, bar), resp.Body)
+	_, err = io.Copy(io.MultiWriter(t.writer, t.meter), resp.Body)
 	if err != nil {
-		return fmt.Errorf("download failed: %w", err)
+		// Errors from the destination are final, broken connections are not
+		return resp, !errors.Is(err, t.writer.err), fmt.Errorf("download failed: %w", err)
 	}
 
-	return nil
+	return resp, false, nil
+}
+
+// countingWriter counts the bytes successfully written to w and remembers
+// the error returned by w.
+type countingWriter struct {
+	w   io.Writer
+	n   int64
+	err error
+}
+
+func (c *countingWriter) Write(p []byte) (int, error) {
+	n, err := c.w.Write(p)
+	c.n += int64(n)
+	if err != nil {
+		c.err = err
+	}
+	return n, err
+}
+
+// validator returns the value to send as If-Range when resuming this
+// response: a strong ETag, or else Last-Modified.
+func validator(resp *http.Response) string {
+	if etag := resp.Header.Get("ETag"); etag != "" && !strings.HasPrefix(etag, "W/") {
+		return etag
+	}
+	return resp.Header.Get("Last-Modified")
 }
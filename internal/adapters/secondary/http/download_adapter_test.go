@@ -7,6 +7,11 @@ import (
 	"net/http/httptest"
 	"strings"
 	"testing"
+	"time"
+
+	"hufschlaeger.net/gitlab-downloader/internal/adapters/secondary/retry"
+	"hufschlaeger.net/gitlab-downloader/internal/core/domain"
+	"hufschlaeger.net/gitlab-downloader/internal/core/ports"
 )
 
 type errWriter struct{ wrote int }
@@ -78,3 +83,291 @@ func TestDownloadAdapter_WriterError(t *testing.T) {
 		t.Fatalf("expected download failed due to writer error, got %v", err)
 	}
 }
+
+func TestDownloadAdapter_DownloadRange_Resumes(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Range") != "bytes=4-" || r.Header.Get("If-Range") != `"v1"` {
+			t.Fatalf("unexpected range headers: %v", r.Header)
+		}
+		w.Header().Set("ETag", `"v1"`)
+		w.Header().Set("Content-Range", "bytes 4-9/10")
+		w.WriteHeader(http.StatusPartialContent)
+		_, _ = io.WriteString(w, "456789")
+	}))
+	defer ts.Close()
+
+	a := NewDownloadAdapter(&http.Client{})
+	var buf strings.Builder
+	info, err := a.DownloadRange(ts.URL, 4, `"v1"`, &buf, nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !info.Resumed || info.Validator != `"v1"` || buf.String() != "456789" {
+		t.Fatalf("unexpected result: %+v body=%q", info, buf.String())
+	}
+}
+
+func TestDownloadAdapter_DownloadRange_StartedBeforeBody(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("ETag", `"v2"`)
+		_, _ = io.WriteString(w, "0123456789")
+	}))
+	defer ts.Close()
+
+	a := NewDownloadAdapter(&http.Client{})
+	var buf strings.Builder
+	var validators []string
+	_, err := a.DownloadRange(ts.URL, 0, "", &buf, func(info *domain.DownloadInfo) {
+		if buf.Len() != 0 {
+			t.Fatalf("expected started to be called before the body, got %q", buf.String())
+		}
+		validators = append(validators, info.Validator)
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(validators) != 1 || validators[0] != `"v2"` || buf.String() != "0123456789" {
+		t.Fatalf("unexpected validators %v, body %q", validators, buf.String())
+	}
+}
+
+func TestDownloadAdapter_DownloadRange_NotHonored(t *testing.T) {
+	for _, status := range []int{http.StatusOK, http.StatusRequestedRangeNotSatisfiable} {
+		ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("ETag", `W/"weak"`)
+			w.Header().Set("Last-Modified", "Mon, 02 Jan 2006 15:04:05 GMT")
+			w.WriteHeader(status)
+			_, _ = io.WriteString(w, "0123456789")
+		}))
+
+		a := NewDownloadAdapter(&http.Client{})
+		var buf strings.Builder
+		info, err := a.DownloadRange(ts.URL, 4, "", &buf, nil)
+		ts.Close()
+		if !errors.Is(err, ports.ErrRangeNotHonored) {
+			t.Fatalf("status %d: expected ErrRangeNotHonored, got %v", status, err)
+		}
+		if buf.Len() != 0 {
+			t.Fatalf("status %d: expected nothing written, got %q", status, buf.String())
+		}
+		if info.Validator != "Mon, 02 Jan 2006 15:04:05 GMT" {
+			t.Fatalf("expected Last-Modified fallback for weak ETag, got %q", info.Validator)
+		}
+	}
+}
+
+func TestDownloadAdapter_RetriesTransientStatus(t *testing.T) {
+	calls := 0
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		if calls == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		_, _ = io.WriteString(w, "payload")
+	}))
+	defer ts.Close()
+
+	a := NewDownloadAdapter(&http.Client{}).WithRetryPolicy(retry.Policy{MaxRetries: 2, Sleep: func(time.Duration) {}})
+	var buf strings.Builder
+	if err := a.DownloadFromURL(ts.URL, &buf); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if calls != 2 || buf.String() != "payload" {
+		t.Fatalf("unexpected result: calls=%d body=%q", calls, buf.String())
+	}
+}
+
+func TestDownloadAdapter_ResumesInterruptedBody(t *testing.T) {
+	calls := 0
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		w.Header().Set("ETag", `"v1"`)
+		if calls == 1 {
+			// announce 10 bytes but only send 4: the client sees an unexpected EOF
+			w.Header().Set("Content-Length", "10")
+			_, _ = io.WriteString(w, "0123")
+			return
+		}
+		if r.Header.Get("Range") != "bytes=4-" || r.Header.Get("If-Range") != `"v1"` {
+			t.Errorf("unexpected resume headers: %v", r.Header)
+		}
+		w.Header().Set("Content-Range", "bytes 4-9/10")
+		w.WriteHeader(http.StatusPartialContent)
+		_, _ = io.WriteString(w, "456789")
+	}))
+	defer ts.Close()
+
+	a := NewDownloadAdapter(&http.Client{}).WithRetryPolicy(retry.Policy{MaxRetries: 1, Sleep: func(time.Duration) {}})
+	var buf strings.Builder
+	if err := a.DownloadFromURL(ts.URL, &buf); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if buf.String() != "0123456789" {
+		t.Fatalf("unexpected body: %q", buf.String())
+	}
+}
+
+// restartableBuffer records how often it was asked to start over.
+type restartableBuffer struct {
+	strings.Builder
+	restarts int
+}
+
+func (b *restartableBuffer) Restart() error {
+	b.Reset()
+	b.restarts++
+	return nil
+}
+
+func TestDownloadAdapter_RestartsInterruptedBodyWithoutValidator(t *testing.T) {
+	calls := 0
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		if r.Header.Get("Range") != "" {
+			t.Errorf("unexpected range request: %v", r.Header)
+		}
+		if calls == 1 {
+			w.Header().Set("Content-Length", "10")
+			_, _ = io.WriteString(w, "0123")
+			return
+		}
+		_, _ = io.WriteString(w, "0123456789")
+	}))
+	defer ts.Close()
+
+	a := NewDownloadAdapter(&http.Client{}).WithRetryPolicy(retry.Policy{MaxRetries: 1, Sleep: func(time.Duration) {}})
+	var buf restartableBuffer
+	if err := a.DownloadFromURL(ts.URL, &buf); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if calls != 2 || buf.restarts != 1 || buf.String() != "0123456789" {
+		t.Fatalf("unexpected result: calls=%d restarts=%d body=%q", calls, buf.restarts, buf.String())
+	}
+
+	// Without a restartable writer the download cannot continue safely
+	calls = 0
+	var plain strings.Builder
+	if err := a.DownloadFromURL(ts.URL, &plain); err == nil || !strings.Contains(err.Error(), "cannot be resumed safely") {
+		t.Fatalf("expected unsafe resume error, got %v", err)
+	}
+	if calls != 1 {
+		t.Fatalf("expected a single attempt, got %d", calls)
+	}
+}
+
+func TestDownloadAdapter_RestartsOnlyWithinRetryBudget(t *testing.T) {
+	calls := 0
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		w.Header().Set("Content-Length", "10")
+		_, _ = io.WriteString(w, "0123")
+	}))
+	defer ts.Close()
+
+	a := NewDownloadAdapter(&http.Client{}).WithRetryPolicy(retry.Policy{MaxRetries: 2, Sleep: func(time.Duration) {}})
+	var buf restartableBuffer
+	if err := a.DownloadFromURL(ts.URL, &buf); err == nil {
+		t.Fatalf("expected error")
+	}
+	if calls != 3 || buf.restarts != 2 {
+		t.Fatalf("expected 3 attempts and 2 restarts, got calls=%d restarts=%d", calls, buf.restarts)
+	}
+}
+
+func TestDownloadAdapter_DoesNotRetryClientErrors(t *testing.T) {
+	calls := 0
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		w.WriteHeader(http.StatusNotFound)
+	}))
+	defer ts.Close()
+
+	a := NewDownloadAdapter(&http.Client{}).WithRetryPolicy(retry.Policy{MaxRetries: 3, Sleep: func(time.Duration) {}})
+	var buf strings.Builder
+	if err := a.DownloadFromURL(ts.URL, &buf); err == nil || calls != 1 {
+		t.Fatalf("expected single failing attempt, got calls=%d err=%v", calls, err)
+	}
+}
+
+func TestDownloadAdapter_Validator(t *testing.T) {
+	var method string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		method = r.Method
+		w.Header().Set("ETag", `"abc"`)
+		_, _ = io.WriteString(w, "body")
+	}))
+	defer ts.Close()
+
+	a := NewDownloadAdapter(&http.Client{})
+	got, err := a.Validator(ts.URL)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if method != "HEAD" || got != `"abc"` {
+		t.Fatalf("expected HEAD returning the ETag, got %s %q", method, got)
+	}
+}
+
+func TestDownloadAdapter_ValidatorRetriesAndFails(t *testing.T) {
+	calls := 0
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer ts.Close()
+
+	a := NewDownloadAdapter(&http.Client{}).WithRetryPolicy(retry.Policy{MaxRetries: 2, Sleep: func(time.Duration) {}})
+	if _, err := a.Validator(ts.URL); err == nil || !strings.Contains(err.Error(), "HTTP 503") {
+		t.Fatalf("expected HTTP 503 error, got %v", err)
+	}
+	if calls != 3 {
+		t.Fatalf("expected 3 attempts, got %d", calls)
+	}
+}
+
+func TestDownloadAdapter_FileName(t *testing.T) {
+	for header, want := range map[string]string{
+		`attachment; filename="app-linux.tar.gz"`:   "app-linux.tar.gz",
+		`attachment; filename*=UTF-8''%C3%A4pp.zip`: "äpp.zip",
+		`attachment; filename="..\..\etc\passwd"`:   "passwd",
+		`inline`: "",
+		``:       "",
+	} {
+		ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Disposition", header)
+		}))
+		got, err := NewDownloadAdapter(&http.Client{}).FileName(ts.URL)
+		ts.Close()
+		if err != nil || got != want {
+			t.Fatalf("%s: expected %q, got %q, %v", header, want, got, err)
+		}
+	}
+}
+
+func TestDownloadAdapter_WithTokenOnlyForGitLabAPI(t *testing.T) {
+	var tokens []string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		tokens = append(tokens, r.Header.Get("PRIVATE-TOKEN"))
+		if strings.HasSuffix(r.URL.Path, "/download") {
+			http.Redirect(w, r, "/storage/artifacts.zip", http.StatusFound)
+			return
+		}
+		_, _ = io.WriteString(w, "ok")
+	}))
+	defer ts.Close()
+
+	a := NewDownloadAdapter(ts.Client()).WithToken(ts.URL+"/gitlab/", "tok").WithProgress(ProgressNone)
+	for _, url := range []string{
+		ts.URL + "/gitlab/api/v4/projects/1/repository/archive.zip?sha=v1",
+		ts.URL + "/files/app.zip",
+		ts.URL + "/gitlab/api/v4/projects/1/jobs/artifacts/main/download?job=build",
+	} {
+		if err := a.DownloadFromURL(url, io.Discard); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+	if strings.Join(tokens, ",") != "tok,,tok," {
+		t.Fatalf("expected token only for API requests, got %q", tokens)
+	}
+}
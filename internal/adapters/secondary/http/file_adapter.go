@@ -12,10 +12,121 @@ func NewFileAdapter() *FileAdapter {
 	return &FileAdapter{}
 }
 
+// CreateFile creates path, replacing an existing file instead of truncating
+// it, so files hard-linked from the download cache stay intact. Closing the
+// file flushes it to disk.
 func (a *FileAdapter) CreateFile(path string) (io.WriteCloser, error) {
+	if err := a.Remove(path); err != nil {
+		return nil, err
+	}
 	file, err := os.Create(path)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create file: %w", err)
 	}
+	return syncedFile{file}, nil
+}
+
+// AppendFile opens path for appending, creating it if missing, and returns
+// the current size as the offset to resume from.
+func (a *FileAdapter) AppendFile(path string) (io.WriteCloser, int64, error) {
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0o644)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to open file: %w", err)
+	}
+
+	info, err := file.Stat()
+	if err != nil {
+		_ = file.Close()
+		return nil, 0, fmt.Errorf("failed to stat file: %w", err)
+	}
+
+	return syncedFile{file}, info.Size(), nil
+}
+
+func (a *FileAdapter) OpenFile(path string) (io.ReadCloser, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open file: %w", err)
+	}
 	return file, nil
 }
+
+func (a *FileAdapter) WriteFile(path string, data []byte) error {
+	if err := os.WriteFile(path, data, 0o644); err != nil {
+		return fmt.Errorf("failed to write file: %w", err)
+	}
+	return nil
+}
+
+func (a *FileAdapter) CreateDir(path string) error {
+	if err := os.MkdirAll(path, 0o755); err != nil {
+		return fmt.Errorf("failed to create directory: %w", err)
+	}
+	return nil
+}
+
+func (a *FileAdapter) Remove(path string) error {
+	if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to remove file: %w", err)
+	}
+	return nil
+}
+
+func (a *FileAdapter) Exists(path string) (bool, error) {
+	_, err := os.Lstat(path)
+	switch {
+	case err == nil:
+		return true, nil
+	case os.IsNotExist(err):
+		return false, nil
+	default:
+		return false, fmt.Errorf("failed to stat file: %w", err)
+	}
+}
+
+func (a *FileAdapter) IsDir(path string) (bool, error) {
+	info, err := os.Stat(path)
+	switch {
+	case err == nil:
+		return info.IsDir(), nil
+	case os.IsNotExist(err):
+		return false, nil
+	default:
+		return false, fmt.Errorf("failed to stat file: %w", err)
+	}
+}
+
+func (a *FileAdapter) RemoveAll(path string) error {
+	if err := os.RemoveAll(path); err != nil {
+		return fmt.Errorf("failed to remove directory: %w", err)
+	}
+	return nil
+}
+
+func (a *FileAdapter) Rename(oldPath, newPath string) error {
+	if err := os.Rename(oldPath, newPath); err != nil {
+		return fmt.Errorf("failed to rename file: %w", err)
+	}
+	return nil
+}
+
+func (a *FileAdapter) Chmod(path string, mode os.FileMode) error {
+	if err := os.Chmod(path, mode); err != nil {
+		return fmt.Errorf("failed to change file mode: %w", err)
+	}
+	return nil
+}
+
+// syncedFile flushes the file to disk before closing it, so a file renamed
+// into place after Close is complete even after a crash.
+type syncedFile struct {
+	*os.File
+}
+
+func (f syncedFile) Close() error {
+	if err := f.File.Sync(); err != nil {
+		_ = f.File.Close()
+		return fmt.Errorf("failed to sync file: %w", err)
+	}
+	return f.File.Close()
+}
@@ -47,3 +47,130 @@ func TestFileAdapter_CreateFile_Error(t *testing.T) {
 		t.Fatalf("expected wrapped error, got %v", err)
 	}
 }
+
+func TestFileAdapter_CreateDir(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "a", "b")
+	a := NewFileAdapter()
+	if err := a.CreateDir(dir); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if info, err := os.Stat(dir); err != nil || !info.IsDir() {
+		t.Fatalf("expected directory to exist, err=%v", err)
+	}
+}
+
+func TestFileAdapter_Remove(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "out.bin")
+	if err := os.WriteFile(path, []byte("x"), 0o644); err != nil {
+		t.Fatalf("setup failed: %v", err)
+	}
+	a := NewFileAdapter()
+	if err := a.Remove(path); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Fatalf("expected file to be removed, err=%v", err)
+	}
+	// removing a missing file is not an error
+	if err := a.Remove(path); err != nil {
+		t.Fatalf("unexpected error for missing file: %v", err)
+	}
+}
+
+func TestFileAdapter_AppendFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "out.bin")
+	if err := os.WriteFile(path, []byte("hel"), 0o644); err != nil {
+		t.Fatalf("setup failed: %v", err)
+	}
+
+	a := NewFileAdapter()
+	wc, offset, err := a.AppendFile(path)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if offset != 3 {
+		t.Fatalf("expected offset 3, got %d", offset)
+	}
+	_, _ = io.WriteString(wc, "lo")
+	_ = wc.Close()
+
+	rc, err := a.OpenFile(path)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer func() { _ = rc.Close() }()
+	b, _ := io.ReadAll(rc)
+	if string(b) != "hello" {
+		t.Fatalf("unexpected content: %q", string(b))
+	}
+}
+
+func TestFileAdapter_CreateFileBreaksHardLinks(t *testing.T) {
+	dir := t.TempDir()
+	original := filepath.Join(dir, "cached")
+	linked := filepath.Join(dir, "out")
+	if err := os.WriteFile(original, []byte("CACHED"), 0o644); err != nil {
+		t.Fatalf("setup failed: %v", err)
+	}
+	if err := os.Link(original, linked); err != nil {
+		t.Skipf("hard links not supported: %v", err)
+	}
+
+	w, err := NewFileAdapter().CreateFile(linked)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	_, _ = w.Write([]byte("NEW"))
+	_ = w.Close()
+
+	if data, _ := os.ReadFile(original); string(data) != "CACHED" {
+		t.Fatalf("expected linked file to stay intact, got %q", data)
+	}
+}
+
+func TestFileAdapter_RenameChmodRemoveAll(t *testing.T) {
+	dir := t.TempDir()
+	src := filepath.Join(dir, "work", "tool.new")
+	dst := filepath.Join(dir, "tool")
+	_ = os.MkdirAll(filepath.Dir(src), 0o755)
+	if err := os.WriteFile(src, []byte("ELF"), 0o644); err != nil {
+		t.Fatalf("setup failed: %v", err)
+	}
+
+	a := NewFileAdapter()
+	if err := a.Chmod(src, 0o755); err != nil {
+		t.Fatalf("chmod failed: %v", err)
+	}
+	if err := a.Rename(src, dst); err != nil {
+		t.Fatalf("rename failed: %v", err)
+	}
+	if info, err := os.Stat(dst); err != nil || info.Mode().Perm() != 0o755 {
+		t.Fatalf("expected executable at %s, got %v, %v", dst, info, err)
+	}
+	if err := a.RemoveAll(filepath.Join(dir, "work")); err != nil {
+		t.Fatalf("remove failed: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "work")); !os.IsNotExist(err) {
+		t.Fatalf("expected directory to be removed, got %v", err)
+	}
+}
+
+func TestFileAdapter_ExistsIsDir(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "file")
+	a := NewFileAdapter()
+
+	if exists, err := a.Exists(path); err != nil || exists {
+		t.Fatalf("expected missing file, got %v, %v", exists, err)
+	}
+	_ = os.WriteFile(path, nil, 0o644)
+	if exists, err := a.Exists(path); err != nil || !exists {
+		t.Fatalf("expected existing file, got %v, %v", exists, err)
+	}
+	if isDir, err := a.IsDir(path); err != nil || isDir {
+		t.Fatalf("expected file not to be a directory, got %v, %v", isDir, err)
+	}
+	if isDir, err := a.IsDir(dir); err != nil || !isDir {
+		t.Fatalf("expected directory, got %v, %v", isDir, err)
+	}
+}
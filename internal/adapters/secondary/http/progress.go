@@ -0,0 +1,215 @@
+package http
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	neturl "net/url"
+	"os"
+	"path"
+	"time"
+
+	"github.com/schollz/progressbar/v3"
+)
+
+// ProgressMode selects how transfers report their progress.
+type ProgressMode string
+
+const (
+	// ProgressAuto draws a bar if stderr is a terminal and prints plain lines otherwise
+	ProgressAuto ProgressMode = "auto"
+	// ProgressBar draws an interactive progress bar
+	ProgressBar ProgressMode = "bar"
+	// ProgressPlain prints a status line every few seconds
+	ProgressPlain ProgressMode = "plain"
+	// ProgressJSON prints one JSON event per line
+	ProgressJSON ProgressMode = "json"
+	// ProgressNone reports nothing
+	ProgressNone ProgressMode = "none"
+)
+
+// Intervals between plain status lines and JSON progress events.
+const (
+	plainInterval = 5 * time.Second
+	jsonInterval  = time.Second
+)
+
+// progress creates a meter for every transfer, writing to out.
+type progress struct {
+	mode     ProgressMode
+	out      io.Writer
+	interval time.Duration
+	now      func() time.Time
+}
+
+// newProgress resolves ProgressAuto by checking whether out is a terminal.
+func newProgress(mode ProgressMode, out *os.File) progress {
+	if mode == ProgressAuto || mode == "" {
+		mode = ProgressPlain
+		if isTerminal(out) {
+			mode = ProgressBar
+		}
+	}
+
+	interval := plainInterval
+	if mode == ProgressJSON {
+		interval = jsonInterval
+	}
+	return progress{mode: mode, out: out, interval: interval, now: time.Now}
+}
+
+func isTerminal(f *os.File) bool {
+	info, err := f.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
+
+// meter observes the bytes of a single transfer.
+type meter interface {
+	io.Writer
+	Done()
+	Fail(err error)
+}
+
+// start returns a meter for a transfer of url with total bytes, -1 if
+// unknown, of which offset were transferred before. action describes the
+// transfer, e.g. "downloading".
+func (p progress) start(action, url string, total, offset int64) meter {
+	switch p.mode {
+	case ProgressNone:
+		return noMeter{}
+	case ProgressBar:
+		bar := progressbar.DefaultBytes(total, action)
+		if offset > 0 {
+			_ = bar.Set64(offset)
+		}
+		return barMeter{bar}
+	}
+
+	now := p.now()
+	m := &reportingMeter{progress: p, action: action, url: url, total: total, offset: offset, n: offset, started: now, last: now}
+	m.report("start", now, nil)
+	return m
+}
+
+type noMeter struct{}
+
+func (noMeter) Write(p []byte) (int, error) { return len(p), nil }
+func (noMeter) Done()                       {}
+func (noMeter) Fail(error)                  {}
+
+type barMeter struct {
+	*progressbar.ProgressBar
+}
+
+func (m barMeter) Done() {}
+
+// Fail ends the bar, so the error is printed on a line of its own.
+func (m barMeter) Fail(error) {
+	_ = m.Exit()
+}
+
+// reportingMeter prints plain status lines or JSON events.
+type reportingMeter struct {
+	progress
+	action  string
+	url     string
+	total   int64
+	offset  int64
+	n       int64
+	started time.Time
+	last    time.Time
+}
+
+// progressEvent is a JSON progress line. Total is -1 if unknown, Rate is
+// the average number of bytes per second since the start.
+type progressEvent struct {
+	Event  string    `json:"event"`
+	Action string    `json:"action"`
+	URL    string    `json:"url"`
+	Bytes  int64     `json:"bytes"`
+	Total  int64     `json:"total"`
+	Rate   int64     `json:"rate"`
+	Error  string    `json:"error,omitempty"`
+	Time   time.Time `json:"time"`
+}
+
+func (m *reportingMeter) Write(p []byte) (int, error) {
+	m.n += int64(len(p))
+	if now := m.now(); now.Sub(m.last) >= m.interval {
+		m.last = now
+		m.report("progress", now, nil)
+	}
+	return len(p), nil
+}
+
+func (m *reportingMeter) Done() {
+	m.report("done", m.now(), nil)
+}
+
+func (m *reportingMeter) Fail(err error) {
+	m.report("error", m.now(), err)
+}
+
+func (m *reportingMeter) report(event string, now time.Time, err error) {
+	elapsed := now.Sub(m.started)
+	var rate int64
+	if elapsed > 0 {
+		rate = int64(float64(m.n-m.offset) / elapsed.Seconds())
+	}
+
+	if m.mode == ProgressJSON {
+		e := progressEvent{Event: event, Action: m.action, URL: m.url, Bytes: m.n, Total: m.total, Rate: rate, Time: now.UTC()}
+		if err != nil {
+			e.Error = err.Error()
+		}
+		line, _ := json.Marshal(e)
+		_, _ = fmt.Fprintf(m.out, "%s\n", line)
+		return
+	}
+
+	name := displayName(m.url)
+	switch event {
+	case "start":
+		size := "unknown size"
+		if m.total >= 0 {
+			size = formatSize(m.total)
+		}
+		if m.offset > 0 {
+			size += ", resuming at " + formatSize(m.offset)
+		}
+		_, _ = fmt.Fprintf(m.out, "%s %s (%s)\n", m.action, name, size)
+	case "progress":
+		done := formatSize(m.n)
+		if m.total > 0 {
+			done = fmt.Sprintf("%s of %s (%d%%)", done, formatSize(m.total), m.n*100/m.total)
+		}
+		_, _ = fmt.Fprintf(m.out, "%s %s: %s, %s/s\n", m.action, name, done, formatSize(rate))
+	case "done":
+		_, _ = fmt.Fprintf(m.out, "%s %s: %s in %s\n", m.action, name, formatSize(m.n), elapsed.Round(time.Second/10))
+	case "error":
+		_, _ = fmt.Fprintf(m.out, "%s %s: failed after %s\n", m.action, name, formatSize(m.n))
+	}
+}
+
+// displayName shortens url to its last path segment.
+func displayName(url string) string {
+	if u, err := neturl.Parse(url); err == nil {
+		if base := path.Base(u.Path); base != "/" && base != "." {
+			return base
+		}
+	}
+	return url
+}
+
+func formatSize(size int64) string {
+	const unit = 1024
+	if size < unit {
+		return fmt.Sprintf("%d B", size)
+	}
+	div, exp := int64(unit), 0
+	for n := size / unit; n >= unit; n /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %ciB", float64(size)/float64(div), "KMGTPE"[exp])
+}
@@ -0,0 +1,119 @@
+package http
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+// fakeClock advances by step on every call.
+func fakeClock(step time.Duration) func() time.Time {
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	return func() time.Time {
+		now = now.Add(step)
+		return now
+	}
+}
+
+func TestProgress_JSONEvents(t *testing.T) {
+	var out bytes.Buffer
+	p := progress{mode: ProgressJSON, out: &out, interval: time.Second, now: fakeClock(time.Second)}
+
+	m := p.start("downloading", "https://example.com/app.zip", 6, 2)
+	_, _ = m.Write([]byte("ab"))
+	_, _ = m.Write([]byte("cd"))
+	m.Fail(errors.New("connection reset"))
+
+	var events []progressEvent
+	for _, line := range strings.Split(strings.TrimSpace(out.String()), "\n") {
+		var e progressEvent
+		if err := json.Unmarshal([]byte(line), &e); err != nil {
+			t.Fatalf("invalid JSON line %q: %v", line, err)
+		}
+		events = append(events, e)
+	}
+
+	if len(events) != 4 {
+		t.Fatalf("expected start, 2 progress and error events, got %+v", events)
+	}
+	if e := events[0]; e.Event != "start" || e.URL != "https://example.com/app.zip" || e.Bytes != 2 || e.Total != 6 {
+		t.Fatalf("unexpected start event %+v", e)
+	}
+	if e := events[2]; e.Event != "progress" || e.Bytes != 6 || e.Rate != 2 {
+		t.Fatalf("unexpected progress event %+v", e)
+	}
+	if e := events[3]; e.Event != "error" || e.Error != "connection reset" {
+		t.Fatalf("unexpected error event %+v", e)
+	}
+}
+
+func TestProgress_PlainLines(t *testing.T) {
+	var out bytes.Buffer
+	p := progress{mode: ProgressPlain, out: &out, interval: 5 * time.Second, now: fakeClock(time.Second)}
+
+	m := p.start("downloading", "https://example.com/files/app.zip?x=1", 5120, 0)
+	for i := 0; i < 5; i++ {
+		_, _ = m.Write(make([]byte, 1024))
+	}
+	m.Done()
+
+	want := "downloading app.zip (5.0 KiB)\n" +
+		"downloading app.zip: 5.0 KiB of 5.0 KiB (100%), 1.0 KiB/s\n" +
+		"downloading app.zip: 5.0 KiB in 6s\n"
+	if out.String() != want {
+		t.Fatalf("expected\n%s\ngot\n%s", want, out.String())
+	}
+}
+
+func TestNewProgress_AutoWithoutTerminal(t *testing.T) {
+	f, err := os.CreateTemp(t.TempDir(), "stderr")
+	if err != nil {
+		t.Fatalf("setup failed: %v", err)
+	}
+	defer func() { _ = f.Close() }()
+
+	if p := newProgress(ProgressAuto, f); p.mode != ProgressPlain {
+		t.Fatalf("expected plain progress for a file, got %s", p.mode)
+	}
+}
+
+func TestDownloadAdapter_ReportsProgress(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/missing" {
+			http.NotFound(w, r)
+			return
+		}
+		_, _ = io.WriteString(w, "DATA")
+	}))
+	defer ts.Close()
+
+	var out bytes.Buffer
+	a := NewDownloadAdapter(&http.Client{})
+	a.progress = progress{mode: ProgressJSON, out: &out, interval: time.Hour, now: time.Now}
+
+	if err := a.DownloadFromURL(ts.URL+"/app.zip", io.Discard); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := a.DownloadFromURL(ts.URL+"/missing", io.Discard); err == nil {
+		t.Fatalf("expected error")
+	}
+
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	if len(lines) != 2 || !strings.Contains(lines[0], `"event":"start"`) || !strings.Contains(lines[1], `"event":"done","action":"downloading"`) || !strings.Contains(lines[1], `"bytes":4,"total":4`) {
+		t.Fatalf("unexpected events %q", lines)
+	}
+}
+
+func TestDownloadAdapter_QuietProgress(t *testing.T) {
+	a := NewDownloadAdapter(&http.Client{}).WithProgress(ProgressNone)
+	if _, ok := a.progress.start("downloading", "u", 1, 0).(noMeter); !ok {
+		t.Fatalf("expected no progress output")
+	}
+}
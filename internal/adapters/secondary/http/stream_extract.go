@@ -0,0 +1,211 @@
+package http
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"hufschlaeger.net/gitlab-downloader/internal/adapters/secondary/archive"
+	"hufschlaeger.net/gitlab-downloader/internal/adapters/secondary/retry"
+	"hufschlaeger.net/gitlab-downloader/internal/core/domain"
+	"hufschlaeger.net/gitlab-downloader/internal/core/ports"
+)
+
+// rangeBlockSize is the minimum number of bytes fetched per Range request
+// when reading zip archives, so sequential reads of an entry do not turn into
+// one request per decompressor buffer.
+const rangeBlockSize = 1 << 20
+
+var zipMagic = []byte("PK\x03\x04")
+
+// ExtractFromURL unpacks the archive at url into opts.Dir without writing the
+// archive to disk. Tar archives, plain or compressed, are extracted from the
+// response body; an interrupted body is continued with a Range request
+// guarded by If-Range. Zip archives are read with Range requests, so only
+// the central directory and the selected entries are transferred.
+func (a *DownloadAdapter) ExtractFromURL(url, name string, opts domain.ExtractOptions) ([]string, error) {
+	resp, err := a.open(url, 0, -1, "")
+	if err != nil {
+		return nil, err
+	}
+
+	body := &resumingBody{adapter: a, url: url, body: resp.Body, validator: validator(resp)}
+	defer func(body *resumingBody) {
+		err := body.Close()
+		if err != nil {
+			_ = fmt.Errorf("failed to close connection: %w", err)
+		}
+	}(body)
+
+	br := bufio.NewReader(body)
+	if head, _ := br.Peek(len(zipMagic)); bytes.Equal(head, zipMagic) {
+		if resp.ContentLength < 0 {
+			return nil, fmt.Errorf("cannot extract zip archive: server sent no content length")
+		}
+		// Zip archives are read from the end, the body is not needed
+		_ = body.Close()
+		r := &rangeReader{adapter: a, url: url, size: resp.ContentLength, validator: body.validator}
+		files, err := archive.ExtractZip(r, r.size, opts)
+		if errors.Is(err, ports.ErrRangeNotHonored) {
+			return files, fmt.Errorf("cannot extract zip archive: %w", err)
+		}
+		return files, err
+	}
+
+	m := a.progress.start("extracting", url, resp.ContentLength, 0)
+	files, err := archive.ExtractStream(io.TeeReader(br, m), name, opts)
+	if err != nil {
+		m.Fail(err)
+		return files, err
+	}
+	m.Done()
+	return files, nil
+}
+
+// open sends a GET request for url, retrying transient failures. With
+// start > 0 or end >= 0 only the bytes from start to end (inclusive, -1 for
+// the rest) are requested, guarded by If-Range when ifRange is set; a
+// response with other content returns ports.ErrRangeNotHonored. The caller
+// closes the body.
+func (a *DownloadAdapter) open(url string, start, end int64, ifRange string) (*http.Response, error) {
+	ranged := start > 0 || end >= 0
+
+	for attempt := 0; ; attempt++ {
+		req, err := a.newRequest("GET", url)
+		if err != nil {
+			return nil, err
+		}
+		if ranged {
+			spec := fmt.Sprintf("bytes=%d-", start)
+			if end >= 0 {
+				spec += fmt.Sprint(end)
+			}
+			req.Header.Set("Range", spec)
+			if ifRange != "" {
+				req.Header.Set("If-Range", ifRange)
+			}
+		}
+
+		resp, err := a.client.Do(req)
+		if err != nil {
+			err = fmt.Errorf("request failed: %w", err)
+		} else {
+			switch {
+			case ranged && resp.StatusCode == http.StatusPartialContent:
+				if strings.HasPrefix(resp.Header.Get("Content-Range"), fmt.Sprintf("bytes %d-", start)) {
+					return resp, nil
+				}
+				_ = resp.Body.Close()
+				return nil, ports.ErrRangeNotHonored
+			case ranged && (resp.StatusCode == http.StatusOK || resp.StatusCode == http.StatusRequestedRangeNotSatisfiable):
+				_ = resp.Body.Close()
+				return nil, ports.ErrRangeNotHonored
+			case !ranged && resp.StatusCode == http.StatusOK:
+				return resp, nil
+			}
+			_ = resp.Body.Close()
+			err = fmt.Errorf("HTTP %d: %s", resp.StatusCode, resp.Status)
+			if !retry.RetryableStatus(resp.StatusCode) {
+				return nil, err
+			}
+		}
+
+		if !a.retry.ShouldRetry(attempt) {
+			return nil, err
+		}
+		a.retry.Wait(attempt, resp)
+	}
+}
+
+// resumingBody reads a response body and continues it with a Range request
+// after a broken connection, if the retry policy allows and the server sent
+// a validator.
+type resumingBody struct {
+	adapter   *DownloadAdapter
+	url       string
+	body      io.ReadCloser
+	validator string
+	n         int64
+	attempt   int
+}
+
+func (r *resumingBody) Read(p []byte) (int, error) {
+	for {
+		n, err := r.body.Read(p)
+		r.n += int64(n)
+		if err == nil || errors.Is(err, io.EOF) {
+			return n, err
+		}
+		if n > 0 {
+			// The error is reported again by the next read
+			return n, nil
+		}
+		if r.validator == "" || !r.adapter.retry.ShouldRetry(r.attempt) {
+			return 0, fmt.Errorf("download failed: %w", err)
+		}
+
+		r.adapter.retry.Wait(r.attempt, nil)
+		r.attempt++
+		_ = r.body.Close()
+		resp, openErr := r.adapter.open(r.url, r.n, -1, r.validator)
+		if openErr != nil {
+			return 0, fmt.Errorf("download failed: %w (resume: %v)", err, openErr)
+		}
+		r.body = resp.Body
+	}
+}
+
+func (r *resumingBody) Close() error {
+	return r.body.Close()
+}
+
+// rangeReader provides random access to a remote file of known size using
+// Range requests. The last fetched block is kept to serve sequential reads.
+type rangeReader struct {
+	adapter    *DownloadAdapter
+	url        string
+	size       int64
+	validator  string
+	block      []byte
+	blockStart int64
+}
+
+func (r *rangeReader) ReadAt(p []byte, off int64) (int, error) {
+	n := 0
+	for n < len(p) {
+		pos := off + int64(n)
+		if pos >= r.size {
+			return n, io.EOF
+		}
+		if pos < r.blockStart || pos >= r.blockStart+int64(len(r.block)) {
+			if err := r.fill(pos, len(p)-n); err != nil {
+				return n, err
+			}
+		}
+		n += copy(p[n:], r.block[pos-r.blockStart:])
+	}
+	return n, nil
+}
+
+// fill fetches at least want bytes starting at off.
+func (r *rangeReader) fill(off int64, want int) error {
+	end := min(off+int64(max(want, rangeBlockSize)), r.size) - 1
+	resp, err := r.adapter.open(r.url, off, end, r.validator)
+	if err != nil {
+		return err
+	}
+	defer func(Body io.ReadCloser) {
+		_ = Body.Close()
+	}(resp.Body)
+
+	block := make([]byte, end-off+1)
+	if _, err := io.ReadFull(resp.Body, block); err != nil {
+		return fmt.Errorf("failed to read bytes %d-%d: %w", off, end, err)
+	}
+	r.block, r.blockStart = block, off
+	return nil
+}
@@ -0,0 +1,165 @@
+package http
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+	"time"
+
+	"hufschlaeger.net/gitlab-downloader/internal/adapters/secondary/retry"
+	"hufschlaeger.net/gitlab-downloader/internal/core/domain"
+	"hufschlaeger.net/gitlab-downloader/internal/core/ports"
+)
+
+var archiveFiles = map[string]string{
+	"src/config/app.yaml": "key: value\n",
+	"src/main.go":         "package main\n",
+}
+
+func tarGz(t *testing.T) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	gw := gzip.NewWriter(&buf)
+	tw := tar.NewWriter(gw)
+	for name, body := range archiveFiles {
+		_ = tw.WriteHeader(&tar.Header{Name: name, Mode: 0o644, Size: int64(len(body)), Typeflag: tar.TypeReg})
+		_, _ = io.WriteString(tw, body)
+	}
+	_ = tw.Close()
+	_ = gw.Close()
+	return buf.Bytes()
+}
+
+// zipWithPadding adds a large stored entry which has to be skipped when only
+// the config is extracted.
+func zipWithPadding(t *testing.T) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	padding, _ := zw.CreateHeader(&zip.FileHeader{Name: "src/vendor.bin", Method: zip.Store})
+	_, _ = padding.Write(bytes.Repeat([]byte{'x'}, 3*rangeBlockSize))
+	for name, body := range archiveFiles {
+		w, _ := zw.Create(name)
+		_, _ = io.WriteString(w, body)
+	}
+	_ = zw.Close()
+	return buf.Bytes()
+}
+
+// serveArchive serves data with range support and counts the bytes sent in
+// answers to range requests.
+func serveArchive(data []byte, sent *int64) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("ETag", `"v1"`)
+		if r.Header.Get("Range") != "" {
+			w = &countingResponse{ResponseWriter: w, n: sent}
+		}
+		http.ServeContent(w, r, "archive", time.Time{}, bytes.NewReader(data))
+	}))
+}
+
+type countingResponse struct {
+	http.ResponseWriter
+	n *int64
+}
+
+func (c *countingResponse) Write(p []byte) (int, error) {
+	n, err := c.ResponseWriter.Write(p)
+	*c.n += int64(n)
+	return n, err
+}
+
+func assertConfigExtracted(t *testing.T, dir string, files []string) {
+	t.Helper()
+	if len(files) != 1 || files[0] != "config/app.yaml" {
+		t.Fatalf("unexpected files %v", files)
+	}
+	data, err := os.ReadFile(filepath.Join(dir, "config", "app.yaml"))
+	if err != nil || string(data) != "key: value\n" {
+		t.Fatalf("unexpected content %q, %v", data, err)
+	}
+}
+
+func TestExtractFromURL_TarStream(t *testing.T) {
+	var sent int64
+	ts := serveArchive(tarGz(t), &sent)
+	defer ts.Close()
+
+	dir := t.TempDir()
+	files, err := NewDownloadAdapter(&http.Client{}).ExtractFromURL(ts.URL, "src.tar.gz", domain.ExtractOptions{Dir: dir, StripComponents: 1, Include: []string{"config"}})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	assertConfigExtracted(t, dir, files)
+}
+
+func TestExtractFromURL_ZipReadsSelectedEntriesOnly(t *testing.T) {
+	data := zipWithPadding(t)
+	var sent int64
+	ts := serveArchive(data, &sent)
+	defer ts.Close()
+
+	dir := t.TempDir()
+	files, err := NewDownloadAdapter(&http.Client{}).ExtractFromURL(ts.URL, "src.zip", domain.ExtractOptions{Dir: dir, StripComponents: 1, Include: []string{"config"}})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	assertConfigExtracted(t, dir, files)
+	if sent >= rangeBlockSize {
+		t.Fatalf("expected the padding entry to be skipped, got %d of %d bytes", sent, len(data))
+	}
+}
+
+func TestExtractFromURL_ZipWithoutRangeSupport(t *testing.T) {
+	data := zipWithPadding(t)
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Length", strconv.Itoa(len(data)))
+		_, _ = w.Write(data)
+	}))
+	defer ts.Close()
+
+	_, err := NewDownloadAdapter(&http.Client{}).ExtractFromURL(ts.URL, "src.zip", domain.ExtractOptions{Dir: t.TempDir()})
+	if !errors.Is(err, ports.ErrRangeNotHonored) {
+		t.Fatalf("expected range error, got %v", err)
+	}
+}
+
+func TestExtractFromURL_ResumesInterruptedStream(t *testing.T) {
+	data := tarGz(t)
+	calls := 0
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		w.Header().Set("ETag", `"v1"`)
+		if calls == 1 {
+			// announce the full size but send only half of it
+			w.Header().Set("Content-Length", strconv.Itoa(len(data)))
+			_, _ = w.Write(data[:len(data)/2])
+			return
+		}
+		if r.Header.Get("If-Range") != `"v1"` {
+			t.Errorf("expected If-Range, got %v", r.Header)
+		}
+		http.ServeContent(w, r, "archive", time.Time{}, bytes.NewReader(data))
+	}))
+	defer ts.Close()
+
+	a := NewDownloadAdapter(&http.Client{}).WithRetryPolicy(retry.Policy{MaxRetries: 1, Sleep: func(time.Duration) {}})
+	dir := t.TempDir()
+	files, err := a.ExtractFromURL(ts.URL, "src.tar.gz", domain.ExtractOptions{Dir: dir, StripComponents: 1, Include: []string{"config"}})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if calls != 2 {
+		t.Fatalf("expected a resumed request, got %d calls", calls)
+	}
+	assertConfigExtracted(t, dir, files)
+}
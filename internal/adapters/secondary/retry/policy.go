@@ -0,0 +1,108 @@
+package retry
+
+import (
+	"math/rand"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+const (
+	DefaultMaxRetries = 3
+	DefaultBaseDelay  = time.Second
+	DefaultMaxDelay   = 30 * time.Second
+)
+
+// Policy describes how often and how long to wait before retrying a failed
+// HTTP call. The zero value disables retries.
+type Policy struct {
+	MaxRetries int
+	BaseDelay  time.Duration
+	MaxDelay   time.Duration
+
+	// Sleep is used to wait between attempts; nil means time.Sleep.
+	Sleep func(time.Duration)
+}
+
+func DefaultPolicy() Policy {
+	return Policy{
+		MaxRetries: DefaultMaxRetries,
+		BaseDelay:  DefaultBaseDelay,
+		MaxDelay:   DefaultMaxDelay,
+	}
+}
+
+// ShouldRetry reports whether another attempt is allowed after the given
+// zero-based attempt failed.
+func (p Policy) ShouldRetry(attempt int) bool {
+	return attempt < p.MaxRetries
+}
+
+// RetryableStatus reports whether an HTTP status indicates a transient
+// failure: 429 Too Many Requests and 5xx server errors.
+func RetryableStatus(code int) bool {
+	switch code {
+	case http.StatusTooManyRequests,
+		http.StatusInternalServerError,
+		http.StatusBadGateway,
+		http.StatusServiceUnavailable,
+		http.StatusGatewayTimeout:
+		return true
+	}
+	return false
+}
+
+// Wait sleeps before the next attempt. A Retry-After header on resp takes
+// precedence over the jittered exponential backoff, but is capped at
+// MaxDelay as well.
+func (p Policy) Wait(attempt int, resp *http.Response) {
+	sleep := p.Sleep
+	if sleep == nil {
+		sleep = time.Sleep
+	}
+	sleep(p.Delay(attempt, resp))
+}
+
+// Delay returns the wait time before retrying the given zero-based attempt.
+func (p Policy) Delay(attempt int, resp *http.Response) time.Duration {
+	if resp != nil {
+		if d, ok := retryAfter(resp.Header.Get("Retry-After"), time.Now()); ok {
+			if p.MaxDelay > 0 && d > p.MaxDelay {
+				d = p.MaxDelay
+			}
+			return d
+		}
+	}
+
+	delay := p.BaseDelay
+	for i := 0; i < attempt && (p.MaxDelay <= 0 || delay < p.MaxDelay); i++ {
+		delay *= 2
+	}
+	if p.MaxDelay > 0 && delay > p.MaxDelay {
+		delay = p.MaxDelay
+	}
+	if delay <= 0 {
+		return 0
+	}
+
+	// Jitter: wait between half and the full backoff
+	half := delay / 2
+	return half + time.Duration(rand.Int63n(int64(delay-half)+1))
+}
+
+// retryAfter parses a Retry-After header given in seconds or as HTTP date.
+func retryAfter(value string, now time.Time) (time.Duration, bool) {
+	if value == "" {
+		return 0, false
+	}
+	if seconds, err := strconv.Atoi(value); err == nil && seconds >= 0 {
+		return time.Duration(seconds) * time.Second, true
+	}
+	if t, err := http.ParseTime(value); err == nil {
+		if d := t.Sub(now); d > 0 {
+			return d, true
+		}
+		return 0, true
+	}
+	return 0, false
+}
@@ -0,0 +1,77 @@
+package retry
+
+import (
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestRetryableStatus(t *testing.T) {
+	for _, code := range []int{429, 500, 502, 503, 504} {
+		if !RetryableStatus(code) {
+			t.Fatalf("expected %d to be retryable", code)
+		}
+	}
+	for _, code := range []int{200, 400, 401, 404, 501} {
+		if RetryableStatus(code) {
+			t.Fatalf("expected %d not to be retryable", code)
+		}
+	}
+}
+
+func TestDelay_ExponentialWithJitterAndCap(t *testing.T) {
+	p := Policy{MaxRetries: 5, BaseDelay: 100 * time.Millisecond, MaxDelay: time.Second}
+	cases := []struct {
+		attempt  int
+		min, max time.Duration
+	}{
+		{0, 50 * time.Millisecond, 100 * time.Millisecond},
+		{1, 100 * time.Millisecond, 200 * time.Millisecond},
+		{3, 400 * time.Millisecond, 800 * time.Millisecond},
+		{10, 500 * time.Millisecond, time.Second},
+	}
+	for _, tc := range cases {
+		for i := 0; i < 20; i++ {
+			d := p.Delay(tc.attempt, nil)
+			if d < tc.min || d > tc.max {
+				t.Fatalf("attempt %d: delay %v outside [%v, %v]", tc.attempt, d, tc.min, tc.max)
+			}
+		}
+	}
+}
+
+func TestDelay_HonorsRetryAfter(t *testing.T) {
+	p := Policy{MaxRetries: 1, BaseDelay: time.Millisecond}
+	resp := &http.Response{Header: http.Header{"Retry-After": []string{"7"}}}
+	if d := p.Delay(0, resp); d != 7*time.Second {
+		t.Fatalf("expected 7s from Retry-After, got %v", d)
+	}
+
+	p.MaxDelay = 5 * time.Second
+	if d := p.Delay(0, resp); d != 5*time.Second {
+		t.Fatalf("expected Retry-After capped at 5s, got %v", d)
+	}
+}
+
+func TestRetryAfter_HTTPDate(t *testing.T) {
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	d, ok := retryAfter(now.Add(90*time.Second).Format(http.TimeFormat), now)
+	if !ok || d != 90*time.Second {
+		t.Fatalf("expected 90s, got %v (ok=%v)", d, ok)
+	}
+	if _, ok := retryAfter("soon", now); ok {
+		t.Fatalf("expected invalid value to be ignored")
+	}
+}
+
+func TestWait_UsesSleep(t *testing.T) {
+	var slept time.Duration
+	p := Policy{MaxRetries: 1, BaseDelay: 10 * time.Millisecond, Sleep: func(d time.Duration) { slept = d }}
+	p.Wait(0, nil)
+	if slept < 5*time.Millisecond || slept > 10*time.Millisecond {
+		t.Fatalf("unexpected sleep %v", slept)
+	}
+	if !p.ShouldRetry(0) || p.ShouldRetry(1) {
+		t.Fatalf("unexpected ShouldRetry results")
+	}
+}
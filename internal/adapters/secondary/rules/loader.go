@@ -0,0 +1,51 @@
+package rules
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+
+	"gopkg.in/yaml.v3"
+
+	"hufschlaeger.net/gitlab-downloader/internal/core/domain"
+)
+
+// ruleFile is the on-disk format. JSON is accepted as well, since it is a
+// subset of YAML.
+type ruleFile struct {
+	Rules []domain.ResolutionRule `yaml:"rules"`
+}
+
+// LoadFile reads and validates URL resolution rules from a YAML or JSON file.
+func LoadFile(path string) ([]domain.ResolutionRule, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read rules file: %w", err)
+	}
+
+	rules, err := Parse(data)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return rules, nil
+}
+
+// Parse decodes and validates rules from YAML or JSON content.
+func Parse(data []byte) ([]domain.ResolutionRule, error) {
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	decoder.KnownFields(true)
+
+	var file ruleFile
+	if err := decoder.Decode(&file); err != nil && !errors.Is(err, io.EOF) {
+		return nil, fmt.Errorf("failed to parse rules: %w", err)
+	}
+
+	for i, rule := range file.Rules {
+		if err := rule.Validate(); err != nil {
+			return nil, fmt.Errorf("rule %d: %w", i+1, err)
+		}
+	}
+	return file.Rules, nil
+}
@@ -0,0 +1,70 @@
+package rules
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"hufschlaeger.net/gitlab-downloader/internal/core/domain"
+)
+
+func TestParse_YAML(t *testing.T) {
+	rules, err := Parse([]byte(`
+rules:
+  - project: dimag/ingest/*
+    strategy: generic-package
+    package: releases
+    file: "ipm.{tag}.zip"
+  - project: "*"
+    strategy: template
+    template: "{host}/api/v4/projects/{projectID}/packages/generic/app/{tag}/app.tar.gz"
+`))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(rules) != 2 || rules[0].Strategy != domain.StrategyGenericPackage || rules[0].File != "ipm.{tag}.zip" {
+		t.Fatalf("unexpected rules: %+v", rules)
+	}
+}
+
+func TestParse_JSON(t *testing.T) {
+	rules, err := Parse([]byte(`{"rules": [{"project": "group/proj", "strategy": "source-archive", "format": "tar.gz"}]}`))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(rules) != 1 || rules[0].Format != "tar.gz" {
+		t.Fatalf("unexpected rules: %+v", rules)
+	}
+}
+
+func TestParse_Errors(t *testing.T) {
+	cases := map[string]string{
+		"rules:\n  - project: p\n    strategy: magic\n":           "unknown strategy",
+		"rules:\n  - project: p\n    strategy: template\n":        "requires template",
+		"rules:\n  - project: p\n    strategy: generic-package\n": "requires package and file",
+		"rules:\n  - strategy: blob-to-raw\n":                     "project pattern is required",
+		"rules:\n  - project: p\n    strategie: template\n":       "failed to parse rules",
+	}
+	for content, want := range cases {
+		_, err := Parse([]byte(content))
+		if err == nil || !strings.Contains(err.Error(), want) {
+			t.Fatalf("expected error containing %q, got %v", want, err)
+		}
+	}
+}
+
+func TestLoadFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "rules.yaml")
+	if err := os.WriteFile(path, []byte("rules:\n  - project: p\n    strategy: blob-to-raw\n"), 0o644); err != nil {
+		t.Fatalf("setup failed: %v", err)
+	}
+	rules, err := LoadFile(path)
+	if err != nil || len(rules) != 1 {
+		t.Fatalf("unexpected result: %+v, %v", rules, err)
+	}
+
+	if _, err := LoadFile(filepath.Join(t.TempDir(), "missing.yaml")); err == nil {
+		t.Fatalf("expected error for missing file")
+	}
+}
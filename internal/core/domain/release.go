@@ -1,14 +1,40 @@
 package domain
 
+import (
+	"fmt"
+	"path"
+	"strings"
+	"time"
+)
+
 type Project struct {
 	ID   int
 	Name string
 }
 
 type Release struct {
-	ProjectID int
-	Tag       string
-	Assets    Assets
+	ProjectID   int
+	Tag         string
+	Name        string
+	Description string
+	ReleasedAt  time.Time
+	// Upcoming is set for releases whose ReleasedAt lies in the future
+	Upcoming   bool
+	CommitSHA  string
+	Milestones []string
+	Assets     Assets
+}
+
+// ReleaseFilter selects releases to list. Zero values do not filter.
+type ReleaseFilter struct {
+	// Since drops releases published before this time
+	Since time.Time
+	// Prerelease keeps releases whose tag is a semantic pre-release version
+	Prerelease bool
+	// Match is a glob the tag must match
+	Match string
+	// Limit caps the number of releases returned
+	Limit int
 }
 
 type Assets struct {
@@ -17,8 +43,13 @@ type Assets struct {
 }
 
 type Link struct {
+	ID   int
 	Name string
 	URL  string
+	// DirectAssetURL is the permanent /-/releases/<tag>/downloads/ URL
+	DirectAssetURL string
+	// LinkType is other, runbook, image or package
+	LinkType string
 }
 
 type Source struct {
@@ -27,8 +58,221 @@ type Source struct {
 }
 
 type DownloadRequest struct {
+	ProjectName    string
+	ReleaseTag     string
+	OutputPath     string
+	ExtIndex       int
+	All            bool
+	IncludeSources bool
+	AssetGlob      string
+	AssetRegex     string
+	SourceFormat   string
+	SHA256         string
+	Continue       bool
+	Lock           *Lock
+	Extract        *ExtractOptions
+	Overwrite      OverwritePolicy
+	// RepositoryArchive decides when the repository archive of the tag is
+	// used instead of the release; RepositoryPath limits it to a directory
+	RepositoryArchive RepositoryArchiveMode
+	RepositoryPath    string
+}
+
+// StdoutPath as output path writes the download to standard output.
+const StdoutPath = "-"
+
+// OverwritePolicy decides what happens to an existing output file.
+type OverwritePolicy string
+
+const (
+	// OverwriteForce replaces existing files; the default
+	OverwriteForce OverwritePolicy = "force"
+	// OverwriteNoClobber keeps existing files and skips their download
+	OverwriteNoClobber OverwritePolicy = "no-clobber"
+	// OverwriteBackup renames existing files to .bak or .~N~
+	OverwriteBackup OverwritePolicy = "backup"
+	// OverwriteSkipIfSame skips the download of files identical to the remote
+	OverwriteSkipIfSame OverwritePolicy = "skip-if-same"
+)
+
+// RepositoryArchiveMode decides when sources are taken from the repository
+// archive of a tag instead of a GitLab release.
+type RepositoryArchiveMode string
+
+const (
+	// RepositoryArchiveAuto falls back to the archive for tags without a
+	// release; the default
+	RepositoryArchiveAuto RepositoryArchiveMode = "auto"
+	// RepositoryArchiveAlways skips the release and uses the archive of any ref
+	RepositoryArchiveAlways RepositoryArchiveMode = "always"
+	// RepositoryArchiveNever fails for tags without a release
+	RepositoryArchiveNever RepositoryArchiveMode = "never"
+)
+
+// ExtractOptions configure unpacking a downloaded archive into Dir.
+type ExtractOptions struct {
+	Dir string
+	// StripComponents removes leading path elements from every entry
+	StripComponents int
+	// Include and Exclude are globs matched against the stripped entry path
+	Include []string
+	Exclude []string
+}
+
+// ArtifactRequest describes downloading the artifacts of the latest
+// successful Job of a pipeline for Ref, a branch or tag. With Path only that
+// file of the artifacts archive is downloaded.
+type ArtifactRequest struct {
 	ProjectName string
-	ReleaseTag  string
+	Ref         string
+	Job         string
+	Path        string
 	OutputPath  string
-	ExtIndex    int
+	SHA256      string
+	Continue    bool
+	Extract     *ExtractOptions
+	Overwrite   OverwritePolicy
+}
+
+// InstallRequest describes installing the executable of a release asset
+// built for OS and Arch into BinDir.
+type InstallRequest struct {
+	ProjectName string
+	ReleaseTag  string
+	BinDir      string
+	// Name of the executable, defaults to the last element of ProjectName
+	Name string
+	// OS and Arch use GOOS and GOARCH values, default to the running platform
+	OS   string
+	Arch string
+	// AssetGlob selects the asset by name instead of by OS and Arch
+	AssetGlob string
+	SHA256    string
+}
+
+// Lock pins a download to the project, tag and files recorded in a lockfile.
+type Lock struct {
+	ProjectID int
+	Tag       string
+	Files     []LockedFile
+}
+
+// LockedFile is a downloaded file as recorded in a lockfile.
+type LockedFile struct {
+	URL    string
+	Size   int64
+	SHA256 string
+}
+
+// File returns the locked file downloaded from url, or nil.
+func (l *Lock) File(url string) *LockedFile {
+	for i := range l.Files {
+		if l.Files[i].URL == url {
+			return &l.Files[i]
+		}
+	}
+	return nil
+}
+
+// DownloadInfo describes a download response. Validator holds the strong
+// ETag, or Last-Modified, usable as If-Range when resuming.
+type DownloadInfo struct {
+	Validator string
+	Resumed   bool
+}
+
+// CacheEntry describes a file in the download cache.
+type CacheEntry struct {
+	URL       string    `json:"url"`
+	Validator string    `json:"validator,omitempty"`
+	SHA256    string    `json:"sha256"`
+	Size      int64     `json:"size"`
+	LastUsed  time.Time `json:"last_used"`
+}
+
+const (
+	SHA256 = "sha256"
+	SHA512 = "sha512"
+)
+
+// Checksum is an expected hex-encoded digest of a downloaded file. Size is
+// the expected length in bytes, or 0 if unknown.
+type Checksum struct {
+	Algorithm string
+	Value     string
+	Size      int64
+}
+
+// AssetResult reports the outcome of a single downloaded file.
+type AssetResult struct {
+	ProjectID int
+	Tag       string
+	Name      string
+	URL       string
+	Path      string
+	Size      int64
+	SHA256    string
+	// Extracted lists the unpacked files relative to ExtractOptions.Dir
+	Extracted []string
+	// Skipped is set if an existing file was kept by the overwrite policy
+	Skipped bool
+	Err     error
+}
+
+// URL resolution strategies for ResolutionRule.
+const (
+	StrategyGenericPackage  = "generic-package"
+	StrategyBlobToRaw       = "blob-to-raw"
+	StrategyArtifactRewrite = "artifact-rewrite"
+	StrategySourceArchive   = "source-archive"
+	StrategyTemplate        = "template"
+)
+
+// ResolutionRule maps projects matching a glob pattern to a strategy for
+// building the download URL of a release.
+type ResolutionRule struct {
+	Project  string `yaml:"project" json:"project"`
+	Strategy string `yaml:"strategy" json:"strategy"`
+
+	// Asset is a glob matched against link names (blob-to-raw, artifact-rewrite)
+	Asset string `yaml:"asset,omitempty" json:"asset,omitempty"`
+	// Package and File name a generic package file (generic-package)
+	Package string `yaml:"package,omitempty" json:"package,omitempty"`
+	File    string `yaml:"file,omitempty" json:"file,omitempty"`
+	// Format selects a source archive (source-archive)
+	Format string `yaml:"format,omitempty" json:"format,omitempty"`
+	// Template is a URL with {host}, {projectID}, {project} and {tag} placeholders (template)
+	Template string `yaml:"template,omitempty" json:"template,omitempty"`
+}
+
+// Validate checks that the rule names a known strategy and carries the
+// fields that strategy requires.
+func (r ResolutionRule) Validate() error {
+	if r.Project == "" {
+		return fmt.Errorf("project pattern is required")
+	}
+	if _, err := path.Match(strings.ToLower(r.Project), ""); err != nil {
+		return fmt.Errorf("invalid project pattern %q: %w", r.Project, err)
+	}
+
+	switch r.Strategy {
+	case StrategyGenericPackage:
+		if r.Package == "" || r.File == "" {
+			return fmt.Errorf("strategy %s requires package and file", r.Strategy)
+		}
+	case StrategyTemplate:
+		if r.Template == "" {
+			return fmt.Errorf("strategy %s requires template", r.Strategy)
+		}
+	case StrategyBlobToRaw, StrategyArtifactRewrite, StrategySourceArchive:
+	default:
+		return fmt.Errorf("unknown strategy %q", r.Strategy)
+	}
+
+	if r.Asset != "" {
+		if _, err := path.Match(r.Asset, ""); err != nil {
+			return fmt.Errorf("invalid asset pattern %q: %w", r.Asset, err)
+		}
+	}
+	return nil
 }
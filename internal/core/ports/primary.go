@@ -4,5 +4,34 @@ import "hufschlaeger.net/gitlab-downloader/internal/core/domain"
 
 // ReleaseDownloadPort - Primary Port (Driver)
 type ReleaseDownloadPort interface {
-	DownloadRelease(req domain.DownloadRequest) error
+	DownloadRelease(req domain.DownloadRequest) (*domain.AssetResult, error)
+	DownloadAllAssets(req domain.DownloadRequest) ([]domain.AssetResult, error)
+}
+
+// CacheManagementPort - Primary Port (Driver)
+type CacheManagementPort interface {
+	ListCache() ([]domain.CacheEntry, error)
+	PruneCache(maxSize int64) ([]domain.CacheEntry, error)
+}
+
+// InstallPort - Primary Port (Driver)
+type InstallPort interface {
+	InstallBinary(req domain.InstallRequest) (*domain.AssetResult, error)
+}
+
+// ArtifactsPort - Primary Port (Driver)
+type ArtifactsPort interface {
+	DownloadArtifacts(req domain.ArtifactRequest) (*domain.AssetResult, error)
+}
+
+// ReleaseQueryPort - Primary Port (Driver)
+type ReleaseQueryPort interface {
+	ListReleases(projectName string, filter domain.ReleaseFilter) ([]domain.Release, error)
+	GetRelease(projectName, releaseSpec string) (*domain.Release, error)
+	ListAssets(req domain.DownloadRequest) ([]domain.AssetResult, error)
+}
+
+// VerifyPort - Primary Port (Driver)
+type VerifyPort interface {
+	VerifyFiles(req domain.DownloadRequest, paths []string) ([]domain.AssetResult, error)
 }
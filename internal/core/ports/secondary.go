@@ -1,22 +1,113 @@
 package ports
 
 import (
-	"hufschlaeger.net/gitlab-downloader/internal/core/domain"
+	"errors"
 	"io"
+	"os"
+
+	"hufschlaeger.net/gitlab-downloader/internal/core/domain"
 )
 
+// ErrRangeNotHonored is returned by DownloadPort.DownloadRange when the server
+// answers a range request with the full content or rejects the range. The
+// caller has to restart the download from the beginning.
+var ErrRangeNotHonored = errors.New("server did not honor range request")
+
+// ErrOffline is returned in offline mode for API responses and files which
+// are not in the cache.
+var ErrOffline = errors.New("offline mode")
+
+// ErrNotFound is returned by GitLabPort for projects, releases and tags which
+// do not exist.
+var ErrNotFound = errors.New("not found")
+
+// ErrUnsupportedArchive is returned by ArchivePort.Extract for files which
+// are not an archive in a supported format.
+var ErrUnsupportedArchive = errors.New("unsupported archive format")
+
+// RestartableWriter is implemented by writers passed to
+// DownloadPort.DownloadRange which can discard everything written so far.
+// A download from offset 0 which is interrupted before the server sent a
+// validator then starts over instead of failing.
+type RestartableWriter interface {
+	io.Writer
+	Restart() error
+}
+
 // GitLabPort - Secondary Port (Driven)
 type GitLabPort interface {
+	BaseURL() string
 	GetProject(name string) (*domain.Project, error)
 	GetRelease(projectID int, tag string) (*domain.Release, error)
+	// ListReleases passes the releases of a project to visit, newest first,
+	// until visit returns false or every release was visited. Pages are
+	// fetched only as far as visit goes.
+	ListReleases(projectID int, visit func(domain.Release) bool) error
+	// TagExists reports whether the repository has a tag, with or without
+	// a release.
+	TagExists(projectID int, tag string) (bool, error)
 }
 
 // DownloadPort - Secondary Port (Driven)
 type DownloadPort interface {
 	DownloadFromURL(url string, writer io.Writer) error
+	// DownloadRange calls started, if not nil, once the response headers are
+	// accepted and before any content is written.
+	DownloadRange(url string, offset int64, ifRange string, writer io.Writer, started func(*domain.DownloadInfo)) (*domain.DownloadInfo, error)
+	Validator(url string) (string, error)
+	// FileName returns the file name suggested by the server in the
+	// Content-Disposition header of url, or an empty string.
+	FileName(url string) (string, error)
 }
 
 // FileSystemPort - Secondary Port (Driven)
 type FileSystemPort interface {
 	CreateFile(path string) (io.WriteCloser, error)
+	AppendFile(path string) (io.WriteCloser, int64, error)
+	OpenFile(path string) (io.ReadCloser, error)
+	WriteFile(path string, data []byte) error
+	CreateDir(path string) error
+	Remove(path string) error
+	Exists(path string) (bool, error)
+	IsDir(path string) (bool, error)
+	RemoveAll(path string) error
+	Rename(oldPath, newPath string) error
+	Chmod(path string, mode os.FileMode) error
+}
+
+// CachePort - Secondary Port (Driven)
+//
+// A content-addressed store of downloaded files. Files are keyed by SHA-256
+// and indexed by the URL and validator (ETag or Last-Modified) they were
+// downloaded with.
+type CachePort interface {
+	// Lookup returns the entry with the given SHA-256, or, if sha256 is
+	// empty, the entry recorded for url with the same validator. An empty
+	// validator matches any entry for url. A miss returns nil without error.
+	Lookup(url, validator, sha256 string) (*domain.CacheEntry, error)
+	// Materialize places the cached file at path, hard-linked if possible.
+	Materialize(entry *domain.CacheEntry, path string) error
+	// Store adds the downloaded file at path to the cache.
+	Store(entry domain.CacheEntry, path string) error
+	Evict(sha256 string) error
+	List() ([]domain.CacheEntry, error)
+	// Prune evicts least recently used files until the cache holds at most
+	// maxSize bytes and returns the evicted entries.
+	Prune(maxSize int64) ([]domain.CacheEntry, error)
+}
+
+// ArchivePort - Secondary Port (Driven)
+type ArchivePort interface {
+	// Extract unpacks the archive at path into opts.Dir and returns the
+	// extracted files relative to it. name is the asset's file name, used to
+	// name the output of single compressed files.
+	Extract(path, name string, opts domain.ExtractOptions) ([]string, error)
+}
+
+// StreamExtractPort - Secondary Port (Driven)
+type StreamExtractPort interface {
+	// ExtractFromURL unpacks the archive at url into opts.Dir while it is
+	// downloaded, without storing the archive, and returns the extracted
+	// files relative to opts.Dir.
+	ExtractFromURL(url, name string, opts domain.ExtractOptions) ([]string, error)
 }
@@ -0,0 +1,94 @@
+package services
+
+import (
+	"fmt"
+	neturl "net/url"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"hufschlaeger.net/gitlab-downloader/internal/core/domain"
+)
+
+// DownloadArtifacts downloads the artifacts archive of the latest successful
+// job req.Job of req.Ref or, with req.Path, a single file of it. The result
+// is stored, verified and extracted like a release asset.
+func (s *ReleaseService) DownloadArtifacts(req domain.ArtifactRequest) (*domain.AssetResult, error) {
+	download := domain.DownloadRequest{
+		ProjectName: req.ProjectName,
+		ReleaseTag:  req.Ref,
+		OutputPath:  req.OutputPath,
+		SHA256:      req.SHA256,
+		Continue:    req.Continue,
+		Extract:     req.Extract,
+		Overwrite:   req.Overwrite,
+	}
+	if err := checkOutput(download); err != nil {
+		return nil, err
+	}
+
+	project, err := s.gitlab.GetProject(req.ProjectName)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get project: %w", err)
+	}
+
+	release := s.jobArtifactsRelease(project.ID, req.Ref, req.Job, req.Path)
+	link := release.Assets.Links[0]
+	if download.OutputPath, err = s.artifactsOutputPath(download, release, link.Name); err != nil {
+		return nil, err
+	}
+	result, err := s.downloadAsset(download, release, link.URL)
+	if err != nil {
+		return nil, fmt.Errorf("failed to download artifacts of job %s for %s: %w", req.Job, req.Ref, err)
+	}
+	result.Name = link.Name
+	return result, nil
+}
+
+// artifactsOutputPath fills in name where req.OutputPath asks for the remote
+// file name, as {asset} or by naming a directory. The archive endpoint ends
+// in "download", which would otherwise become the file name.
+func (s *ReleaseService) artifactsOutputPath(req domain.DownloadRequest, release *domain.Release, name string) (string, error) {
+	out := req.OutputPath
+	if out == "" || out == "-" {
+		return out, nil
+	}
+	template := outputTemplate(out)
+	if template.uses("asset") {
+		return strings.ReplaceAll(out, "{asset}", pathComponent(name)), nil
+	}
+
+	dir := isDirPath(out)
+	if !dir {
+		var err error
+		if dir, err = s.filesystem.IsDir(template.expand(outputVars(req.ProjectName, release))); err != nil {
+			return "", err
+		}
+	}
+	if dir {
+		out = filepath.Join(out, pathComponent(name))
+	}
+	return out, nil
+}
+
+// jobArtifactsRelease stands in for a release of ref with a single link to
+// the artifacts of job: the whole archive or, with file, the file at that
+// path inside it. Placeholders such as {tag} expand to ref.
+func (s *ReleaseService) jobArtifactsRelease(projectID int, ref, job, file string) *domain.Release {
+	endpoint, name := "download", "artifacts.zip"
+	if file = strings.Trim(file, "/"); file != "" {
+		segments := strings.Split(file, "/")
+		for i, segment := range segments {
+			segments[i] = neturl.PathEscape(segment)
+		}
+		endpoint, name = "raw/"+strings.Join(segments, "/"), path.Base(file)
+	}
+
+	url := fmt.Sprintf("%s/api/v4/projects/%d/jobs/artifacts/%s/%s?%s",
+		s.host(), projectID, neturl.PathEscape(ref), endpoint, neturl.Values{"job": {job}}.Encode())
+	return &domain.Release{
+		ProjectID: projectID,
+		Tag:       ref,
+		Assets:    domain.Assets{Links: []domain.Link{{Name: name, URL: url}}},
+	}
+}
@@ -0,0 +1,76 @@
+package services
+
+import (
+	"strings"
+	"testing"
+
+	"hufschlaeger.net/gitlab-downloader/internal/core/domain"
+)
+
+func TestDownloadArtifacts(t *testing.T) {
+	gl := &mockGitLab{project: &domain.Project{ID: 7}}
+	dl := &mockDownloader{}
+	fs := &mockFS{}
+	service := newTestService(gl, dl, fs)
+
+	result, err := service.DownloadArtifacts(domain.ArtifactRequest{ProjectName: "g/p", Ref: "feature/x", Job: "build linux", OutputPath: "dist/{tag}.zip", SHA256: dataSHA256})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want := "https://gitlab.example.com/api/v4/projects/7/jobs/artifacts/feature%2Fx/download?job=build+linux"
+	if dl.lastURL != want {
+		t.Fatalf("expected %s, got %s", want, dl.lastURL)
+	}
+	if result.Path != "dist/feature_x.zip" || result.Name != "artifacts.zip" || result.Tag != "feature/x" || fs.files["dist/feature_x.zip"].String() != "DATA" {
+		t.Fatalf("unexpected result %+v", result)
+	}
+	if gl.lastTag != "" {
+		t.Fatalf("artifacts must not look up a release, got %q", gl.lastTag)
+	}
+}
+
+func TestDownloadArtifacts_SingleFile(t *testing.T) {
+	dl := &mockDownloader{}
+	fs := &mockFS{dirs: map[string]bool{"bin": true}}
+	service := newTestService(&mockGitLab{project: &domain.Project{ID: 7}}, dl, fs)
+
+	result, err := service.DownloadArtifacts(domain.ArtifactRequest{ProjectName: "g/p", Ref: "main", Job: "build", Path: "/out/my tool", OutputPath: "bin"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if want := "https://gitlab.example.com/api/v4/projects/7/jobs/artifacts/main/raw/out/my%20tool?job=build"; dl.lastURL != want {
+		t.Fatalf("expected %s, got %s", want, dl.lastURL)
+	}
+	if result.Path != "bin/my tool" || result.Name != "my tool" {
+		t.Fatalf("unexpected result %+v", result)
+	}
+}
+
+func TestDownloadArtifacts_NamesArchiveInDirectory(t *testing.T) {
+	for out, want := range map[string]string{"dist/": "dist/artifacts.zip", "dist/{tag}-{asset}": "dist/v1-artifacts.zip"} {
+		fs := &mockFS{}
+		result, err := newTestService(&mockGitLab{}, &mockDownloader{}, fs).DownloadArtifacts(domain.ArtifactRequest{ProjectName: "g/p", Ref: "v1", Job: "build", OutputPath: out})
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if result.Path != want || fs.files[want] == nil {
+			t.Fatalf("-out %s: expected %s, got %+v", out, want, result)
+		}
+	}
+}
+
+func TestDownloadArtifacts_Errors(t *testing.T) {
+	service := newTestService(&mockGitLab{}, &mockDownloader{failURLs: map[string]bool{
+		"https://gitlab.example.com/api/v4/projects/1/jobs/artifacts/main/download?job=missing": true,
+	}}, &mockFS{})
+
+	_, err := service.DownloadArtifacts(domain.ArtifactRequest{ProjectName: "g/p", Ref: "main", Job: "missing", OutputPath: "a.zip"})
+	if err == nil || !strings.Contains(err.Error(), "failed to download artifacts of job missing for main: download failed: HTTP 404") {
+		t.Fatalf("expected download error, got %v", err)
+	}
+
+	_, err = service.DownloadArtifacts(domain.ArtifactRequest{ProjectName: "g/p", Ref: "main", Job: "build", OutputPath: "-", Continue: true})
+	if err == nil || !strings.Contains(err.Error(), "cannot be resumed or extracted") {
+		t.Fatalf("expected stdout error, got %v", err)
+	}
+}
@@ -0,0 +1,157 @@
+package services
+
+import (
+	"fmt"
+	neturl "net/url"
+	"path"
+	"regexp"
+	"strings"
+
+	"hufschlaeger.net/gitlab-downloader/internal/core/domain"
+)
+
+// assetFilter selects release links by name and sources by format.
+type assetFilter struct {
+	glob   string
+	regex  *regexp.Regexp
+	format string
+}
+
+func newAssetFilter(req domain.DownloadRequest) (*assetFilter, error) {
+	f := &assetFilter{glob: req.AssetGlob, format: strings.ToLower(req.SourceFormat)}
+
+	if f.glob != "" {
+		if _, err := path.Match(f.glob, ""); err != nil {
+			return nil, fmt.Errorf("invalid asset pattern %q: %w", f.glob, err)
+		}
+	}
+
+	if req.AssetRegex != "" {
+		re, err := regexp.Compile(req.AssetRegex)
+		if err != nil {
+			return nil, fmt.Errorf("invalid asset regex %q: %w", req.AssetRegex, err)
+		}
+		f.regex = re
+	}
+
+	return f, nil
+}
+
+// active reports whether any selector was given. Without selectors the
+// positional defaults of determineDownloadURL apply.
+func (f *assetFilter) active() bool {
+	return f.filtersLinks() || f.filtersSources()
+}
+
+func (f *assetFilter) filtersLinks() bool {
+	return f.glob != "" || f.regex != nil
+}
+
+func (f *assetFilter) filtersSources() bool {
+	return f.format != ""
+}
+
+// matchLink matches the link name or the basename of its URL against every
+// configured link selector.
+func (f *assetFilter) matchLink(link domain.Link) bool {
+	if !f.filtersLinks() {
+		return false
+	}
+
+	for _, candidate := range []string{link.Name, urlBaseName(link.URL)} {
+		if candidate == "" {
+			continue
+		}
+		if f.matchName(candidate) {
+			return true
+		}
+	}
+	return false
+}
+
+func (f *assetFilter) matchName(name string) bool {
+	if f.glob != "" {
+		if ok, _ := path.Match(f.glob, name); !ok {
+			return false
+		}
+	}
+	if f.regex != nil && !f.regex.MatchString(name) {
+		return false
+	}
+	return true
+}
+
+func (f *assetFilter) matchSource(source domain.Source) bool {
+	return f.filtersSources() && strings.ToLower(source.Format) == f.format
+}
+
+func (f *assetFilter) describe() string {
+	var parts []string
+	if f.glob != "" {
+		parts = append(parts, fmt.Sprintf("asset %q", f.glob))
+	}
+	if f.regex != nil {
+		parts = append(parts, fmt.Sprintf("asset regex %q", f.regex.String()))
+	}
+	if f.format != "" {
+		parts = append(parts, fmt.Sprintf("format %q", f.format))
+	}
+	return strings.Join(parts, ", ")
+}
+
+// selectAsset returns the download URL of the single asset matching the
+// filter. Empty and ambiguous matches are reported with the candidate list.
+func (s *ReleaseService) selectAsset(projectName string, release *domain.Release, filter *assetFilter) (string, error) {
+	host := s.host()
+
+	var names, urls []string
+	for _, link := range release.Assets.Links {
+		if filter.matchLink(link) {
+			names = append(names, assetFileName(link.Name, link.URL))
+			urls = append(urls, s.rewriteArtifactURL(host, projectName, release, link.URL))
+		}
+	}
+	for _, source := range release.Assets.Sources {
+		if filter.matchSource(source) {
+			names = append(names, source.Format)
+			urls = append(urls, source.URL)
+		}
+	}
+
+	switch len(urls) {
+	case 1:
+		return urls[0], nil
+	case 0:
+		return "", fmt.Errorf("no asset matches %s; candidates: %s", filter.describe(), candidateList(release))
+	default:
+		return "", fmt.Errorf("%s is ambiguous; matches: %s", filter.describe(), strings.Join(names, ", "))
+	}
+}
+
+// candidateList renders all links and source formats of a release for error
+// messages.
+func candidateList(release *domain.Release) string {
+	var candidates []string
+	for _, link := range release.Assets.Links {
+		candidates = append(candidates, assetFileName(link.Name, link.URL))
+	}
+	for _, source := range release.Assets.Sources {
+		candidates = append(candidates, "format:"+source.Format)
+	}
+	if len(candidates) == 0 {
+		return "(none)"
+	}
+	return strings.Join(candidates, ", ")
+}
+
+func urlBaseName(rawURL string) string {
+	u, err := neturl.Parse(rawURL)
+	if err != nil {
+		return ""
+	}
+	base := path.Base(u.Path)
+	if base == "/" || base == "." {
+		return ""
+	}
+	return base
+}
@@ -0,0 +1,108 @@
+package services
+
+import (
+	"strings"
+	"testing"
+
+	"hufschlaeger.net/gitlab-downloader/internal/core/domain"
+)
+
+func selectorRelease() *domain.Release {
+	return &domain.Release{
+		ProjectID: 9,
+		Tag:       "v1.0.0",
+		Assets: domain.Assets{
+			Links: []domain.Link{
+				{Name: "SHA256SUMS", URL: "https://example.com/dl/SHA256SUMS"},
+				{Name: "Linux binary", URL: "https://example.com/dl/app-linux-amd64.tar.gz"},
+				{Name: "app-darwin-arm64.tar.gz", URL: "https://example.com/dl/1"},
+			},
+			Sources: []domain.Source{
+				{Format: "zip", URL: "https://example.com/src.zip"},
+				{Format: "tar.gz", URL: "https://example.com/src.tar.gz"},
+			},
+		},
+	}
+}
+
+func TestSelectAsset(t *testing.T) {
+	cases := []struct {
+		name string
+		req  domain.DownloadRequest
+		want string
+	}{
+		{"glob on URL basename", domain.DownloadRequest{AssetGlob: "*linux-amd64*"}, "https://example.com/dl/app-linux-amd64.tar.gz"},
+		{"glob on link name", domain.DownloadRequest{AssetGlob: "app-darwin-*"}, "https://example.com/dl/1"},
+		{"regex", domain.DownloadRequest{AssetRegex: `^SHA\d+SUMS$`}, "https://example.com/dl/SHA256SUMS"},
+		{"format", domain.DownloadRequest{SourceFormat: "TAR.GZ"}, "https://example.com/src.tar.gz"},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			service := newTestService(&mockGitLab{}, nil, nil)
+			filter, err := newAssetFilter(tc.req)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			got, err := service.selectAsset("group/proj", selectorRelease(), filter)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got != tc.want {
+				t.Fatalf("expected %q, got %q", tc.want, got)
+			}
+		})
+	}
+}
+
+func TestSelectAsset_Errors(t *testing.T) {
+	service := newTestService(&mockGitLab{}, nil, nil)
+
+	filter, _ := newAssetFilter(domain.DownloadRequest{AssetGlob: "*.tar.gz"})
+	_, err := service.selectAsset("group/proj", selectorRelease(), filter)
+	if err == nil || !strings.Contains(err.Error(), "ambiguous") || !strings.Contains(err.Error(), "app-darwin-arm64.tar.gz") {
+		t.Fatalf("expected ambiguity error listing matches, got %v", err)
+	}
+
+	filter, _ = newAssetFilter(domain.DownloadRequest{AssetGlob: "*.exe"})
+	_, err = service.selectAsset("group/proj", selectorRelease(), filter)
+	if err == nil || !strings.Contains(err.Error(), "no asset matches") || !strings.Contains(err.Error(), "SHA256SUMS, Linux binary, app-darwin-arm64.tar.gz, format:zip, format:tar.gz") {
+		t.Fatalf("expected no match error listing candidates, got %v", err)
+	}
+}
+
+func TestNewAssetFilter_InvalidPatterns(t *testing.T) {
+	if _, err := newAssetFilter(domain.DownloadRequest{AssetGlob: "["}); err == nil {
+		t.Fatalf("expected invalid glob error")
+	}
+	if _, err := newAssetFilter(domain.DownloadRequest{AssetRegex: "("}); err == nil {
+		t.Fatalf("expected invalid regex error")
+	}
+}
+
+func TestDownloadRelease_UsesAssetSelector(t *testing.T) {
+	gl := &mockGitLab{release: selectorRelease()}
+	dl := &mockDownloader{}
+	service := newTestService(gl, dl, &mockFS{})
+
+	req := domain.DownloadRequest{ProjectName: "group/proj", ReleaseTag: "v1.0.0", OutputPath: "out", AssetGlob: "*darwin*"}
+	if _, err := service.DownloadRelease(req); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if dl.lastURL != "https://example.com/dl/1" {
+		t.Fatalf("expected selected asset to be downloaded, got %q", dl.lastURL)
+	}
+}
+
+func TestDownloadAllAssets_AppliesFilter(t *testing.T) {
+	gl := &mockGitLab{release: selectorRelease()}
+	service := newTestService(gl, &mockDownloader{}, &mockFS{})
+
+	req := domain.DownloadRequest{ProjectName: "group/proj", ReleaseTag: "v1.0.0", OutputPath: "out", All: true, AssetGlob: "*.tar.gz", SourceFormat: "zip"}
+	results, err := service.DownloadAllAssets(req)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(results) != 3 || results[0].Name != "Linux binary" || results[2].Name != "src.zip" {
+		t.Fatalf("unexpected results: %+v", results)
+	}
+}
@@ -0,0 +1,127 @@
+package services
+
+import (
+	"fmt"
+	"io"
+	"sort"
+
+	"hufschlaeger.net/gitlab-downloader/internal/core/domain"
+	"hufschlaeger.net/gitlab-downloader/internal/core/ports"
+)
+
+// WithCache serves downloads from a local cache when the same file was
+// downloaded before and adds new downloads to it.
+func (s *ReleaseService) WithCache(cache ports.CachePort) *ReleaseService {
+	s.cache = cache
+	return s
+}
+
+// WithOffline serves every file from the cache without network access.
+// Files which are not cached fail with ports.ErrOffline.
+func (s *ReleaseService) WithOffline(offline bool) *ReleaseService {
+	s.offline = offline
+	return s
+}
+
+// WithWarnings reports problems that do not fail a download, such as a file
+// that could not be added to the cache, to w.
+func (s *ReleaseService) WithWarnings(w io.Writer) *ReleaseService {
+	s.warnings = w
+	return s
+}
+
+func (s *ReleaseService) warn(format string, args ...any) {
+	if s.warnings != nil {
+		_, _ = fmt.Fprintf(s.warnings, "Warning: "+format+"\n", args...)
+	}
+}
+
+// cacheKey returns what a cached copy of url has to match: the expected
+// SHA-256 if known, otherwise the current validator of url, which costs a
+// HEAD request.
+func (s *ReleaseService) cacheKey(url string, expected *domain.Checksum) (validator, sha256 string) {
+	if expected != nil && expected.Algorithm == domain.SHA256 {
+		return "", expected.Value
+	}
+	validator, _ = s.downloader.Validator(url)
+	return validator, ""
+}
+
+// fromCache places a cached copy of url at path. The copy is hashed again, so
+// a damaged cache entry is evicted and reported as a miss.
+func (s *ReleaseService) fromCache(url, path, validator, sha256 string, expected *domain.Checksum, overwrite domain.OverwritePolicy) (int64, string, bool) {
+	entry, err := s.cache.Lookup(url, validator, sha256)
+	if err != nil || entry == nil {
+		return 0, "", false
+	}
+	part := partPath(path)
+	if err := s.cache.Materialize(entry, part); err != nil {
+		return 0, "", false
+	}
+
+	tap := newDownloadTap(expected)
+	if err := s.hashExisting(part, tap); err != nil || tap.sum() != entry.SHA256 ||
+		(tap.verifier != nil && tap.verifier.verify() != nil) {
+		_ = s.filesystem.Remove(part)
+		_ = s.cache.Evict(entry.SHA256)
+		return 0, "", false
+	}
+	if err := s.commit(part, path, overwrite); err != nil {
+		return 0, "", false
+	}
+	return tap.size, tap.sum(), true
+}
+
+// offlineCopy places the cached copy of url at path. Without an expected
+// SHA-256 the most recent file cached for url is used.
+func (s *ReleaseService) offlineCopy(url, path string, expected *domain.Checksum, overwrite domain.OverwritePolicy) (int64, string, error) {
+	if s.cache == nil {
+		return 0, "", fmt.Errorf("%w: no cache configured", ports.ErrOffline)
+	}
+
+	var sha256 string
+	if expected != nil && expected.Algorithm == domain.SHA256 {
+		sha256 = expected.Value
+	}
+	if size, sum, ok := s.fromCache(url, path, "", sha256, expected, overwrite); ok {
+		return size, sum, nil
+	}
+	return 0, "", fmt.Errorf("%w: %s is not in the cache", ports.ErrOffline, url)
+}
+
+// storeInCache adds a completed download to the cache. The download itself
+// succeeded, so a failure is reported as a warning only.
+func (s *ReleaseService) storeInCache(url, path, validator string, size int64, sha256 string) {
+	entry := domain.CacheEntry{URL: url, Validator: validator, SHA256: sha256, Size: size}
+	if err := s.cache.Store(entry, path); err != nil {
+		s.warn("failed to cache %s: %v", url, err)
+	}
+}
+
+// CacheService manages the download cache.
+type CacheService struct {
+	cache ports.CachePort
+}
+
+func NewCacheService(cache ports.CachePort) *CacheService {
+	return &CacheService{cache: cache}
+}
+
+// ListCache returns the cached files, most recently used first.
+func (s *CacheService) ListCache() ([]domain.CacheEntry, error) {
+	entries, err := s.cache.List()
+	if err != nil {
+		return nil, err
+	}
+	sort.SliceStable(entries, func(i, j int) bool {
+		return entries[i].LastUsed.After(entries[j].LastUsed)
+	})
+	return entries, nil
+}
+
+func (s *CacheService) PruneCache(maxSize int64) ([]domain.CacheEntry, error) {
+	if maxSize < 0 {
+		return nil, fmt.Errorf("maximum cache size must not be negative")
+	}
+	return s.cache.Prune(maxSize)
+}
@@ -0,0 +1,195 @@
+package services
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"hufschlaeger.net/gitlab-downloader/internal/core/domain"
+	"hufschlaeger.net/gitlab-downloader/internal/core/ports"
+)
+
+// mockCache keeps entries in memory and materialises them into a mockFS.
+type mockCache struct {
+	fs       *mockFS
+	entries  []domain.CacheEntry
+	content  map[string]string
+	evicted  []string
+	lookups  []string
+	storeErr error
+}
+
+func (m *mockCache) Lookup(url, validator, sha256 string) (*domain.CacheEntry, error) {
+	m.lookups = append(m.lookups, url+"|"+validator+"|"+sha256)
+	for i, entry := range m.entries {
+		if (sha256 != "" && entry.SHA256 == sha256) || (sha256 == "" && entry.URL == url && (validator == "" || entry.Validator == validator)) {
+			return &m.entries[i], nil
+		}
+	}
+	return nil, nil
+}
+
+func (m *mockCache) Materialize(entry *domain.CacheEntry, path string) error {
+	return m.fs.WriteFile(path, []byte(m.content[entry.SHA256]))
+}
+
+func (m *mockCache) Store(entry domain.CacheEntry, path string) error {
+	if m.storeErr != nil {
+		return m.storeErr
+	}
+	if m.content == nil {
+		m.content = make(map[string]string)
+	}
+	m.content[entry.SHA256] = m.fs.files[path].String()
+	m.entries = append(m.entries, entry)
+	return nil
+}
+
+func (m *mockCache) Evict(sha256 string) error {
+	m.evicted = append(m.evicted, sha256)
+	return nil
+}
+
+func (m *mockCache) List() ([]domain.CacheEntry, error) {
+	return m.entries, nil
+}
+
+func (m *mockCache) Prune(maxSize int64) ([]domain.CacheEntry, error) {
+	return nil, nil
+}
+
+func TestDownloadRelease_CacheStoresAndServes(t *testing.T) {
+	fs := &mockFS{}
+	cache := &mockCache{fs: fs}
+	dl := &mockDownloader{validator: `"v1"`}
+	service := newTestService(resumeRelease(), dl, fs).WithCache(cache)
+	req := domain.DownloadRequest{ProjectName: "p", ReleaseTag: "v1", OutputPath: "out.zip"}
+
+	if _, err := service.DownloadRelease(req); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(cache.entries) != 1 || cache.entries[0].SHA256 != dataSHA256 || cache.entries[0].Validator != `"v1"` || cache.entries[0].Size != 4 {
+		t.Fatalf("expected download to be cached, got %+v", cache.entries)
+	}
+
+	delete(fs.files, "out.zip")
+	result, err := service.DownloadRelease(req)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if dl.downloads != 1 {
+		t.Fatalf("expected second download to be served from cache, got %d downloads", dl.downloads)
+	}
+	if got := fs.files["out.zip"].String(); got != "DATA" || result.SHA256 != dataSHA256 {
+		t.Fatalf("unexpected cached result: content=%q result=%+v", got, result)
+	}
+}
+
+func TestDownloadRelease_CacheRevalidatesByValidator(t *testing.T) {
+	fs := &mockFS{}
+	cache := &mockCache{fs: fs}
+	dl := &mockDownloader{validator: `"v1"`}
+	service := newTestService(resumeRelease(), dl, fs).WithCache(cache)
+	req := domain.DownloadRequest{ProjectName: "p", ReleaseTag: "v1", OutputPath: "out.zip"}
+
+	_, _ = service.DownloadRelease(req)
+	dl.validator = `"v2"`
+	if _, err := service.DownloadRelease(req); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if dl.downloads != 2 {
+		t.Fatalf("expected changed file to be downloaded again, got %d downloads", dl.downloads)
+	}
+}
+
+func TestDownloadRelease_CacheLooksUpPinnedChecksum(t *testing.T) {
+	fs := &mockFS{}
+	cache := &mockCache{fs: fs, entries: []domain.CacheEntry{{URL: "https://mirror.example.com/app.zip", SHA256: dataSHA256, Size: 4}}, content: map[string]string{dataSHA256: "DATA"}}
+	dl := &mockDownloader{}
+	service := newTestService(resumeRelease(), dl, fs).WithCache(cache)
+
+	req := domain.DownloadRequest{ProjectName: "p", ReleaseTag: "v1", OutputPath: "out.zip", SHA256: dataSHA256}
+	if _, err := service.DownloadRelease(req); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if dl.downloads != 0 || cache.lookups[0] != "https://example.com/app.zip||"+dataSHA256 {
+		t.Fatalf("expected content-addressed hit, got downloads=%d lookups=%v", dl.downloads, cache.lookups)
+	}
+}
+
+func TestDownloadRelease_CacheEvictsDamagedEntry(t *testing.T) {
+	fs := &mockFS{}
+	cache := &mockCache{fs: fs, entries: []domain.CacheEntry{{URL: "https://example.com/app.zip", SHA256: dataSHA256, Size: 4}}, content: map[string]string{dataSHA256: "DAMAGED"}}
+	dl := &mockDownloader{}
+	service := newTestService(resumeRelease(), dl, fs).WithCache(cache)
+
+	req := domain.DownloadRequest{ProjectName: "p", ReleaseTag: "v1", OutputPath: "out.zip", SHA256: dataSHA256}
+	if _, err := service.DownloadRelease(req); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(cache.evicted) != 1 || dl.downloads != 1 || fs.files["out.zip"].String() != "DATA" {
+		t.Fatalf("expected damaged entry to be evicted and re-downloaded, got evicted=%v downloads=%d", cache.evicted, dl.downloads)
+	}
+}
+
+func TestCacheService_ListsMostRecentFirst(t *testing.T) {
+	now := time.Now()
+	cache := &mockCache{entries: []domain.CacheEntry{
+		{URL: "old", LastUsed: now.Add(-time.Hour)},
+		{URL: "new", LastUsed: now},
+	}}
+	entries, err := NewCacheService(cache).ListCache()
+	if err != nil || len(entries) != 2 || entries[0].URL != "new" {
+		t.Fatalf("unexpected entries %+v, err %v", entries, err)
+	}
+
+	if _, err := NewCacheService(cache).PruneCache(-1); err == nil || !strings.Contains(err.Error(), "negative") {
+		t.Fatalf("expected error for negative size, got %v", err)
+	}
+}
+
+// ensure the mock implements the interface
+var _ ports.CachePort = (*mockCache)(nil)
+
+func TestDownloadRelease_OfflineServesFromCache(t *testing.T) {
+	fs := &mockFS{}
+	cache := &mockCache{fs: fs, entries: []domain.CacheEntry{{URL: "https://example.com/app.zip", Validator: `"v1"`, SHA256: dataSHA256, Size: 4}}, content: map[string]string{dataSHA256: "DATA"}}
+	gl := resumeRelease()
+	gl.release.Assets.Links = append(gl.release.Assets.Links, domain.Link{Name: "SHA256SUMS", URL: "https://example.com/SHA256SUMS"})
+	dl := &mockDownloader{downloadErr: errors.New("network used")}
+	service := newTestService(gl, dl, fs).WithCache(cache).WithOffline(true)
+
+	req := domain.DownloadRequest{ProjectName: "p", ReleaseTag: "v1", OutputPath: "out.zip", AssetGlob: "app.zip"}
+	if _, err := service.DownloadRelease(req); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got := fs.files["out.zip"].String(); got != "DATA" {
+		t.Fatalf("expected cached content, got %q", got)
+	}
+}
+
+func TestDownloadRelease_OfflineMiss(t *testing.T) {
+	fs := &mockFS{}
+	service := newTestService(resumeRelease(), &mockDownloader{}, fs).WithCache(&mockCache{fs: fs}).WithOffline(true)
+
+	_, err := service.DownloadRelease(domain.DownloadRequest{ProjectName: "p", ReleaseTag: "v1", OutputPath: "out.zip"})
+	if !errors.Is(err, ports.ErrOffline) || !strings.Contains(err.Error(), "https://example.com/app.zip is not in the cache") {
+		t.Fatalf("expected offline miss, got %v", err)
+	}
+}
+
+func TestDownloadRelease_CacheStoreFailureWarns(t *testing.T) {
+	fs := &mockFS{}
+	cache := &mockCache{fs: fs, storeErr: errors.New("no space left on device")}
+	var warnings bytes.Buffer
+	service := newTestService(resumeRelease(), &mockDownloader{}, fs).WithCache(cache).WithWarnings(&warnings)
+
+	if _, err := service.DownloadRelease(domain.DownloadRequest{ProjectName: "p", ReleaseTag: "v1", OutputPath: "out.zip"}); err != nil {
+		t.Fatalf("expected the download to succeed, got %v", err)
+	}
+	if want := "Warning: failed to cache https://example.com/app.zip: no space left on device\n"; warnings.String() != want {
+		t.Fatalf("expected warning %q, got %q", want, warnings.String())
+	}
+}
@@ -0,0 +1,264 @@
+package services
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/hex"
+	"fmt"
+	"hash"
+	"path"
+	"strings"
+
+	"hufschlaeger.net/gitlab-downloader/internal/core/domain"
+)
+
+// Checksum files published as release links. Per-file checksums use the
+// asset name plus one of checksumSuffixes, aggregated files one of
+// checksumListNames (compared case-insensitively).
+var (
+	checksumSuffixes  = []string{".sha256", ".sha512", ".sha256sum", ".sha512sum"}
+	checksumListNames = []string{"sha256sums", "sha512sums", "sha256sums.txt", "sha512sums.txt", "checksums.txt", "*_checksums.txt", "*.checksums"}
+)
+
+// newChecksum validates a hex digest and derives the algorithm from its length.
+func newChecksum(value string) (*domain.Checksum, error) {
+	value = strings.ToLower(strings.TrimSpace(value))
+	if _, err := hex.DecodeString(value); err != nil {
+		return nil, fmt.Errorf("invalid checksum %q: not hex encoded", value)
+	}
+
+	switch len(value) {
+	case sha256.Size * 2:
+		return &domain.Checksum{Algorithm: domain.SHA256, Value: value}, nil
+	case sha512.Size * 2:
+		return &domain.Checksum{Algorithm: domain.SHA512, Value: value}, nil
+	}
+	return nil, fmt.Errorf("invalid checksum %q: unsupported length %d", value, len(value))
+}
+
+// lockedChecksum returns the checksum and size a lockfile records for a file.
+func lockedChecksum(file *domain.LockedFile) (*domain.Checksum, error) {
+	sum, err := newChecksum(file.SHA256)
+	if err != nil {
+		return nil, fmt.Errorf("invalid lockfile entry: %w", err)
+	}
+	sum.Size = file.Size
+	return sum, nil
+}
+
+func newHash(algorithm string) hash.Hash {
+	if algorithm == domain.SHA512 {
+		return sha512.New()
+	}
+	return sha256.New()
+}
+
+// isChecksumAsset reports whether a file name looks like a published checksum
+// file.
+func isChecksumAsset(name string) bool {
+	lower := strings.ToLower(name)
+	for _, suffix := range checksumSuffixes {
+		if strings.HasSuffix(lower, suffix) {
+			return true
+		}
+	}
+	for _, pattern := range checksumListNames {
+		if ok, _ := path.Match(pattern, lower); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// checksumLookup finds expected digests for release assets. Checksum files are
+// fetched at most once per lookup.
+type checksumLookup struct {
+	service *ReleaseService
+	release *domain.Release
+	fetched map[string][]byte
+}
+
+func (s *ReleaseService) newChecksumLookup(release *domain.Release) *checksumLookup {
+	return &checksumLookup{service: s, release: release, fetched: make(map[string][]byte)}
+}
+
+// find returns the published checksum for a file known under any of names, or
+// nil when the release does not publish one.
+func (l *checksumLookup) find(names []string) (*domain.Checksum, error) {
+	// Offline there is nothing to fetch; cached files were verified against
+	// the published checksums when they were downloaded
+	if l.service.offline {
+		return nil, nil
+	}
+
+	for _, name := range names {
+		if name == "" || isChecksumAsset(name) {
+			continue
+		}
+
+		for _, link := range l.release.Assets.Links {
+			linkName := urlBaseName(link.URL)
+			for _, candidate := range []string{link.Name, linkName} {
+				if candidate == "" || !isChecksumAsset(candidate) {
+					continue
+				}
+				perFile, ok := l.covers(candidate, name)
+				if !ok {
+					continue
+				}
+
+				content, err := l.fetch(link.URL)
+				if err != nil {
+					return nil, fmt.Errorf("failed to fetch checksum file %s: %w", candidate, err)
+				}
+				if sum := parseChecksumFile(content, name, perFile); sum != nil {
+					return sum, nil
+				}
+			}
+		}
+	}
+	return nil, nil
+}
+
+// covers reports whether a checksum file can contain the digest of name and
+// whether it is a per-file checksum ("<name>.sha256") rather than a list.
+func (l *checksumLookup) covers(checksumName, name string) (perFile bool, ok bool) {
+	lower := strings.ToLower(checksumName)
+	for _, suffix := range checksumSuffixes {
+		if strings.HasSuffix(lower, suffix) {
+			return true, strings.EqualFold(checksumName[:len(checksumName)-len(suffix)], name)
+		}
+	}
+	return false, true
+}
+
+func (l *checksumLookup) fetch(url string) ([]byte, error) {
+	if content, ok := l.fetched[url]; ok {
+		return content, nil
+	}
+
+	var buf bytes.Buffer
+	if err := l.service.downloader.DownloadFromURL(url, &buf); err != nil {
+		return nil, err
+	}
+	l.fetched[url] = buf.Bytes()
+	return buf.Bytes(), nil
+}
+
+// parseChecksumFile extracts the digest for name from GNU coreutils
+// ("<hash>  <name>", "<hash> *<name>") or BSD ("SHA256 (<name>) = <hash>")
+// lines. Per-file checksums may also hold just the bare digest.
+func parseChecksumFile(content []byte, name string, perFile bool) *domain.Checksum {
+	var lines []string
+	scanner := bufio.NewScanner(bytes.NewReader(content))
+	for scanner.Scan() {
+		if line := strings.TrimSpace(scanner.Text()); line != "" && !strings.HasPrefix(line, "#") {
+			lines = append(lines, line)
+		}
+	}
+
+	for _, line := range lines {
+		if open := strings.Index(line, " ("); open > 0 && strings.Contains(line, ") = ") {
+			end := strings.LastIndex(line, ") = ")
+			if checksumFileNameMatches(line[open+2:end], name) {
+				if sum, err := newChecksum(line[end+4:]); err == nil {
+					return sum
+				}
+			}
+			continue
+		}
+
+		fields := strings.Fields(line)
+		if len(fields) >= 2 && checksumFileNameMatches(strings.Join(fields[1:], " "), name) {
+			if sum, err := newChecksum(fields[0]); err == nil {
+				return sum
+			}
+		}
+	}
+
+	if perFile && len(lines) == 1 && len(strings.Fields(lines[0])) == 1 {
+		if sum, err := newChecksum(lines[0]); err == nil {
+			return sum
+		}
+	}
+	return nil
+}
+
+func checksumFileNameMatches(entry, name string) bool {
+	entry = strings.TrimPrefix(strings.TrimSpace(entry), "*")
+	entry = strings.TrimPrefix(entry, "./")
+	return entry == name || path.Base(entry) == name
+}
+
+// verifyingWriter hashes and counts everything written to it for comparison
+// with an expected checksum.
+type verifyingWriter struct {
+	expected *domain.Checksum
+	hash     hash.Hash
+	size     int64
+}
+
+func newVerifyingWriter(expected *domain.Checksum) *verifyingWriter {
+	return &verifyingWriter{expected: expected, hash: newHash(expected.Algorithm)}
+}
+
+func (w *verifyingWriter) Write(p []byte) (int, error) {
+	w.size += int64(len(p))
+	return w.hash.Write(p)
+}
+
+func (w *verifyingWriter) reset() {
+	w.hash.Reset()
+	w.size = 0
+}
+
+func (w *verifyingWriter) verify() error {
+	if w.expected.Size > 0 && w.size != w.expected.Size {
+		return fmt.Errorf("size mismatch: expected %d bytes, got %d", w.expected.Size, w.size)
+	}
+	actual := hex.EncodeToString(w.hash.Sum(nil))
+	if actual != w.expected.Value {
+		return fmt.Errorf("checksum mismatch: expected %s %s, got %s", w.expected.Algorithm, w.expected.Value, actual)
+	}
+	return nil
+}
+
+// downloadTap observes the bytes of a download: it always records size and
+// SHA-256, and feeds the optional verifier.
+type downloadTap struct {
+	digest   hash.Hash
+	size     int64
+	verifier *verifyingWriter
+}
+
+func newDownloadTap(expected *domain.Checksum) *downloadTap {
+	t := &downloadTap{digest: sha256.New()}
+	if expected != nil {
+		t.verifier = newVerifyingWriter(expected)
+	}
+	return t
+}
+
+func (t *downloadTap) Write(p []byte) (int, error) {
+	t.digest.Write(p)
+	t.size += int64(len(p))
+	if t.verifier != nil {
+		_, _ = t.verifier.Write(p)
+	}
+	return len(p), nil
+}
+
+// Reset discards everything observed so far, used when a download restarts.
+func (t *downloadTap) Reset() {
+	t.digest.Reset()
+	t.size = 0
+	if t.verifier != nil {
+		t.verifier.reset()
+	}
+}
+
+func (t *downloadTap) sum() string {
+	return hex.EncodeToString(t.digest.Sum(nil))
+}
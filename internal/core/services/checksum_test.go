@@ -0,0 +1,139 @@
+package services
+
+import (
+	"strings"
+	"testing"
+
+	"hufschlaeger.net/gitlab-downloader/internal/core/domain"
+)
+
+// sha256("DATA") and sha512("DATA"), the payload served by mockDownloader.
+const (
+	dataSHA256 = "c97c29c7a71b392b437ee03fd17f09bb10b75e879466fc0eb757b2c4a78ac938"
+	dataSHA512 = "4ba7d4149c32f5ccc6e54190beef0f503d1e637249baa9e4b123f5aa5c89506f299c10a7e32ab1e4bae30ed32df848f87d9b03a640320b0ca758c5ee56cb2db4"
+)
+
+func TestNewChecksum(t *testing.T) {
+	sum, err := newChecksum(strings.ToUpper(dataSHA256))
+	if err != nil || sum.Algorithm != domain.SHA256 || sum.Value != dataSHA256 {
+		t.Fatalf("unexpected checksum %+v, err=%v", sum, err)
+	}
+	sum, err = newChecksum(dataSHA512)
+	if err != nil || sum.Algorithm != domain.SHA512 {
+		t.Fatalf("unexpected checksum %+v, err=%v", sum, err)
+	}
+	if _, err := newChecksum("abc"); err == nil {
+		t.Fatalf("expected error for short checksum")
+	}
+	if _, err := newChecksum(strings.Repeat("z", 64)); err == nil {
+		t.Fatalf("expected error for non-hex checksum")
+	}
+}
+
+func TestParseChecksumFile(t *testing.T) {
+	content := []byte("# generated\n" +
+		strings.Repeat("a", 64) + "  other.zip\n" +
+		dataSHA256 + " *dist/app.zip\n" +
+		"SHA512 (app.tar.gz) = " + dataSHA512 + "\n")
+
+	if sum := parseChecksumFile(content, "app.zip", false); sum == nil || sum.Value != dataSHA256 {
+		t.Fatalf("expected GNU entry, got %+v", sum)
+	}
+	if sum := parseChecksumFile(content, "app.tar.gz", false); sum == nil || sum.Algorithm != domain.SHA512 {
+		t.Fatalf("expected BSD entry, got %+v", sum)
+	}
+	if sum := parseChecksumFile(content, "missing.zip", false); sum != nil {
+		t.Fatalf("expected no entry, got %+v", sum)
+	}
+	if sum := parseChecksumFile([]byte(dataSHA256+"\n"), "app.zip", true); sum == nil {
+		t.Fatalf("expected bare digest for per-file checksum")
+	}
+	if sum := parseChecksumFile([]byte(dataSHA256+"\n"), "app.zip", false); sum != nil {
+		t.Fatalf("bare digest must not apply to checksum lists")
+	}
+}
+
+func checksumRelease(checksumName, checksumURL string) *domain.Release {
+	return &domain.Release{
+		ProjectID: 1,
+		Tag:       "v1",
+		Assets: domain.Assets{Links: []domain.Link{
+			{Name: "app.zip", URL: "https://example.com/app.zip"},
+			{Name: checksumName, URL: checksumURL},
+		}},
+	}
+}
+
+func TestDownloadRelease_VerifiesPublishedChecksum(t *testing.T) {
+	cases := []struct {
+		name         string
+		checksumName string
+		content      string
+	}{
+		{"SHA256SUMS", "SHA256SUMS", dataSHA256 + "  app.zip\n"},
+		{"per-file sha256", "app.zip.sha256", dataSHA256 + "\n"},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			gl := &mockGitLab{release: checksumRelease(tc.checksumName, "https://example.com/sums")}
+			dl := &mockDownloader{content: map[string]string{"https://example.com/sums": tc.content}}
+			fs := &mockFS{}
+			service := newTestService(gl, dl, fs)
+
+			req := domain.DownloadRequest{ProjectName: "p", ReleaseTag: "v1", OutputPath: "out.zip", AssetGlob: "app.zip"}
+			if _, err := service.DownloadRelease(req); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if len(fs.removed) != 0 {
+				t.Fatalf("expected file to be kept, removed %v", fs.removed)
+			}
+		})
+	}
+}
+
+func TestDownloadRelease_ChecksumMismatchRemovesFile(t *testing.T) {
+	gl := &mockGitLab{release: checksumRelease("SHA256SUMS", "https://example.com/sums")}
+	dl := &mockDownloader{content: map[string]string{"https://example.com/sums": strings.Repeat("0", 64) + "  app.zip\n"}}
+	fs := &mockFS{}
+	service := newTestService(gl, dl, fs)
+
+	req := domain.DownloadRequest{ProjectName: "p", ReleaseTag: "v1", OutputPath: "out.zip", AssetGlob: "app.zip"}
+	_, err := service.DownloadRelease(req)
+	if err == nil || !strings.Contains(err.Error(), "checksum mismatch") {
+		t.Fatalf("expected checksum mismatch, got %v", err)
+	}
+	if len(fs.removed) != 1 || fs.removed[0] != "out.zip.part" {
+		t.Fatalf("expected partial file to be removed, got %v", fs.removed)
+	}
+}
+
+func TestDownloadRelease_PinnedSHA256(t *testing.T) {
+	gl := &mockGitLab{release: &domain.Release{ProjectID: 1, Tag: "v1", Assets: domain.Assets{Links: []domain.Link{{URL: "https://example.com/app.zip"}}}}}
+	fs := &mockFS{}
+	service := newTestService(gl, &mockDownloader{}, fs)
+
+	req := domain.DownloadRequest{ProjectName: "p", ReleaseTag: "v1", OutputPath: "out.zip", SHA256: dataSHA256}
+	if _, err := service.DownloadRelease(req); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	req.SHA256 = strings.Repeat("1", 64)
+	if _, err := service.DownloadRelease(req); err == nil || !strings.Contains(err.Error(), "checksum mismatch") {
+		t.Fatalf("expected checksum mismatch, got %v", err)
+	}
+}
+
+func TestDownloadAllAssets_VerifiesEachFile(t *testing.T) {
+	gl := &mockGitLab{release: checksumRelease("SHA256SUMS", "https://example.com/sums")}
+	dl := &mockDownloader{content: map[string]string{"https://example.com/sums": strings.Repeat("0", 64) + "  app.zip\n"}}
+	fs := &mockFS{}
+	service := newTestService(gl, dl, fs)
+
+	results, err := service.DownloadAllAssets(domain.DownloadRequest{ProjectName: "p", ReleaseTag: "v1", OutputPath: "out", All: true})
+	if err == nil {
+		t.Fatalf("expected failure for mismatching asset")
+	}
+	if results[0].Err == nil || !strings.Contains(results[0].Err.Error(), "checksum mismatch") || results[1].Err != nil {
+		t.Fatalf("unexpected results: %+v", results)
+	}
+}
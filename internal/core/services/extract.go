@@ -0,0 +1,65 @@
+package services
+
+import (
+	"fmt"
+
+	"hufschlaeger.net/gitlab-downloader/internal/core/domain"
+	"hufschlaeger.net/gitlab-downloader/internal/core/ports"
+)
+
+// WithExtractor enables unpacking downloaded archives for requests with
+// ExtractOptions.
+func (s *ReleaseService) WithExtractor(archive ports.ArchivePort) *ReleaseService {
+	s.archive = archive
+	return s
+}
+
+// WithStreamExtractor enables extracting archives while they are downloaded,
+// for requests with ExtractOptions but no OutputPath.
+func (s *ReleaseService) WithStreamExtractor(streamer ports.StreamExtractPort) *ReleaseService {
+	s.streamer = streamer
+	return s
+}
+
+// extract unpacks the downloaded file of result into opts.Dir.
+func (s *ReleaseService) extract(result *domain.AssetResult, opts domain.ExtractOptions) error {
+	if s.archive == nil {
+		return fmt.Errorf("archive extraction is not available")
+	}
+
+	files, err := s.archive.Extract(result.Path, result.Name, opts)
+	if err != nil {
+		return fmt.Errorf("failed to extract %s: %w", result.Name, err)
+	}
+	result.Extracted = files
+	return nil
+}
+
+// streamExtract extracts the archive at url without storing it. The archive
+// is never complete on disk, so it can neither be verified nor cached.
+func (s *ReleaseService) streamExtract(release *domain.Release, url string, req domain.DownloadRequest) (*domain.AssetResult, error) {
+	switch {
+	case s.streamer == nil:
+		return nil, fmt.Errorf("extraction from a stream is not available")
+	case s.offline:
+		return nil, fmt.Errorf("%w: archives cannot be extracted from a stream, use an output path", ports.ErrOffline)
+	case req.Lock != nil || req.SHA256 != "":
+		return nil, fmt.Errorf("checksums cannot be verified when extracting from a stream, use an output path")
+	case req.Continue:
+		return nil, fmt.Errorf("extraction from a stream cannot be resumed")
+	}
+
+	result := &domain.AssetResult{
+		ProjectID: release.ProjectID,
+		Tag:       release.Tag,
+		Name:      assetFileName("", url),
+		URL:       url,
+	}
+
+	files, err := s.streamer.ExtractFromURL(url, result.Name, *req.Extract)
+	if err != nil {
+		return nil, fmt.Errorf("failed to extract %s: %w", result.Name, err)
+	}
+	result.Extracted = files
+	return result, nil
+}
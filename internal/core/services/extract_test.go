@@ -0,0 +1,98 @@
+package services
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"hufschlaeger.net/gitlab-downloader/internal/core/domain"
+	"hufschlaeger.net/gitlab-downloader/internal/core/ports"
+)
+
+type mockArchive struct {
+	path, name string
+	opts       domain.ExtractOptions
+	err        error
+}
+
+func (m *mockArchive) Extract(path, name string, opts domain.ExtractOptions) ([]string, error) {
+	m.path, m.name, m.opts = path, name, opts
+	if m.err != nil {
+		return nil, m.err
+	}
+	return []string{"bin/app"}, nil
+}
+
+func TestDownloadRelease_ExtractsArchive(t *testing.T) {
+	archive := &mockArchive{}
+	service := newTestService(resumeRelease(), &mockDownloader{}, &mockFS{}).WithExtractor(archive)
+
+	opts := &domain.ExtractOptions{Dir: "dist", StripComponents: 1}
+	result, err := service.DownloadRelease(domain.DownloadRequest{ProjectName: "p", ReleaseTag: "v1", OutputPath: "out.zip", Extract: opts})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if archive.path != "out.zip" || archive.name != "app.zip" || archive.opts.Dir != "dist" || archive.opts.StripComponents != 1 {
+		t.Fatalf("unexpected extract call: %+v", archive)
+	}
+	if len(result.Extracted) != 1 || result.Extracted[0] != "bin/app" {
+		t.Fatalf("unexpected extracted files %v", result.Extracted)
+	}
+}
+
+func TestDownloadRelease_ExtractErrors(t *testing.T) {
+	req := domain.DownloadRequest{ProjectName: "p", ReleaseTag: "v1", OutputPath: "out.zip", Extract: &domain.ExtractOptions{Dir: "dist"}}
+
+	service := newTestService(resumeRelease(), &mockDownloader{}, &mockFS{})
+	if _, err := service.DownloadRelease(req); err == nil || !strings.Contains(err.Error(), "not available") {
+		t.Fatalf("expected error without extractor, got %v", err)
+	}
+
+	service.WithExtractor(&mockArchive{err: errors.New("unsafe path in archive")})
+	if _, err := service.DownloadRelease(req); err == nil || !strings.Contains(err.Error(), "failed to extract app.zip: unsafe path") {
+		t.Fatalf("expected extraction error, got %v", err)
+	}
+}
+
+type mockStreamer struct {
+	url  string
+	opts domain.ExtractOptions
+}
+
+func (m *mockStreamer) ExtractFromURL(url, name string, opts domain.ExtractOptions) ([]string, error) {
+	m.url, m.opts = url, opts
+	return []string{"config/app.yaml"}, nil
+}
+
+func TestDownloadRelease_ExtractsFromStream(t *testing.T) {
+	streamer := &mockStreamer{}
+	fs := &mockFS{}
+	dl := &mockDownloader{}
+	service := newTestService(resumeRelease(), dl, fs).WithStreamExtractor(streamer)
+
+	result, err := service.DownloadRelease(domain.DownloadRequest{ProjectName: "p", ReleaseTag: "v1", Extract: &domain.ExtractOptions{Dir: "cfg", Include: []string{"config"}}})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if streamer.url != "https://example.com/app.zip" || streamer.opts.Dir != "cfg" || dl.downloads != 0 || len(fs.files) != 0 {
+		t.Fatalf("expected extraction from the stream only, got url=%q downloads=%d files=%v", streamer.url, dl.downloads, fs.files)
+	}
+	if result.Path != "" || len(result.Extracted) != 1 {
+		t.Fatalf("unexpected result %+v", result)
+	}
+}
+
+func TestDownloadRelease_ExtractFromStreamRejectsChecksums(t *testing.T) {
+	service := newTestService(resumeRelease(), &mockDownloader{}, &mockFS{}).WithStreamExtractor(&mockStreamer{})
+
+	req := domain.DownloadRequest{ProjectName: "p", ReleaseTag: "v1", SHA256: dataSHA256, Extract: &domain.ExtractOptions{Dir: "cfg"}}
+	if _, err := service.DownloadRelease(req); err == nil || !strings.Contains(err.Error(), "cannot be verified") {
+		t.Fatalf("expected checksum error, got %v", err)
+	}
+}
+
+// ensure the mocks implement the interfaces
+var (
+	_ ports.ArchivePort       = (*mockArchive)(nil)
+	_ ports.StreamExtractPort = (*mockStreamer)(nil)
+)
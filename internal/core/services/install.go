@@ -0,0 +1,201 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"path"
+	"path/filepath"
+	"runtime"
+	"strings"
+
+	"hufschlaeger.net/gitlab-downloader/internal/core/domain"
+	"hufschlaeger.net/gitlab-downloader/internal/core/ports"
+)
+
+// InstallBinary downloads the release asset built for req.OS and req.Arch,
+// unpacks the executable if the asset is an archive and installs it as
+// BinDir/Name with mode 0755. An existing file is replaced atomically.
+func (s *ReleaseService) InstallBinary(req domain.InstallRequest) (*domain.AssetResult, error) {
+	if s.archive == nil {
+		return nil, fmt.Errorf("archive extraction is not available")
+	}
+	if req.OS == "" {
+		req.OS = runtime.GOOS
+	}
+	if req.Arch == "" {
+		req.Arch = runtime.GOARCH
+	}
+	name := req.Name
+	if name == "" {
+		name = path.Base(req.ProjectName)
+	}
+	if req.OS == "windows" && !strings.HasSuffix(strings.ToLower(name), ".exe") {
+		name += ".exe"
+	}
+
+	release, err := s.fetchRelease(domain.DownloadRequest{ProjectName: req.ProjectName, ReleaseTag: req.ReleaseTag})
+	if err != nil {
+		return nil, err
+	}
+
+	link, err := selectPlatformAsset(release, req)
+	if err != nil {
+		return nil, err
+	}
+	url := s.rewriteArtifactURL(s.host(), req.ProjectName, release, link.URL)
+
+	var expected *domain.Checksum
+	if req.SHA256 != "" {
+		expected, err = newChecksum(req.SHA256)
+	} else {
+		expected, err = s.newChecksumLookup(release).find(s.assetNames(req.ProjectName, release, url))
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.filesystem.CreateDir(req.BinDir); err != nil {
+		return nil, fmt.Errorf("failed to create bin directory: %w", err)
+	}
+
+	// Work files stay in BinDir, so the final rename does not cross file systems
+	result := &domain.AssetResult{
+		ProjectID: release.ProjectID,
+		Tag:       release.Tag,
+		Name:      assetFileName(link.Name, link.URL),
+		URL:       url,
+		Path:      filepath.Join(req.BinDir, name),
+	}
+	download := filepath.Join(req.BinDir, "."+name+".download")
+	defer func() {
+		_ = s.filesystem.Remove(download)
+	}()
+	if result.Size, result.SHA256, err = s.downloadToFile(url, download, expected, false, domain.OverwriteForce); err != nil {
+		return nil, err
+	}
+
+	source := download
+	dir := filepath.Join(req.BinDir, "."+name+".extract")
+	_ = s.filesystem.RemoveAll(dir)
+	defer func() {
+		_ = s.filesystem.RemoveAll(dir)
+	}()
+	files, err := s.archive.Extract(download, result.Name, domain.ExtractOptions{Dir: dir})
+	switch {
+	case errors.Is(err, ports.ErrUnsupportedArchive):
+		// The asset is the executable itself
+	case err != nil:
+		return nil, fmt.Errorf("failed to extract %s: %w", result.Name, err)
+	default:
+		file, err := findExecutable(files, name)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", result.Name, err)
+		}
+		source = filepath.Join(dir, filepath.FromSlash(file))
+	}
+
+	if err := s.installExecutable(source, result.Path); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// selectPlatformAsset picks the release link matching req.AssetGlob, or else
+// the first link with the best platformScore.
+func selectPlatformAsset(release *domain.Release, req domain.InstallRequest) (domain.Link, error) {
+	if req.AssetGlob != "" {
+		filter, err := newAssetFilter(domain.DownloadRequest{AssetGlob: req.AssetGlob})
+		if err != nil {
+			return domain.Link{}, err
+		}
+
+		var matches []domain.Link
+		for _, link := range release.Assets.Links {
+			if filter.matchLink(link) {
+				matches = append(matches, link)
+			}
+		}
+		switch len(matches) {
+		case 1:
+			return matches[0], nil
+		case 0:
+			return domain.Link{}, fmt.Errorf("no asset matches %s; candidates: %s", filter.describe(), candidateList(release))
+		default:
+			var names []string
+			for _, link := range matches {
+				names = append(names, assetFileName(link.Name, link.URL))
+			}
+			return domain.Link{}, fmt.Errorf("%s is ambiguous; matches: %s", filter.describe(), strings.Join(names, ", "))
+		}
+	}
+
+	best, bestScore := domain.Link{}, 0
+	for _, link := range release.Assets.Links {
+		score := max(platformScore(link.Name, req.OS, req.Arch), platformScore(urlBaseName(link.URL), req.OS, req.Arch))
+		if score > bestScore {
+			best, bestScore = link, score
+		}
+	}
+	if bestScore == 0 {
+		return domain.Link{}, fmt.Errorf("no asset for %s/%s found (use -asset to select one); candidates: %s", req.OS, req.Arch, candidateList(release))
+	}
+	return best, nil
+}
+
+// findExecutable returns the extracted file named name, or the only file of
+// the archive.
+func findExecutable(files []string, name string) (string, error) {
+	var matches []string
+	for _, file := range files {
+		if path.Base(file) == name {
+			matches = append(matches, file)
+		}
+	}
+
+	switch {
+	case len(matches) == 1:
+		return matches[0], nil
+	case len(matches) > 1:
+		return "", fmt.Errorf("archive contains several files named %s: %s", name, strings.Join(matches, ", "))
+	case len(files) == 1:
+		return files[0], nil
+	default:
+		return "", fmt.Errorf("archive contains no file named %s (use -name); files: %s", name, strings.Join(files, ", "))
+	}
+}
+
+// installExecutable copies source next to target, makes it executable and
+// renames it over target. Copying keeps files hard-linked from the download
+// cache unchanged.
+func (s *ReleaseService) installExecutable(source, target string) error {
+	tmp := filepath.Join(filepath.Dir(target), "."+filepath.Base(target)+".new")
+
+	in, err := s.filesystem.OpenFile(source)
+	if err != nil {
+		return err
+	}
+	defer func(in io.ReadCloser) {
+		_ = in.Close()
+	}(in)
+
+	out, err := s.filesystem.CreateFile(tmp)
+	if err != nil {
+		return fmt.Errorf("failed to create %s: %w", tmp, err)
+	}
+	_, err = io.Copy(out, in)
+	if closeErr := out.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = s.filesystem.Chmod(tmp, 0o755)
+	}
+	if err == nil {
+		err = s.filesystem.Rename(tmp, target)
+	}
+	if err != nil {
+		_ = s.filesystem.Remove(tmp)
+		return fmt.Errorf("failed to install %s: %w", target, err)
+	}
+	return nil
+}
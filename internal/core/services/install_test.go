@@ -0,0 +1,99 @@
+package services
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"hufschlaeger.net/gitlab-downloader/internal/core/domain"
+	"hufschlaeger.net/gitlab-downloader/internal/core/ports"
+)
+
+// fsArchive "extracts" a fixed set of files into a mockFS, or reports the
+// asset as no archive.
+type fsArchive struct {
+	fs    *mockFS
+	files map[string]string
+}
+
+func (a *fsArchive) Extract(path, name string, opts domain.ExtractOptions) ([]string, error) {
+	if a.files == nil {
+		return nil, ports.ErrUnsupportedArchive
+	}
+	var names []string
+	for file, content := range a.files {
+		_ = a.fs.WriteFile(filepath.Join(opts.Dir, file), []byte(content))
+		names = append(names, file)
+	}
+	return names, nil
+}
+
+func installRelease() *mockGitLab {
+	return &mockGitLab{release: &domain.Release{ProjectID: 1, Tag: "v1.2.0", Assets: domain.Assets{Links: []domain.Link{
+		{Name: "checksums.txt", URL: "https://example.com/checksums.txt"},
+		{Name: "tool_Darwin_arm64.tar.gz", URL: "https://example.com/tool_Darwin_arm64.tar.gz"},
+		{Name: "tool_Linux_x86_64.tar.gz", URL: "https://example.com/tool_Linux_x86_64.tar.gz"},
+		{Name: "tool_Linux_arm64.tar.gz", URL: "https://example.com/tool_Linux_arm64.tar.gz"},
+	}}}}
+}
+
+func TestInstallBinary_FromArchive(t *testing.T) {
+	fs := &mockFS{}
+	dl := &mockDownloader{content: map[string]string{"https://example.com/checksums.txt": dataSHA256 + "  tool_Linux_arm64.tar.gz\n"}}
+	service := newTestService(installRelease(), dl, fs).WithExtractor(&fsArchive{fs: fs, files: map[string]string{"LICENSE": "MIT", "tool": "ELF"}})
+
+	result, err := service.InstallBinary(domain.InstallRequest{ProjectName: "group/tool", ReleaseTag: "v1.2.0", BinDir: "/bin", OS: "linux", Arch: "arm64"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if result.Name != "tool_Linux_arm64.tar.gz" || result.Path != "/bin/tool" || result.SHA256 != dataSHA256 {
+		t.Fatalf("unexpected result %+v", result)
+	}
+	if got := fs.files["/bin/tool"].String(); got != "ELF" || fs.modes["/bin/.tool.new"] != 0o755 {
+		t.Fatalf("expected executable to be installed, got content=%q modes=%v", got, fs.modes)
+	}
+	for name := range fs.files {
+		if strings.HasPrefix(name, "/bin/.") {
+			t.Fatalf("work file %s was left behind", name)
+		}
+	}
+}
+
+func TestInstallBinary_PlainExecutable(t *testing.T) {
+	fs := &mockFS{}
+	gl := &mockGitLab{release: &domain.Release{Tag: "v1", Assets: domain.Assets{Links: []domain.Link{
+		{Name: "tool-linux-amd64", URL: "https://example.com/tool-linux-amd64"},
+		{Name: "tool-windows-amd64.exe", URL: "https://example.com/tool-windows-amd64.exe"},
+	}}}}
+	service := newTestService(gl, &mockDownloader{}, fs).WithExtractor(&fsArchive{fs: fs})
+
+	result, err := service.InstallBinary(domain.InstallRequest{ProjectName: "group/tool", ReleaseTag: "v1", BinDir: "/bin", Name: "tl", OS: "windows", Arch: "amd64"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if result.Path != "/bin/tl.exe" || fs.files["/bin/tl.exe"].String() != "DATA" {
+		t.Fatalf("unexpected result %+v", result)
+	}
+}
+
+func TestInstallBinary_Errors(t *testing.T) {
+	fs := &mockFS{}
+	service := newTestService(installRelease(), &mockDownloader{}, fs).WithExtractor(&fsArchive{fs: fs, files: map[string]string{"LICENSE": "MIT", "README.md": "#"}})
+
+	for _, tc := range []struct {
+		req  domain.InstallRequest
+		want string
+	}{
+		{domain.InstallRequest{OS: "windows", Arch: "amd64"}, "no asset for windows/amd64 found"},
+		{domain.InstallRequest{OS: "linux", Arch: "amd64"}, "archive contains no file named tool"},
+		{domain.InstallRequest{AssetGlob: "*Linux*"}, "is ambiguous"},
+	} {
+		tc.req.ProjectName, tc.req.ReleaseTag, tc.req.BinDir = "group/tool", "v1.2.0", "/bin"
+		if _, err := service.InstallBinary(tc.req); err == nil || !strings.Contains(err.Error(), tc.want) {
+			t.Fatalf("expected error containing %q, got %v", tc.want, err)
+		}
+	}
+}
+
+// ensure the mock implements the interface
+var _ ports.ArchivePort = (*fsArchive)(nil)
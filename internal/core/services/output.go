@@ -0,0 +1,135 @@
+package services
+
+import (
+	"fmt"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"hufschlaeger.net/gitlab-downloader/internal/core/domain"
+)
+
+// outputPlaceholders are the names usable as {name} in output paths.
+var outputPlaceholders = []string{"project", "project_path", "project_id", "tag", "asset"}
+
+var placeholderPattern = regexp.MustCompile(`\{([^{}/]*)\}`)
+
+// outputTemplate is an output path which may contain placeholders such as
+// {tag} or {asset}.
+type outputTemplate string
+
+// check rejects placeholders not listed in outputPlaceholders.
+func (t outputTemplate) check() error {
+	for _, match := range placeholderPattern.FindAllStringSubmatch(string(t), -1) {
+		known := false
+		for _, name := range outputPlaceholders {
+			known = known || match[1] == name
+		}
+		if !known {
+			return fmt.Errorf("unknown placeholder %s in output path; available: {%s}", match[0], strings.Join(outputPlaceholders, "}, {"))
+		}
+	}
+	return nil
+}
+
+func (t outputTemplate) uses(name string) bool {
+	return strings.Contains(string(t), "{"+name+"}")
+}
+
+func (t outputTemplate) expand(vars map[string]string) string {
+	return placeholderPattern.ReplaceAllStringFunc(string(t), func(match string) string {
+		return vars[match[1:len(match)-1]]
+	})
+}
+
+// outputVars returns the placeholder values describing a release. Every
+// value is sanitized to a single path component, except project_path which
+// keeps the group directories.
+func outputVars(projectName string, release *domain.Release) map[string]string {
+	var groups []string
+	for _, part := range strings.Split(strings.Trim(projectName, "/"), "/") {
+		groups = append(groups, pathComponent(part))
+	}
+	return map[string]string{
+		"project":      pathComponent(path.Base(projectName)),
+		"project_path": filepath.Join(groups...),
+		"project_id":   strconv.Itoa(release.ProjectID),
+		"tag":          pathComponent(release.Tag),
+	}
+}
+
+func pathComponent(value string) string {
+	if name := sanitizeFileName(value); name != "" {
+		return name
+	}
+	return "_"
+}
+
+// isDirPath reports whether an output path explicitly names a directory.
+func isDirPath(p string) bool {
+	return strings.HasSuffix(p, "/") || strings.HasSuffix(p, string(filepath.Separator))
+}
+
+// outputPath expands the placeholders of req.OutputPath for the download of
+// url. If the result names a directory, by a trailing separator or because
+// it exists as one, the remote file name is appended. Missing parent
+// directories are created.
+func (s *ReleaseService) outputPath(req domain.DownloadRequest, release *domain.Release, url string) (string, error) {
+	template := outputTemplate(req.OutputPath)
+	vars := outputVars(req.ProjectName, release)
+
+	var name string
+	if template.uses("asset") {
+		name = s.remoteFileName(req.ProjectName, release, url)
+		vars["asset"] = name
+	}
+	out := template.expand(vars)
+
+	dir := isDirPath(out)
+	if !dir {
+		var err error
+		if dir, err = s.filesystem.IsDir(out); err != nil {
+			return "", err
+		}
+	}
+	if dir {
+		if name == "" {
+			name = s.remoteFileName(req.ProjectName, release, url)
+		}
+		out = filepath.Join(out, name)
+	}
+
+	if err := s.filesystem.CreateDir(filepath.Dir(out)); err != nil {
+		return "", fmt.Errorf("failed to create output directory: %w", err)
+	}
+	return out, nil
+}
+
+// remoteFileName names the file behind url after the Content-Disposition
+// sent by the server, the last segment of the URL or the name of the
+// release link, whichever is available first.
+func (s *ReleaseService) remoteFileName(projectName string, release *domain.Release, url string) string {
+	var names []string
+	if !s.offline {
+		// Without a HEAD response the file is named from the URL
+		name, _ := s.downloader.FileName(url)
+		names = append(names, name)
+	}
+	names = append(names, urlBaseName(url))
+
+	host := s.host()
+	for _, link := range release.Assets.Links {
+		if link.URL == url || s.rewriteArtifactURL(host, projectName, release, link.URL) == url {
+			names = append(names, link.Name)
+		}
+	}
+
+	for _, name := range names {
+		if name = sanitizeFileName(name); name != "" {
+			return name
+		}
+	}
+	return "asset"
+}
@@ -0,0 +1,90 @@
+package services
+
+import (
+	"strings"
+	"testing"
+
+	"hufschlaeger.net/gitlab-downloader/internal/core/domain"
+)
+
+func TestDownloadRelease_DirectoryOutput(t *testing.T) {
+	for _, tc := range []struct {
+		name     string
+		fileName string
+		links    []domain.Link
+		want     string
+	}{
+		{"content disposition", "app-linux.tar.gz", []domain.Link{{Name: "App", URL: "https://example.com/app.zip"}}, "dist/app-linux.tar.gz"},
+		{"url base name", "", []domain.Link{{Name: "App", URL: "https://example.com/app.zip"}}, "dist/app.zip"},
+		{"link name", "", []domain.Link{{Name: "App: Linux", URL: "https://example.com/"}}, "dist/App_ Linux"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			gl := &mockGitLab{release: &domain.Release{ProjectID: 1, Tag: "v1", Assets: domain.Assets{Links: tc.links}}}
+			fs := &mockFS{}
+			service := newTestService(gl, &mockDownloader{fileName: tc.fileName}, fs)
+
+			result, err := service.DownloadRelease(domain.DownloadRequest{ProjectName: "p", ReleaseTag: "v1", OutputPath: "dist/"})
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if result.Path != tc.want || fs.files[tc.want] == nil {
+				t.Fatalf("expected %s, got %+v", tc.want, result)
+			}
+		})
+	}
+}
+
+func TestDownloadRelease_ExistingDirectoryOutput(t *testing.T) {
+	fs := &mockFS{dirs: map[string]bool{"dist": true}}
+	service := newTestService(resumeRelease(), &mockDownloader{}, fs)
+
+	result, err := service.DownloadRelease(domain.DownloadRequest{ProjectName: "p", ReleaseTag: "v1", OutputPath: "dist"})
+	if err != nil || result.Path != "dist/app.zip" {
+		t.Fatalf("expected download into directory, got %+v, %v", result, err)
+	}
+}
+
+func TestDownloadRelease_OutputTemplate(t *testing.T) {
+	gl := &mockGitLab{
+		project: &domain.Project{ID: 7},
+		release: &domain.Release{ProjectID: 7, Tag: "v1.0/rc", Assets: domain.Assets{Links: []domain.Link{{URL: "https://example.com/app.zip"}}}},
+	}
+	fs := &mockFS{}
+	service := newTestService(gl, &mockDownloader{}, fs)
+
+	req := domain.DownloadRequest{ProjectName: "group/../sub/proj", ReleaseTag: "v1.0/rc", OutputPath: "dist/{project_path}/{tag}/{project}-{project_id}-{asset}"}
+	result, err := service.DownloadRelease(req)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if want := "dist/group/_/sub/proj/v1.0_rc/proj-7-app.zip"; result.Path != want {
+		t.Fatalf("expected %s, got %s", want, result.Path)
+	}
+	if fs.lastDir != "dist/group/_/sub/proj/v1.0_rc" {
+		t.Fatalf("expected parent directory to be created, got %q", fs.lastDir)
+	}
+
+	req.OutputPath = "dist/{version}"
+	if _, err := service.DownloadRelease(req); err == nil || !strings.Contains(err.Error(), "unknown placeholder {version}") {
+		t.Fatalf("expected placeholder error, got %v", err)
+	}
+}
+
+func TestDownloadAllAssets_OutputTemplate(t *testing.T) {
+	gl := &mockGitLab{release: &domain.Release{ProjectID: 1, Tag: "v1", Assets: domain.Assets{Links: []domain.Link{
+		{Name: "a.zip", URL: "https://example.com/a.zip"},
+		{Name: "b.zip", URL: "https://example.com/b.zip"},
+	}}}}
+	fs := &mockFS{}
+	service := newTestService(gl, &mockDownloader{}, fs)
+
+	results, err := service.DownloadAllAssets(domain.DownloadRequest{ProjectName: "g/p", ReleaseTag: "v1", OutputPath: "dist/{tag}"})
+	if err != nil || results[1].Path != "dist/v1/b.zip" || fs.lastDir != "dist/v1" {
+		t.Fatalf("expected files in the expanded directory, got %+v, %v", results, err)
+	}
+
+	results, err = service.DownloadAllAssets(domain.DownloadRequest{ProjectName: "g/p", ReleaseTag: "v1", OutputPath: "dist/{asset}/{project}"})
+	if err != nil || results[0].Path != "dist/a.zip/p" || results[1].Path != "dist/b.zip/p" {
+		t.Fatalf("expected per-asset paths, got %+v, %v", results, err)
+	}
+}
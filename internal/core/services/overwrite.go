@@ -0,0 +1,87 @@
+package services
+
+import (
+	"fmt"
+
+	"hufschlaeger.net/gitlab-downloader/internal/core/domain"
+)
+
+// skipExisting applies the no-clobber and skip-if-same policies to an
+// existing file at result.Path. If the download is skipped, it fills in size
+// and SHA-256 of the existing file and reports true.
+func (s *ReleaseService) skipExisting(result *domain.AssetResult, expected *domain.Checksum, overwrite domain.OverwritePolicy) (bool, error) {
+	if overwrite != domain.OverwriteNoClobber && overwrite != domain.OverwriteSkipIfSame {
+		return false, nil
+	}
+	exists, err := s.filesystem.Exists(result.Path)
+	if err != nil || !exists {
+		return false, err
+	}
+
+	tap := newDownloadTap(expected)
+	if err := s.hashExisting(result.Path, tap); err != nil {
+		return false, err
+	}
+	matches := tap.verifier != nil && tap.verifier.verify() == nil
+
+	switch overwrite {
+	case domain.OverwriteNoClobber:
+		// Keeping the file must not pass off other content as verified
+		if tap.verifier != nil && !matches {
+			return false, fmt.Errorf("%s exists and does not match the expected checksum", result.Path)
+		}
+	case domain.OverwriteSkipIfSame:
+		if !matches && (expected != nil || !s.sameAsCached(result.URL, tap)) {
+			return false, nil
+		}
+	}
+
+	result.Size, result.SHA256, result.Skipped = tap.size, tap.sum(), true
+	return true, nil
+}
+
+// sameAsCached reports whether the hashed file equals the cached copy of url
+// whose validator matches the current ETag or Last-Modified of the remote.
+// Without a checksum this is the only way to tell an unchanged file.
+func (s *ReleaseService) sameAsCached(url string, tap *downloadTap) bool {
+	if s.cache == nil {
+		return false
+	}
+
+	// Offline the most recent copy of url stands for the remote
+	var validator string
+	if !s.offline {
+		if validator, _ = s.downloader.Validator(url); validator == "" {
+			return false
+		}
+	}
+
+	entry, err := s.cache.Lookup(url, validator, "")
+	return err == nil && entry != nil && entry.Size == tap.size && entry.SHA256 == tap.sum()
+}
+
+// backup renames an existing file at path to path.bak, or to the first free
+// path.~N~ if a backup exists already.
+func (s *ReleaseService) backup(path string) error {
+	exists, err := s.filesystem.Exists(path)
+	if err != nil || !exists {
+		return err
+	}
+
+	target := path + ".bak"
+	for n := 1; ; n++ {
+		taken, err := s.filesystem.Exists(target)
+		if err != nil {
+			return err
+		}
+		if !taken {
+			break
+		}
+		target = fmt.Sprintf("%s.~%d~", path, n)
+	}
+
+	if err := s.filesystem.Rename(path, target); err != nil {
+		return fmt.Errorf("failed to back up %s: %w", path, err)
+	}
+	return nil
+}
@@ -0,0 +1,103 @@
+package services
+
+import (
+	"strings"
+	"testing"
+
+	"hufschlaeger.net/gitlab-downloader/internal/core/domain"
+)
+
+func overwriteRequest(policy domain.OverwritePolicy, sha256 string) domain.DownloadRequest {
+	return domain.DownloadRequest{ProjectName: "p", ReleaseTag: "v1", OutputPath: "out.zip", SHA256: sha256, Overwrite: policy}
+}
+
+func TestDownloadRelease_NoClobber(t *testing.T) {
+	fs := &mockFS{}
+	_ = fs.WriteFile("out.zip", []byte("OLD"))
+	dl := &mockDownloader{}
+	service := newTestService(resumeRelease(), dl, fs)
+
+	result, err := service.DownloadRelease(overwriteRequest(domain.OverwriteNoClobber, ""))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if dl.downloads != 0 || !result.Skipped || fs.files["out.zip"].String() != "OLD" {
+		t.Fatalf("expected existing file to be kept, got downloads=%d result=%+v", dl.downloads, result)
+	}
+
+	_, err = service.DownloadRelease(overwriteRequest(domain.OverwriteNoClobber, dataSHA256))
+	if err == nil || !strings.Contains(err.Error(), "does not match the expected checksum") {
+		t.Fatalf("expected checksum error, got %v", err)
+	}
+
+	delete(fs.files, "out.zip")
+	result, err = service.DownloadRelease(overwriteRequest(domain.OverwriteNoClobber, ""))
+	if err != nil || result.Skipped || fs.files["out.zip"].String() != "DATA" {
+		t.Fatalf("expected missing file to be downloaded, got %+v, %v", result, err)
+	}
+}
+
+func TestDownloadRelease_Backup(t *testing.T) {
+	fs := &mockFS{}
+	_ = fs.WriteFile("out.zip", []byte("OLD"))
+	service := newTestService(resumeRelease(), &mockDownloader{}, fs)
+
+	for _, want := range []string{"out.zip.bak", "out.zip.~1~", "out.zip.~2~"} {
+		if _, err := service.DownloadRelease(overwriteRequest(domain.OverwriteBackup, "")); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if _, ok := fs.files[want]; !ok {
+			t.Fatalf("expected backup %s, got %v", want, fs.files)
+		}
+	}
+	if fs.files["out.zip.bak"].String() != "OLD" || fs.files["out.zip"].String() != "DATA" {
+		t.Fatalf("unexpected files %v", fs.files)
+	}
+}
+
+func TestDownloadRelease_SkipIfSame(t *testing.T) {
+	t.Run("matching checksum", func(t *testing.T) {
+		fs := &mockFS{}
+		_ = fs.WriteFile("out.zip", []byte("DATA"))
+		dl := &mockDownloader{}
+		result, err := newTestService(resumeRelease(), dl, fs).DownloadRelease(overwriteRequest(domain.OverwriteSkipIfSame, dataSHA256))
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if dl.downloads != 0 || !result.Skipped || result.Size != 4 || result.SHA256 != dataSHA256 {
+			t.Fatalf("expected skipped download, got downloads=%d result=%+v", dl.downloads, result)
+		}
+	})
+
+	t.Run("different file", func(t *testing.T) {
+		fs := &mockFS{}
+		_ = fs.WriteFile("out.zip", []byte("OLD"))
+		dl := &mockDownloader{}
+		result, err := newTestService(resumeRelease(), dl, fs).DownloadRelease(overwriteRequest(domain.OverwriteSkipIfSame, dataSHA256))
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if dl.downloads != 1 || result.Skipped || fs.files["out.zip"].String() != "DATA" {
+			t.Fatalf("expected file to be replaced, got downloads=%d result=%+v", dl.downloads, result)
+		}
+	})
+
+	t.Run("cached ETag", func(t *testing.T) {
+		fs := &mockFS{}
+		_ = fs.WriteFile("out.zip", []byte("DATA"))
+		cache := &mockCache{fs: fs, entries: []domain.CacheEntry{{URL: "https://example.com/app.zip", Validator: `"v1"`, SHA256: dataSHA256, Size: 4}}}
+		dl := &mockDownloader{validator: `"v1"`}
+		service := newTestService(resumeRelease(), dl, fs).WithCache(cache)
+
+		result, err := service.DownloadRelease(overwriteRequest(domain.OverwriteSkipIfSame, ""))
+		if err != nil || !result.Skipped {
+			t.Fatalf("expected skipped download, got %+v, %v", result, err)
+		}
+
+		dl.validator = `"v2"`
+		result, err = service.DownloadRelease(overwriteRequest(domain.OverwriteSkipIfSame, ""))
+		if err != nil || result.Skipped || dl.downloads != 1 {
+			t.Fatalf("expected changed remote to be downloaded, got %+v, %v", result, err)
+		}
+	})
+}
@@ -0,0 +1,79 @@
+package services
+
+import (
+	"strings"
+	"unicode"
+)
+
+// osAliases and archAliases list the spellings of GOOS and GOARCH values
+// found in release asset names. Values missing here match themselves.
+var osAliases = map[string][]string{
+	"darwin":  {"darwin", "macos", "osx", "mac", "apple"},
+	"windows": {"windows", "win", "win64", "win32"},
+}
+
+var archAliases = map[string][]string{
+	"amd64":   {"amd64", "x64", "64bit"},
+	"arm64":   {"arm64", "aarch64", "armv8"},
+	"386":     {"386", "i386", "i686", "x86", "32bit"},
+	"arm":     {"arm", "armv6", "armv7", "armhf", "armel"},
+	"ppc64le": {"ppc64le"},
+	"s390x":   {"s390x"},
+	"riscv64": {"riscv64"},
+}
+
+// skippedSuffixes mark checksums, signatures, metadata and OS packages,
+// which are never installed even if their name contains the platform.
+var skippedSuffixes = []string{
+	".sha256", ".sha512", ".md5", ".sum", ".sig", ".asc", ".pem", ".sbom",
+	".json", ".txt", ".deb", ".rpm", ".apk", ".msi", ".pkg", ".dmg",
+}
+
+// platformScore rates how well an asset name fits goos and goarch: 2 for
+// both, 1 for the OS without any architecture (e.g. universal macOS builds)
+// and 0 for names of other platforms.
+func platformScore(name, goos, goarch string) int {
+	lower := strings.ToLower(name)
+	for _, suffix := range skippedSuffixes {
+		if strings.HasSuffix(lower, suffix) {
+			return 0
+		}
+	}
+
+	// x86_64 would be split into two tokens
+	lower = strings.NewReplacer("x86_64", "amd64", "x86-64", "amd64").Replace(lower)
+	tokens := strings.FieldsFunc(lower, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+
+	if !containsAny(tokens, aliases(osAliases, goos)) {
+		return 0
+	}
+	if containsAny(tokens, aliases(archAliases, goarch)) {
+		return 2
+	}
+	for arch, spellings := range archAliases {
+		if arch != goarch && containsAny(tokens, spellings) {
+			return 0
+		}
+	}
+	return 1
+}
+
+func aliases(table map[string][]string, value string) []string {
+	if spellings, ok := table[value]; ok {
+		return spellings
+	}
+	return []string{value}
+}
+
+func containsAny(tokens, words []string) bool {
+	for _, token := range tokens {
+		for _, word := range words {
+			if token == word {
+				return true
+			}
+		}
+	}
+	return false
+}
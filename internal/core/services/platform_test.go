@@ -0,0 +1,30 @@
+package services
+
+import "testing"
+
+func TestPlatformScore(t *testing.T) {
+	for _, tc := range []struct {
+		name, goos, goarch string
+		want               int
+	}{
+		{"tool-linux-amd64.tar.gz", "linux", "amd64", 2},
+		{"tool_Linux_x86_64.tar.gz", "linux", "amd64", 2},
+		{"tool_Linux_arm64.tar.gz", "linux", "arm64", 2},
+		{"tool-aarch64-unknown-linux-musl.tgz", "linux", "arm64", 2},
+		{"tool_Linux_arm64.tar.gz", "linux", "amd64", 0},
+		{"tool-linux-armv7", "linux", "arm", 2},
+		{"tool-linux-armv7", "linux", "arm64", 0},
+		{"tool-linux-386", "linux", "amd64", 0},
+		{"tool-darwin-all.tar.gz", "darwin", "arm64", 1},
+		{"tool_macOS_universal.zip", "darwin", "amd64", 1},
+		{"tool-windows-x64.exe", "windows", "amd64", 2},
+		{"tool-darwin-amd64.tar.gz", "linux", "amd64", 0},
+		{"tool-linux-amd64.tar.gz.sha256", "linux", "amd64", 0},
+		{"tool_linux_amd64.deb", "linux", "amd64", 0},
+		{"tool-linux-amd64.sbom.json", "linux", "amd64", 0},
+	} {
+		if got := platformScore(tc.name, tc.goos, tc.goarch); got != tc.want {
+			t.Fatalf("platformScore(%q, %s/%s) = %d, want %d", tc.name, tc.goos, tc.goarch, got, tc.want)
+		}
+	}
+}
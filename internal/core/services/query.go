@@ -0,0 +1,141 @@
+package services
+
+import (
+	"fmt"
+	"path"
+	"path/filepath"
+
+	"hufschlaeger.net/gitlab-downloader/internal/core/domain"
+)
+
+// ListReleases returns the releases of a project selected by filter, in the
+// order GitLab lists them, newest first. Pages past the limit or the Since
+// cutoff are not fetched.
+func (s *ReleaseService) ListReleases(projectName string, filter domain.ReleaseFilter) ([]domain.Release, error) {
+	if filter.Match != "" {
+		if _, err := path.Match(filter.Match, ""); err != nil {
+			return nil, fmt.Errorf("invalid tag pattern %q: %w", filter.Match, err)
+		}
+	}
+
+	project, err := s.gitlab.GetProject(projectName)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get project: %w", err)
+	}
+
+	// Releases arrive newest first, so the walk ends at the first release
+	// before Since or once Limit releases are selected
+	var selected []domain.Release
+	err = s.gitlab.ListReleases(project.ID, func(release domain.Release) bool {
+		if !filter.Since.IsZero() && release.ReleasedAt.Before(filter.Since) {
+			return false
+		}
+		if keepRelease(release, filter) {
+			selected = append(selected, release)
+		}
+		return filter.Limit <= 0 || len(selected) < filter.Limit
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list releases: %w", err)
+	}
+	return selected, nil
+}
+
+func keepRelease(release domain.Release, filter domain.ReleaseFilter) bool {
+	if !filter.Since.IsZero() && release.ReleasedAt.Before(filter.Since) {
+		return false
+	}
+	if v, ok := parseVersion(release.Tag); ok && v.prerelease != "" && !filter.Prerelease {
+		return false
+	}
+	if filter.Match != "" {
+		if ok, _ := path.Match(filter.Match, release.Tag); !ok {
+			return false
+		}
+	}
+	return true
+}
+
+// GetRelease returns the release selected by a tag, "latest",
+// "latest-stable" or a version constraint. Tags without a release are not
+// replaced by their repository archive.
+func (s *ReleaseService) GetRelease(projectName, releaseSpec string) (*domain.Release, error) {
+	return s.fetchRelease(domain.DownloadRequest{ProjectName: projectName, ReleaseTag: releaseSpec, RepositoryArchive: domain.RepositoryArchiveNever})
+}
+
+// ListAssets returns the files DownloadAllAssets would download for req,
+// with their local names and download URLs, without downloading them.
+func (s *ReleaseService) ListAssets(req domain.DownloadRequest) ([]domain.AssetResult, error) {
+	filter, err := newAssetFilter(req)
+	if err != nil {
+		return nil, err
+	}
+
+	release, err := s.fetchRelease(req)
+	if err != nil {
+		return nil, err
+	}
+
+	results := s.collectAssets(req.ProjectName, release, req.IncludeSources, filter)
+	for i := range results {
+		results[i].ProjectID = release.ProjectID
+		results[i].Tag = release.Tag
+	}
+	return results, nil
+}
+
+// VerifyFiles compares local files with req.SHA256 or, without it, with the
+// checksums the release publishes for files of the same name. A file
+// without a checksum fails verification. The per-file outcome is reported
+// in the returned results.
+func (s *ReleaseService) VerifyFiles(req domain.DownloadRequest, paths []string) ([]domain.AssetResult, error) {
+	var pinned *domain.Checksum
+	if req.SHA256 != "" {
+		var err error
+		if pinned, err = newChecksum(req.SHA256); err != nil {
+			return nil, err
+		}
+	}
+
+	release, err := s.fetchRelease(req)
+	if err != nil {
+		return nil, err
+	}
+
+	lookup := s.newChecksumLookup(release)
+	results := make([]domain.AssetResult, len(paths))
+	failed := 0
+	for i, path := range paths {
+		results[i] = domain.AssetResult{ProjectID: release.ProjectID, Tag: release.Tag, Name: filepath.Base(path), Path: path}
+		expected, err := pinned, error(nil)
+		if expected == nil {
+			expected, err = lookup.find([]string{results[i].Name})
+		}
+		if err == nil {
+			err = s.verifyFile(&results[i], expected)
+		}
+		if err != nil {
+			results[i].Err = err
+			failed++
+		}
+	}
+
+	if failed > 0 {
+		return results, fmt.Errorf("%d of %d files failed verification", failed, len(paths))
+	}
+	return results, nil
+}
+
+// verifyFile hashes the file at result.Path and compares it with expected.
+func (s *ReleaseService) verifyFile(result *domain.AssetResult, expected *domain.Checksum) error {
+	if expected == nil {
+		return fmt.Errorf("release %s publishes no checksum for %s", result.Tag, result.Name)
+	}
+
+	tap := newDownloadTap(expected)
+	if err := s.hashExisting(result.Path, tap); err != nil {
+		return err
+	}
+	result.Size, result.SHA256 = tap.size, tap.sum()
+	return tap.verifier.verify()
+}
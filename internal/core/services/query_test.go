@@ -0,0 +1,144 @@
+package services
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"hufschlaeger.net/gitlab-downloader/internal/core/domain"
+)
+
+func TestListReleases(t *testing.T) {
+	day := func(d int) time.Time { return time.Date(2024, 3, d, 12, 0, 0, 0, time.UTC) }
+	gl := &mockGitLab{releases: []domain.Release{
+		{Tag: "v2.1.0-rc.1", ReleasedAt: day(20)},
+		{Tag: "v2.0.0", ReleasedAt: day(10)},
+		{Tag: "nightly", ReleasedAt: day(5)},
+		{Tag: "v1.0.0", ReleasedAt: day(1)},
+	}}
+	service := newTestService(gl, nil, nil)
+
+	tags := func(filter domain.ReleaseFilter) string {
+		t.Helper()
+		releases, err := service.ListReleases("g/p", filter)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		var tags []string
+		for _, release := range releases {
+			tags = append(tags, release.Tag)
+		}
+		return strings.Join(tags, " ")
+	}
+
+	for _, tc := range []struct {
+		filter domain.ReleaseFilter
+		want   string
+	}{
+		{domain.ReleaseFilter{}, "v2.0.0 nightly v1.0.0"},
+		{domain.ReleaseFilter{Prerelease: true}, "v2.1.0-rc.1 v2.0.0 nightly v1.0.0"},
+		{domain.ReleaseFilter{Since: day(5)}, "v2.0.0 nightly"},
+		{domain.ReleaseFilter{Match: "v*", Prerelease: true}, "v2.1.0-rc.1 v2.0.0 v1.0.0"},
+		{domain.ReleaseFilter{Limit: 2}, "v2.0.0 nightly"},
+	} {
+		if got := tags(tc.filter); got != tc.want {
+			t.Fatalf("filter %+v: expected %q, got %q", tc.filter, tc.want, got)
+		}
+	}
+
+	// The walk stops once the limit is reached or releases get older than Since
+	for _, tc := range []struct {
+		filter  domain.ReleaseFilter
+		visited int
+	}{
+		{domain.ReleaseFilter{Limit: 1}, 2},
+		{domain.ReleaseFilter{Limit: 1, Prerelease: true}, 1},
+		{domain.ReleaseFilter{Since: day(10)}, 3},
+	} {
+		tags(tc.filter)
+		if gl.visited != tc.visited {
+			t.Fatalf("filter %+v: expected %d releases to be fetched, got %d", tc.filter, tc.visited, gl.visited)
+		}
+	}
+
+	if _, err := service.ListReleases("g/p", domain.ReleaseFilter{Match: "["}); err == nil || !strings.Contains(err.Error(), "invalid tag pattern") {
+		t.Fatalf("expected pattern error, got %v", err)
+	}
+
+	gl.projErr = errors.New("boom")
+	if _, err := service.ListReleases("g/p", domain.ReleaseFilter{}); err == nil || !strings.Contains(err.Error(), "failed to get project") {
+		t.Fatalf("expected project error, got %v", err)
+	}
+}
+
+func TestGetRelease_ResolvesLatest(t *testing.T) {
+	gl := &mockGitLab{releases: []domain.Release{{Tag: "v2.0.0-rc1"}, {Tag: "v1.5.0"}}}
+	if _, err := newTestService(gl, nil, nil).GetRelease("g/p", "latest-stable"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if gl.lastTag != "v1.5.0" {
+		t.Fatalf("expected v1.5.0 to be fetched, got %q", gl.lastTag)
+	}
+}
+
+func TestListAssets(t *testing.T) {
+	gl := &mockGitLab{release: &domain.Release{ProjectID: 1, Tag: "v1", Assets: domain.Assets{
+		Links: []domain.Link{
+			{Name: "app.zip", URL: "https://example.com/app.zip"},
+			{Name: "app.txt", URL: "https://example.com/app.txt"},
+		},
+		Sources: []domain.Source{{Format: "zip", URL: "https://example.com/src.zip"}},
+	}}}
+	dl := &mockDownloader{}
+	service := newTestService(gl, dl, &mockFS{})
+
+	results, err := service.ListAssets(domain.DownloadRequest{ProjectName: "g/p", ReleaseTag: "v1", AssetGlob: "*.zip", IncludeSources: true})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(results) != 2 || results[0].Name != "app.zip" || results[1].URL != "https://example.com/src.zip" || results[0].Tag != "v1" {
+		t.Fatalf("unexpected assets %+v", results)
+	}
+	if dl.downloads != 0 {
+		t.Fatalf("listing must not download, got %d downloads", dl.downloads)
+	}
+}
+
+func TestVerifyFiles(t *testing.T) {
+	gl := &mockGitLab{release: checksumRelease("SHA256SUMS", "https://example.com/sums")}
+	dl := &mockDownloader{content: map[string]string{"https://example.com/sums": dataSHA256 + "  app.zip\n"}}
+	fs := &mockFS{}
+	_ = fs.WriteFile("dist/app.zip", []byte("DATA"))
+	_ = fs.WriteFile("bad/app.zip", []byte("OLD"))
+	_ = fs.WriteFile("other.zip", []byte("DATA"))
+	service := newTestService(gl, dl, fs)
+
+	results, err := service.VerifyFiles(domain.DownloadRequest{ProjectName: "p", ReleaseTag: "v1"}, []string{"dist/app.zip", "bad/app.zip", "other.zip", "missing/app.zip"})
+	if err == nil || err.Error() != "3 of 4 files failed verification" {
+		t.Fatalf("expected failure count, got %v", err)
+	}
+	if results[0].Err != nil || results[0].SHA256 != dataSHA256 || results[0].Size != 4 {
+		t.Fatalf("expected dist/app.zip to verify, got %+v", results[0])
+	}
+	for i, want := range []string{"checksum mismatch", "publishes no checksum for other.zip", "failed to read missing/app.zip"} {
+		if err := results[i+1].Err; err == nil || !strings.Contains(err.Error(), want) {
+			t.Fatalf("expected %q for %s, got %v", want, results[i+1].Path, err)
+		}
+	}
+}
+
+func TestVerifyFiles_PinnedChecksum(t *testing.T) {
+	fs := &mockFS{}
+	_ = fs.WriteFile("other.zip", []byte("DATA"))
+	dl := &mockDownloader{}
+	service := newTestService(resumeRelease(), dl, fs)
+
+	results, err := service.VerifyFiles(domain.DownloadRequest{ProjectName: "p", ReleaseTag: "v1", SHA256: dataSHA256}, []string{"other.zip"})
+	if err != nil || results[0].Err != nil {
+		t.Fatalf("expected pinned checksum to verify, got %+v, %v", results, err)
+	}
+	if dl.downloads != 0 {
+		t.Fatalf("expected no checksum download, got %d", dl.downloads)
+	}
+}
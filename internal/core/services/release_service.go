@@ -1,8 +1,11 @@
 package services
 
 import (
+	"errors"
 	"fmt"
 	"io"
+	"path"
+	"path/filepath"
 	"strings"
 
 	"hufschlaeger.net/gitlab-downloader/internal/core/domain"
@@ -13,6 +16,13 @@ type ReleaseService struct {
 	gitlab     ports.GitLabPort
 	downloader ports.DownloadPort
 	filesystem ports.FileSystemPort
+	rules      []domain.ResolutionRule
+	cache      ports.CachePort
+	offline    bool
+	archive    ports.ArchivePort
+	streamer   ports.StreamExtractPort
+	stdout     io.Writer
+	warnings   io.Writer
 }
 
 func NewReleaseService(
@@ -27,81 +37,525 @@ func NewReleaseService(
 	}
 }
 
-func (s *ReleaseService) DownloadRelease(req domain.DownloadRequest) error {
+func (s *ReleaseService) DownloadRelease(req domain.DownloadRequest) (*domain.AssetResult, error) {
+	filter, err := newAssetFilter(req)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkOutput(req); err != nil {
+		return nil, err
+	}
+
+	release, err := s.fetchRelease(req)
+	if err != nil {
+		return nil, err
+	}
+
+	// Determine download URL
+	var url string
+	if filter.active() {
+		if url, err = s.selectAsset(req.ProjectName, release, filter); err != nil {
+			return nil, err
+		}
+	} else {
+		url = s.determineDownloadURL(req.ProjectName, release, req.ExtIndex)
+	}
+	if url == "" {
+		return nil, fmt.Errorf("no download URL found")
+	}
+
+	return s.downloadAsset(req, release, url)
+}
+
+// checkOutput rejects output options which cannot be combined, before any
+// request is sent.
+func checkOutput(req domain.DownloadRequest) error {
+	if err := outputTemplate(req.OutputPath).check(); err != nil {
+		return err
+	}
+	if req.OutputPath == domain.StdoutPath && (req.Continue || req.Extract != nil) {
+		return fmt.Errorf("downloads written to stdout cannot be resumed or extracted")
+	}
+	return nil
+}
+
+// downloadAsset downloads url, a file of release, to the output path of
+// req, verifies it and extracts it if requested.
+func (s *ReleaseService) downloadAsset(req domain.DownloadRequest, release *domain.Release, url string) (*domain.AssetResult, error) {
+	// Without an output path the archive is extracted while it streams
+	if req.OutputPath == "" && req.Extract != nil {
+		return s.streamExtract(release, url, req)
+	}
+
+	// Determine expected checksum: locked, pinned via request or published in release
+	var (
+		expected *domain.Checksum
+		err      error
+	)
+	switch {
+	case req.Lock != nil:
+		locked := req.Lock.File(url)
+		if locked == nil {
+			return nil, fmt.Errorf("download URL %s does not match the lockfile", url)
+		}
+		if expected, err = lockedChecksum(locked); err != nil {
+			return nil, err
+		}
+	case req.SHA256 != "":
+		if expected, err = newChecksum(req.SHA256); err != nil {
+			return nil, err
+		}
+	default:
+		lookup := s.newChecksumLookup(release)
+		if expected, err = lookup.find(s.assetNames(req.ProjectName, release, url)); err != nil {
+			return nil, err
+		}
+	}
+
+	if req.OutputPath == domain.StdoutPath {
+		result := &domain.AssetResult{ProjectID: release.ProjectID, Tag: release.Tag, Name: assetFileName("", url), URL: url, Path: req.OutputPath}
+		if err := s.writeToStdout(result, expected); err != nil {
+			return nil, err
+		}
+		return result, nil
+	}
+
+	out, err := s.outputPath(req, release, url)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &domain.AssetResult{
+		ProjectID: release.ProjectID,
+		Tag:       release.Tag,
+		Name:      assetFileName("", url),
+		URL:       url,
+		Path:      out,
+	}
+	skipped, err := s.skipExisting(result, expected, req.Overwrite)
+	if err != nil {
+		return nil, err
+	}
+	if !skipped {
+		if result.Size, result.SHA256, err = s.downloadToFile(url, out, expected, req.Continue, req.Overwrite); err != nil {
+			return nil, err
+		}
+	}
+
+	if req.Extract != nil {
+		if err := s.extract(result, *req.Extract); err != nil {
+			return nil, err
+		}
+	}
+
+	return result, nil
+}
+
+// DownloadAllAssets downloads every link of a release, and optionally every
+// source archive, into the directory given by req.OutputPath. If the path
+// contains {asset}, it is expanded for every file instead. A failing file
+// does not stop the remaining downloads; the per-file outcome is reported in
+// the returned results.
+func (s *ReleaseService) DownloadAllAssets(req domain.DownloadRequest) ([]domain.AssetResult, error) {
+	filter, err := newAssetFilter(req)
+	if err != nil {
+		return nil, err
+	}
+	template := outputTemplate(req.OutputPath)
+	if err := template.check(); err != nil {
+		return nil, err
+	}
+	if req.OutputPath == domain.StdoutPath {
+		return nil, fmt.Errorf("several assets cannot be written to stdout")
+	}
+
+	release, err := s.fetchRelease(req)
+	if err != nil {
+		return nil, err
+	}
+
+	results := s.collectAssets(req.ProjectName, release, req.IncludeSources, filter)
+	if len(results) == 0 {
+		if filter.active() {
+			return nil, fmt.Errorf("no asset matches %s; candidates: %s", filter.describe(), candidateList(release))
+		}
+		return nil, fmt.Errorf("no assets found in release %s", release.Tag)
+	}
+
+	if req.Lock != nil {
+		if err := checkLockedAssets(req.Lock, results); err != nil {
+			return nil, err
+		}
+	}
+
+	vars := outputVars(req.ProjectName, release)
+	perAsset := template.uses("asset")
+	dir := template.expand(vars)
+	if !perAsset {
+		if err := s.filesystem.CreateDir(dir); err != nil {
+			return nil, fmt.Errorf("failed to create output directory: %w", err)
+		}
+	}
+
+	lookup := s.newChecksumLookup(release)
+	failed := 0
+	for i := range results {
+		results[i].ProjectID = release.ProjectID
+		results[i].Tag = release.Tag
+		var err error
+		if perAsset {
+			vars["asset"] = results[i].Name
+			results[i].Path = template.expand(vars)
+			err = s.filesystem.CreateDir(filepath.Dir(results[i].Path))
+		} else {
+			results[i].Path = filepath.Join(dir, results[i].Name)
+		}
+
+		var expected *domain.Checksum
+		switch {
+		case err != nil:
+			err = fmt.Errorf("failed to create output directory: %w", err)
+		case req.Lock != nil:
+			expected, err = lockedChecksum(req.Lock.File(results[i].URL))
+		default:
+			expected, err = lookup.find(s.assetNames(req.ProjectName, release, results[i].URL))
+		}
+		var skipped bool
+		if err == nil {
+			skipped, err = s.skipExisting(&results[i], expected, req.Overwrite)
+		}
+		if err == nil && !skipped {
+			results[i].Size, results[i].SHA256, err = s.downloadToFile(results[i].URL, results[i].Path, expected, req.Continue, req.Overwrite)
+		}
+		if err != nil {
+			results[i].Err = err
+			failed++
+		}
+	}
+
+	if failed > 0 {
+		return results, fmt.Errorf("%d of %d downloads failed", failed, len(results))
+	}
+
+	return results, nil
+}
+
+// checkLockedAssets ensures a multi-asset download fetches exactly the files
+// recorded in the lockfile.
+func checkLockedAssets(lock *domain.Lock, results []domain.AssetResult) error {
+	for _, result := range results {
+		if lock.File(result.URL) == nil {
+			return fmt.Errorf("asset %s (%s) does not match the lockfile", result.Name, result.URL)
+		}
+	}
+	if len(results) != len(lock.Files) {
+		return fmt.Errorf("lockfile lists %d files, release provides %d", len(lock.Files), len(results))
+	}
+	return nil
+}
+
+func (s *ReleaseService) fetchRelease(req domain.DownloadRequest) (*domain.Release, error) {
 	// Get project
 	project, err := s.gitlab.GetProject(req.ProjectName)
 	if err != nil {
-		return fmt.Errorf("failed to get project: %w", err)
+		return nil, fmt.Errorf("failed to get project: %w", err)
+	}
+
+	// Resolve release tag; a lock pins project and tag
+	var tag string
+	if req.Lock != nil {
+		if project.ID != req.Lock.ProjectID {
+			return nil, fmt.Errorf("project ID %d does not match the lockfile (%d)", project.ID, req.Lock.ProjectID)
+		}
+		tag = req.Lock.Tag
+	} else if tag, err = s.resolveReleaseTag(project.ID, req.ReleaseTag); err != nil {
+		return nil, fmt.Errorf("failed to resolve release: %w", err)
+	}
+
+	if req.RepositoryArchive == domain.RepositoryArchiveAlways {
+		return s.repositoryArchiveRelease(project.ID, tag, req.RepositoryPath), nil
 	}
 
 	// Get release
-	release, err := s.gitlab.GetRelease(project.ID, req.ReleaseTag)
+	release, err := s.gitlab.GetRelease(project.ID, tag)
+	if errors.Is(err, ports.ErrNotFound) && req.RepositoryArchive != domain.RepositoryArchiveNever {
+		// Tags without a release still have a repository archive
+		exists, tagErr := s.gitlab.TagExists(project.ID, tag)
+		if tagErr != nil {
+			return nil, fmt.Errorf("failed to look up tag %s: %w", tag, tagErr)
+		}
+		if exists {
+			return s.repositoryArchiveRelease(project.ID, tag, req.RepositoryPath), nil
+		}
+	}
 	if err != nil {
-		return fmt.Errorf("failed to get release: %w", err)
+		return nil, fmt.Errorf("failed to get release: %w", err)
 	}
 
-	// Determine download URL
-	url := s.determineDownloadURL(req.ProjectName, release, req.ExtIndex)
-	if url == "" {
-		return fmt.Errorf("no download URL found")
+	return release, nil
+}
+
+// downloadToFile streams url into path and returns its size and SHA-256.
+// The content is written to a temporary file next to path, which is renamed
+// into place only once it is complete and verified, so path either does not
+// exist or holds a complete file. With resume, the temporary file of an
+// earlier failed download is continued instead of starting over. With a
+// cache, a cached copy is used instead and new downloads are added to it.
+// The overwrite policy decides whether an existing file is backed up.
+func (s *ReleaseService) downloadToFile(url, path string, expected *domain.Checksum, resume bool, overwrite domain.OverwritePolicy) (int64, string, error) {
+	if s.offline {
+		return s.offlineCopy(url, path, expected, overwrite)
+	}
+
+	var validator, sha256 string
+	if s.cache != nil {
+		validator, sha256 = s.cacheKey(url, expected)
+		if validator != "" || sha256 != "" {
+			if size, sum, ok := s.fromCache(url, path, validator, sha256, expected, overwrite); ok {
+				return size, sum, nil
+			}
+		}
+	}
+
+	part := partPath(path)
+	tap := newDownloadTap(expected)
+
+	resumed := false
+	if resume {
+		var err error
+		if resumed, err = s.resumeDownload(url, part, tap); err != nil {
+			return 0, "", err
+		}
+	}
+
+	if !resumed {
+		tap.Reset()
+		if err := s.fullDownload(url, part, tap, resume); err != nil {
+			return 0, "", err
+		}
+	}
+
+	// Verify
+	if tap.verifier != nil {
+		if err := tap.verifier.verify(); err != nil {
+			s.removeValidator(part)
+			if removeErr := s.filesystem.Remove(part); removeErr != nil {
+				return 0, "", fmt.Errorf("%w (failed to remove %s: %v)", err, part, removeErr)
+			}
+			return 0, "", err
+		}
+	}
+
+	if err := s.commit(part, path, overwrite); err != nil {
+		return 0, "", err
+	}
+
+	if s.cache != nil {
+		s.storeInCache(url, path, validator, tap.size, tap.sum())
 	}
 
+	return tap.size, tap.sum(), nil
+}
+
+// commit renames a complete temporary file to path, replacing an existing
+// file atomically or, with OverwriteBackup, after moving it aside.
+func (s *ReleaseService) commit(part, path string, overwrite domain.OverwritePolicy) error {
+	if overwrite == domain.OverwriteBackup {
+		if err := s.backup(path); err != nil {
+			_ = s.filesystem.Remove(part)
+			return err
+		}
+	}
+	if err := s.filesystem.Rename(part, path); err != nil {
+		_ = s.filesystem.Remove(part)
+		return fmt.Errorf("failed to move download into place: %w", err)
+	}
+	return nil
+}
+
+// fullDownload writes the complete content of url to the temporary file
+// path, truncating any existing file. A failed download is removed, unless
+// keepPartial is set: then the response validator is stored next to it as
+// soon as the response arrives, so a later run can resume it even if this
+// one is killed.
+func (s *ReleaseService) fullDownload(url, path string, tap *downloadTap, keepPartial bool) error {
 	// Create output file
-	file, err := s.filesystem.CreateFile(req.OutputPath)
+	file, err := s.filesystem.CreateFile(path)
 	if err != nil {
 		return fmt.Errorf("failed to create output file: %w", err)
 	}
-	defer func(file io.WriteCloser) {
-		err := file.Close()
-		if err != nil {
-			_ = fmt.Errorf("failed to close file: %w", err)
+
+	var started func(*domain.DownloadInfo)
+	if keepPartial {
+		started = func(info *domain.DownloadInfo) {
+			s.saveValidator(path, info)
 		}
-	}(file)
+	}
 
 	// Download
-	if err := s.downloader.DownloadFromURL(url, file); err != nil {
+	part := &partFile{filesystem: s.filesystem, path: path, file: file, tap: tap}
+	_, err = s.downloader.DownloadRange(url, 0, "", part, started)
+	if closeErr := part.file.Close(); closeErr != nil && err == nil {
+		return s.discardPartial(path, closeErr)
+	}
+	if err != nil {
+		if !keepPartial {
+			_ = s.filesystem.Remove(path)
+			s.removeValidator(path)
+		}
 		return fmt.Errorf("download failed: %w", err)
 	}
 
+	// A validator left by an earlier run no longer belongs to the file
+	s.removeValidator(path)
 	return nil
 }
 
-func (s *ReleaseService) determineDownloadURL(projectName string, release *domain.Release, extIndex int) string {
-	projectLower := strings.ToLower(projectName)
-	host := "https://gitlab.la-bw.de"
-
-	switch projectLower {
-	case "dimag/ingest/ingestprozessmodul":
-		return s.buildIngestURL(host, release)
-	case "dimag/access/accessmodul":
-		return s.buildAccessURL(host, projectName, release)
-	default:
-		return s.buildGenericURL(host, projectName, release, extIndex)
+// partFile is the destination of a full download. It implements
+// ports.RestartableWriter, so a download interrupted before the server sent
+// a validator can start over with an empty file.
+type partFile struct {
+	filesystem ports.FileSystemPort
+	path       string
+	file       io.WriteCloser
+	tap        *downloadTap
+}
+
+func (f *partFile) Write(p []byte) (int, error) {
+	return io.MultiWriter(f.file, f.tap).Write(p)
+}
+
+// Restart truncates the file and discards what tap has observed.
+func (f *partFile) Restart() error {
+	// The content is discarded, so a failure to flush it does not matter
+	_ = f.file.Close()
+	file, err := f.filesystem.CreateFile(f.path)
+	if err != nil {
+		return err
 	}
+	f.file = file
+	f.tap.Reset()
+	return nil
 }
 
-func (s *ReleaseService) buildIngestURL(host string, release *domain.Release) string {
-	return fmt.Sprintf("%s/api/v4/projects/%d/packages/generic/releases/%s/ipm.%s.zip",
-		host, release.ProjectID, release.Tag, release.Tag)
+// discardPartial removes a temporary file whose content may not have
+// reached the disk, so it is neither committed nor resumed.
+func (s *ReleaseService) discardPartial(path string, closeErr error) error {
+	_ = s.filesystem.Remove(path)
+	_ = s.filesystem.Remove(validatorPath(path))
+	return fmt.Errorf("failed to write %s: %w", path, closeErr)
 }
 
-func (s *ReleaseService) buildAccessURL(host, projectName string, release *domain.Release) string {
+// assetNames returns the file names under which the asset behind url is
+// published, used to look up its checksum.
+func (s *ReleaseService) assetNames(projectName string, release *domain.Release, url string) []string {
+	host := s.host()
+	names := []string{urlBaseName(url)}
+
+	for _, link := range release.Assets.Links {
+		if link.URL == url || s.rewriteArtifactURL(host, projectName, release, link.URL) == url {
+			names = append(names, link.Name, urlBaseName(link.URL))
+		}
+	}
+	return names
+}
+
+// collectAssets lists every downloadable file of a release with a unique,
+// filesystem-safe name. An active filter restricts links to matching names
+// and sources to the matching format.
+func (s *ReleaseService) collectAssets(projectName string, release *domain.Release, includeSources bool, filter *assetFilter) []domain.AssetResult {
+	host := s.host()
+	used := make(map[string]int)
+
+	var results []domain.AssetResult
 	for _, link := range release.Assets.Links {
-		if strings.Contains(strings.ToLower(link.Name), "access") {
-			urlPath := strings.Replace(
-				link.URL,
-				fmt.Sprintf("%s/%s/-/blob/", host, projectName),
-				"",
-				1,
-			)
-			parts := strings.SplitN(urlPath, "/", 2)
-			if len(parts) == 2 {
-				return fmt.Sprintf("%s/api/v4/projects/%d/repository/files/%s/raw?ref=%s",
-					host, release.ProjectID, parts[1], parts[0])
+		if filter.active() && !filter.matchLink(link) {
+			continue
+		}
+		url := s.rewriteArtifactURL(host, projectName, release, link.URL)
+		name := assetFileName(link.Name, link.URL)
+		results = append(results, domain.AssetResult{Name: uniqueName(used, name), URL: url})
+	}
+
+	if includeSources || filter.filtersSources() {
+		for _, source := range release.Assets.Sources {
+			if filter.filtersSources() && !filter.matchSource(source) {
+				continue
 			}
+			name := assetFileName("", source.URL)
+			results = append(results, domain.AssetResult{Name: uniqueName(used, name), URL: source.URL})
 		}
 	}
-	return ""
+
+	return results
+}
+
+// assetFileName derives a local file name from a link name, falling back to
+// the last path segment of the URL.
+func assetFileName(name, rawURL string) string {
+	name = sanitizeFileName(name)
+	if name != "" {
+		return name
+	}
+
+	name = sanitizeFileName(urlBaseName(rawURL))
+	if name == "" {
+		name = "asset"
+	}
+	return name
+}
+
+func sanitizeFileName(name string) string {
+	name = strings.TrimSpace(name)
+	name = strings.Map(func(r rune) rune {
+		switch r {
+		case '/', '\\', ':', '*', '?', '"', '<', '>', '|':
+			return '_'
+		}
+		return r
+	}, name)
+	if name == "." || name == ".." {
+		return ""
+	}
+	return name
+}
+
+func uniqueName(used map[string]int, name string) string {
+	used[name]++
+	if used[name] == 1 {
+		return name
+	}
+
+	stem, ext := splitExt(name)
+	candidate := fmt.Sprintf("%s-%d%s", stem, used[name]-1, ext)
+	return uniqueName(used, candidate)
+}
+
+// splitExt splits a file name into stem and extension, keeping compound
+// archive extensions such as ".tar.gz" together.
+func splitExt(name string) (string, string) {
+	ext := path.Ext(name)
+	stem := strings.TrimSuffix(name, ext)
+	if path.Ext(stem) == ".tar" {
+		ext = ".tar" + ext
+		stem = strings.TrimSuffix(stem, ".tar")
+	}
+	return stem, ext
+}
+
+// host returns the configured GitLab instance URL used to rewrite web UI
+// links into API URLs.
+func (s *ReleaseService) host() string {
+	return strings.TrimSuffix(s.gitlab.BaseURL(), "/")
+}
+
+func (s *ReleaseService) determineDownloadURL(projectName string, release *domain.Release, extIndex int) string {
+	host := s.host()
+
+	if rule := s.matchRule(projectName); rule != nil {
+		return s.applyRule(rule, host, projectName, release, extIndex)
+	}
+	return s.buildGenericURL(host, projectName, release, extIndex)
 }
 
 func (s *ReleaseService) buildGenericURL(host, projectName string, release *domain.Release, extIndex int) string {
@@ -109,13 +563,7 @@ func (s *ReleaseService) buildGenericURL(host, projectName string, release *doma
 		url := release.Assets.Links[0].URL
 
 		if strings.Contains(url, "artifacts") {
-			url = strings.Replace(
-				url,
-				fmt.Sprintf("%s/%s/-/", host, projectName),
-				fmt.Sprintf("%s/api/v4/projects/%d/", host, release.ProjectID),
-				1,
-			)
-			return strings.TrimSuffix(url, "/download")
+			return s.rewriteArtifactURL(host, projectName, release, url)
 		}
 
 		if strings.Contains(url, "uploads") && extIndex < len(release.Assets.Sources) {
@@ -131,3 +579,19 @@ func (s *ReleaseService) buildGenericURL(host, projectName string, release *doma
 
 	return ""
 }
+
+// rewriteArtifactURL converts a CI job artifacts link from the web UI into
+// the corresponding API endpoint. Other URLs are returned unchanged.
+func (s *ReleaseService) rewriteArtifactURL(host, projectName string, release *domain.Release, url string) string {
+	if !strings.Contains(url, "artifacts") {
+		return url
+	}
+
+	url = strings.Replace(
+		url,
+		fmt.Sprintf("%s/%s/-/", host, projectName),
+		fmt.Sprintf("%s/api/v4/projects/%d/", host, release.ProjectID),
+		1,
+	)
+	return strings.TrimSuffix(url, "/download")
+}
@@ -4,6 +4,8 @@ import (
 	"bytes"
 	"errors"
 	"io"
+	"os"
+	"reflect"
 	"strings"
 	"testing"
 
@@ -12,10 +14,27 @@ import (
 )
 
 type mockGitLab struct {
-	project *domain.Project
-	release *domain.Release
-	projErr error
-	relErr  error
+	baseURL  string
+	project  *domain.Project
+	release  *domain.Release
+	releases []domain.Release
+	projErr  error
+	relErr   error
+	listErr  error
+	visited  int
+	lastTag  string
+	tags     map[string]bool
+	// pageSize splits releases into pages like the GitLab API; pages counts
+	// the pages fetched by the last ListReleases call
+	pageSize int
+	pages    int
+}
+
+func (m *mockGitLab) BaseURL() string {
+	if m.baseURL != "" {
+		return m.baseURL
+	}
+	return "https://gitlab.example.com"
 }
 
 func (m *mockGitLab) GetProject(name string) (*domain.Project, error) {
@@ -29,6 +48,7 @@ func (m *mockGitLab) GetProject(name string) (*domain.Project, error) {
 }
 
 func (m *mockGitLab) GetRelease(projectID int, tag string) (*domain.Release, error) {
+	m.lastTag = tag
 	if m.relErr != nil {
 		return nil, m.relErr
 	}
@@ -38,16 +58,46 @@ func (m *mockGitLab) GetRelease(projectID int, tag string) (*domain.Release, err
 	return &domain.Release{ProjectID: projectID, Tag: tag}, nil
 }
 
+func (m *mockGitLab) ListReleases(projectID int, visit func(domain.Release) bool) error {
+	m.visited = 0
+	m.pages = 0
+	if m.listErr != nil {
+		return m.listErr
+	}
+	for i, release := range m.releases {
+		if m.pageSize > 0 && i%m.pageSize == 0 {
+			m.pages++
+		}
+		m.visited++
+		if !visit(release) {
+			break
+		}
+	}
+	return nil
+}
+
+func (m *mockGitLab) TagExists(projectID int, tag string) (bool, error) {
+	return m.tags[tag], nil
+}
+
 type writeCatcher struct {
 	bytes.Buffer
+	closeErr error
 }
 
-func (w *writeCatcher) Close() error { return nil }
+func (w *writeCatcher) Close() error { return w.closeErr }
 
 type mockFS struct {
 	createErr error
+	closeErr  error
+	dirErr    error
 	lastPath  string
+	lastDir   string
+	dirs      map[string]bool
 	wc        *writeCatcher
+	files     map[string]*writeCatcher
+	modes     map[string]os.FileMode
+	removed   []string
 }
 
 func (m *mockFS) CreateFile(path string) (io.WriteCloser, error) {
@@ -55,22 +105,163 @@ func (m *mockFS) CreateFile(path string) (io.WriteCloser, error) {
 		return nil, m.createErr
 	}
 	m.lastPath = path
-	m.wc = &writeCatcher{}
+	m.wc = &writeCatcher{closeErr: m.closeErr}
+	if m.files == nil {
+		m.files = make(map[string]*writeCatcher)
+	}
+	m.files[path] = m.wc
 	return m.wc, nil
 }
 
+func (m *mockFS) CreateDir(path string) error {
+	m.lastDir = path
+	if m.dirs == nil {
+		m.dirs = make(map[string]bool)
+	}
+	m.dirs[path] = true
+	return m.dirErr
+}
+
+func (m *mockFS) AppendFile(path string) (io.WriteCloser, int64, error) {
+	if m.createErr != nil {
+		return nil, 0, m.createErr
+	}
+	if wc, ok := m.files[path]; ok {
+		wc.closeErr = m.closeErr
+		m.wc = wc
+		return wc, int64(wc.Len()), nil
+	}
+	wc, err := m.CreateFile(path)
+	return wc, 0, err
+}
+
+func (m *mockFS) OpenFile(path string) (io.ReadCloser, error) {
+	wc, ok := m.files[path]
+	if !ok {
+		return nil, errors.New("not found")
+	}
+	return io.NopCloser(bytes.NewReader(wc.Bytes())), nil
+}
+
+func (m *mockFS) WriteFile(path string, data []byte) error {
+	if m.files == nil {
+		m.files = make(map[string]*writeCatcher)
+	}
+	m.files[path] = &writeCatcher{}
+	_, err := m.files[path].Write(data)
+	return err
+}
+
+func (m *mockFS) Remove(path string) error {
+	m.removed = append(m.removed, path)
+	delete(m.files, path)
+	return nil
+}
+
+func (m *mockFS) Exists(path string) (bool, error) {
+	_, ok := m.files[path]
+	return ok, nil
+}
+
+func (m *mockFS) IsDir(path string) (bool, error) {
+	return m.dirs[path], nil
+}
+
+func (m *mockFS) RemoveAll(path string) error {
+	m.removed = append(m.removed, path)
+	for name := range m.files {
+		if name == path || strings.HasPrefix(name, path+"/") {
+			delete(m.files, name)
+		}
+	}
+	return nil
+}
+
+func (m *mockFS) Rename(oldPath, newPath string) error {
+	wc, ok := m.files[oldPath]
+	if !ok {
+		return errors.New("not found")
+	}
+	delete(m.files, oldPath)
+	m.files[newPath] = wc
+	return nil
+}
+
+func (m *mockFS) Chmod(path string, mode os.FileMode) error {
+	if _, ok := m.files[path]; !ok {
+		return errors.New("not found")
+	}
+	if m.modes == nil {
+		m.modes = make(map[string]os.FileMode)
+	}
+	m.modes[path] = mode
+	return nil
+}
+
 type mockDownloader struct {
-	lastURL     string
-	downloadErr error
+	lastURL      string
+	lastOffset   int64
+	lastIfRange  string
+	downloadErr  error
+	failURLs     map[string]bool
+	content      map[string]string
+	validator    string
+	fileName     string
+	rangeIgnored bool
+	downloads    int
+	beforeWrite  func()
+	// interrupted is written before the download restarts, like a body
+	// interrupted before the server sent a validator
+	interrupted string
 }
 
 func (m *mockDownloader) DownloadFromURL(url string, writer io.Writer) error {
+	_, err := m.DownloadRange(url, 0, "", writer, nil)
+	return err
+}
+
+func (m *mockDownloader) DownloadRange(url string, offset int64, ifRange string, writer io.Writer, started func(*domain.DownloadInfo)) (*domain.DownloadInfo, error) {
+	info := &domain.DownloadInfo{Validator: m.validator}
+	if m.failURLs[url] {
+		return info, errors.New("HTTP 404")
+	}
+	if offset > 0 && m.rangeIgnored {
+		return info, ports.ErrRangeNotHonored
+	}
+	if started != nil {
+		started(info)
+	}
+	if m.beforeWrite != nil {
+		m.beforeWrite()
+	}
 	if m.downloadErr != nil {
-		return m.downloadErr
+		return info, m.downloadErr
 	}
+	m.downloads++
 	m.lastURL = url
-	_, err := writer.Write([]byte("DATA"))
-	return err
+	m.lastOffset = offset
+	m.lastIfRange = ifRange
+	data, ok := m.content[url]
+	if !ok {
+		data = "DATA"
+	}
+	info.Resumed = offset > 0
+	if m.interrupted != "" {
+		_, _ = writer.Write([]byte(m.interrupted))
+		if err := writer.(ports.RestartableWriter).Restart(); err != nil {
+			return info, err
+		}
+	}
+	_, err := writer.Write([]byte(data[offset:]))
+	return info, err
+}
+
+func (m *mockDownloader) Validator(url string) (string, error) {
+	return m.validator, nil
+}
+
+func (m *mockDownloader) FileName(url string) (string, error) {
+	return m.fileName, nil
 }
 
 // Helper to build a service with pluggable parts
@@ -78,8 +269,14 @@ func newTestService(gl ports.GitLabPort, dl ports.DownloadPort, fs ports.FileSys
 	return NewReleaseService(gl, dl, fs)
 }
 
+// dimagRules mirrors rules.example.yaml.
+var dimagRules = []domain.ResolutionRule{
+	{Project: "dimag/ingest/ingestprozessmodul", Strategy: domain.StrategyGenericPackage, Package: "releases", File: "ipm.{tag}.zip"},
+	{Project: "dimag/access/accessmodul", Strategy: domain.StrategyBlobToRaw, Asset: "*access*"},
+}
+
 func TestDetermineDownloadURL_Ingest(t *testing.T) {
-	service := newTestService(nil, nil, nil)
+	service := newTestService(&mockGitLab{baseURL: "https://gitlab.la-bw.de/"}, nil, nil).WithRules(dimagRules)
 	release := &domain.Release{ProjectID: 123, Tag: "v1.2.3"}
 	url := service.determineDownloadURL("DiMAG/Ingest/IngestProzessModul", release, 0)
 	expected := "https://gitlab.la-bw.de/api/v4/projects/123/packages/generic/releases/v1.2.3/ipm.v1.2.3.zip"
@@ -89,7 +286,7 @@ func TestDetermineDownloadURL_Ingest(t *testing.T) {
 }
 
 func TestDetermineDownloadURL_Access(t *testing.T) {
-	service := newTestService(nil, nil, nil)
+	service := newTestService(&mockGitLab{baseURL: "https://gitlab.la-bw.de"}, nil, nil).WithRules(dimagRules)
 	release := &domain.Release{
 		ProjectID: 456,
 		Tag:       "v2.0.0",
@@ -100,7 +297,7 @@ func TestDetermineDownloadURL_Access(t *testing.T) {
 			},
 		}},
 	}
-	url := service.buildAccessURL("https://gitlab.la-bw.de", "DiMAG/Access/AccessModul", release)
+	url := service.determineDownloadURL("DiMAG/Access/AccessModul", release, 0)
 	expected := "https://gitlab.la-bw.de/api/v4/projects/456/repository/files/path/to/access-installer.exe/raw?ref=v2.0.0"
 	if url != expected {
 		t.Fatalf("expected %q, got %q", expected, url)
@@ -177,7 +374,7 @@ func TestDownloadRelease_Success(t *testing.T) {
 	service := newTestService(gl, dl, fs)
 
 	req := domain.DownloadRequest{ProjectName: "group/proj", ReleaseTag: "v1.0.0", OutputPath: "out.zip", ExtIndex: 0}
-	if err := service.DownloadRelease(req); err != nil {
+	if _, err := service.DownloadRelease(req); err != nil {
 		t.Fatalf("unexpected error: %v", err)
 	}
 	if dl.lastURL != "https://example.com/app.zip" {
@@ -241,10 +438,286 @@ func TestDownloadRelease_Errors(t *testing.T) {
 	for _, tc := range cases {
 		t.Run(tc.name, func(t *testing.T) {
 			service := newTestService(tc.gitlab, tc.dl, tc.fs)
-			err := service.DownloadRelease(tc.req)
+			_, err := service.DownloadRelease(tc.req)
 			if err == nil || !strings.Contains(err.Error(), tc.expectErr) {
 				t.Fatalf("expected error containing %q, got %v", tc.expectErr, err)
 			}
 		})
 	}
 }
+
+func TestDownloadAllAssets_Success(t *testing.T) {
+	gl := &mockGitLab{
+		release: &domain.Release{
+			ProjectID: 5,
+			Tag:       "v1.0.0",
+			Assets: domain.Assets{
+				Links: []domain.Link{
+					{Name: "app-linux.tar.gz", URL: "https://example.com/a"},
+					{URL: "https://example.com/dl/SHA256SUMS"},
+					{Name: "app-linux.tar.gz", URL: "https://example.com/b"},
+				},
+				Sources: []domain.Source{{Format: "zip", URL: "https://example.com/proj-v1.0.0.zip"}},
+			},
+		},
+	}
+	fs := &mockFS{}
+	service := newTestService(gl, &mockDownloader{}, fs)
+
+	req := domain.DownloadRequest{ProjectName: "group/proj", ReleaseTag: "v1.0.0", OutputPath: "dist", All: true, IncludeSources: true}
+	results, err := service.DownloadAllAssets(req)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if fs.lastDir != "dist" {
+		t.Fatalf("expected output directory to be created, got %q", fs.lastDir)
+	}
+
+	wantNames := []string{"app-linux.tar.gz", "SHA256SUMS", "app-linux-1.tar.gz", "proj-v1.0.0.zip"}
+	if len(results) != len(wantNames) {
+		t.Fatalf("expected %d results, got %+v", len(wantNames), results)
+	}
+	for i, want := range wantNames {
+		if results[i].Name != want {
+			t.Fatalf("result %d: expected name %q, got %q", i, want, results[i].Name)
+		}
+		if _, ok := fs.files[results[i].Path]; !ok {
+			t.Fatalf("expected file %q to be written", results[i].Path)
+		}
+	}
+}
+
+func TestDownloadAllAssets_ReportsFailures(t *testing.T) {
+	gl := &mockGitLab{
+		release: &domain.Release{
+			ProjectID: 5,
+			Tag:       "v1.0.0",
+			Assets: domain.Assets{
+				Links: []domain.Link{{Name: "ok", URL: "https://example.com/ok"}, {Name: "bad", URL: "https://example.com/bad"}},
+			},
+		},
+	}
+	dl := &mockDownloader{failURLs: map[string]bool{"https://example.com/bad": true}}
+	service := newTestService(gl, dl, &mockFS{})
+
+	results, err := service.DownloadAllAssets(domain.DownloadRequest{ProjectName: "p", ReleaseTag: "v1.0.0", OutputPath: "out", All: true})
+	if err == nil || !strings.Contains(err.Error(), "1 of 2 downloads failed") {
+		t.Fatalf("expected failure summary error, got %v", err)
+	}
+	if results[0].Err != nil || results[1].Err == nil {
+		t.Fatalf("unexpected per-file results: %+v", results)
+	}
+}
+
+func TestDownloadAllAssets_NoAssets(t *testing.T) {
+	gl := &mockGitLab{release: &domain.Release{ProjectID: 1, Tag: "v1"}}
+	service := newTestService(gl, &mockDownloader{}, &mockFS{})
+	_, err := service.DownloadAllAssets(domain.DownloadRequest{ProjectName: "p", ReleaseTag: "v1", OutputPath: "out", All: true})
+	if err == nil || !strings.Contains(err.Error(), "no assets found") {
+		t.Fatalf("expected no assets error, got %v", err)
+	}
+}
+
+func TestAssetFileName(t *testing.T) {
+	cases := []struct{ name, url, want string }{
+		{"app.zip", "https://example.com/x", "app.zip"},
+		{"", "https://example.com/dl/app.tar.gz?x=1", "app.tar.gz"},
+		{"../evil", "https://example.com/x", ".._evil"},
+		{"", "https://example.com/", "asset"},
+	}
+	for _, tc := range cases {
+		if got := assetFileName(tc.name, tc.url); got != tc.want {
+			t.Fatalf("assetFileName(%q, %q) = %q, want %q", tc.name, tc.url, got, tc.want)
+		}
+	}
+}
+
+func TestDownloadRelease_FailedDownloadRemovesPartialFile(t *testing.T) {
+	gl := &mockGitLab{release: &domain.Release{ProjectID: 1, Tag: "t", Assets: domain.Assets{Links: []domain.Link{{URL: "https://example.com"}}}}}
+	fs := &mockFS{}
+	service := newTestService(gl, &mockDownloader{downloadErr: errors.New("net")}, fs)
+
+	_, err := service.DownloadRelease(domain.DownloadRequest{ProjectName: "p", ReleaseTag: "t", OutputPath: "out"})
+	if err == nil {
+		t.Fatalf("expected error")
+	}
+	if len(fs.removed) != 1 || fs.removed[0] != "out.part" {
+		t.Fatalf("expected partial file to be removed, got %v", fs.removed)
+	}
+}
+
+func TestDownloadRelease_FailedDownloadKeepsExistingFile(t *testing.T) {
+	fs := &mockFS{}
+	_ = fs.WriteFile("out.zip", []byte("OLD"))
+	service := newTestService(resumeRelease(), &mockDownloader{downloadErr: errors.New("net")}, fs)
+
+	if _, err := service.DownloadRelease(domain.DownloadRequest{ProjectName: "p", ReleaseTag: "v1", OutputPath: "out.zip"}); err == nil {
+		t.Fatalf("expected error")
+	}
+	if got := fs.files["out.zip"].String(); got != "OLD" {
+		t.Fatalf("expected existing file to stay untouched, got %q", got)
+	}
+
+	service = newTestService(resumeRelease(), &mockDownloader{}, fs)
+	if _, err := service.DownloadRelease(domain.DownloadRequest{ProjectName: "p", ReleaseTag: "v1", OutputPath: "out.zip"}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, ok := fs.files["out.zip.part"]; ok || fs.files["out.zip"].String() != "DATA" {
+		t.Fatalf("expected download to be renamed into place, got %v", fs.files)
+	}
+}
+
+func TestDownloadRelease_FailedCloseIsNotCommitted(t *testing.T) {
+	for _, resume := range []bool{false, true} {
+		fs := &mockFS{closeErr: errors.New("fsync: input/output error")}
+		_ = fs.WriteFile("out.zip", []byte("OLD"))
+		if resume {
+			_ = fs.WriteFile("out.zip.part", []byte("DA"))
+		}
+		service := newTestService(resumeRelease(), &mockDownloader{}, fs)
+
+		_, err := service.DownloadRelease(domain.DownloadRequest{ProjectName: "p", ReleaseTag: "v1", OutputPath: "out.zip", Continue: resume})
+		if err == nil || !strings.Contains(err.Error(), "failed to write out.zip.part: fsync") {
+			t.Fatalf("resume=%v: expected close error, got %v", resume, err)
+		}
+		if _, ok := fs.files["out.zip.part"]; ok || fs.files["out.zip"].String() != "OLD" {
+			t.Fatalf("resume=%v: expected partial file to be discarded and output kept, got %v", resume, fs.files)
+		}
+	}
+}
+
+func TestDetermineDownloadURL_UsesConfiguredHost(t *testing.T) {
+	service := newTestService(&mockGitLab{baseURL: "https://gitlab.com"}, nil, nil)
+	release := &domain.Release{
+		ProjectID: 42,
+		Tag:       "v0.1.0",
+		Assets: domain.Assets{Links: []domain.Link{
+			{URL: "https://gitlab.com/group/proj/-/jobs/123456/artifacts/download"},
+		}},
+	}
+	url := service.determineDownloadURL("group/proj", release, 0)
+	expected := "https://gitlab.com/api/v4/projects/42/jobs/123456/artifacts"
+	if url != expected {
+		t.Fatalf("expected %q, got %q", expected, url)
+	}
+
+	service.WithRules(dimagRules)
+	url = service.determineDownloadURL("dimag/ingest/ingestprozessmodul", release, 0)
+	expected = "https://gitlab.com/api/v4/projects/42/packages/generic/releases/v0.1.0/ipm.v0.1.0.zip"
+	if url != expected {
+		t.Fatalf("expected %q, got %q", expected, url)
+	}
+}
+
+func TestDownloadRelease_ReturnsResult(t *testing.T) {
+	service := newTestService(resumeRelease(), &mockDownloader{}, &mockFS{})
+
+	result, err := service.DownloadRelease(domain.DownloadRequest{ProjectName: "p", ReleaseTag: "v1", OutputPath: "out.zip"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want := domain.AssetResult{ProjectID: 1, Tag: "v1", Name: "app.zip", URL: "https://example.com/app.zip", Path: "out.zip", Size: 4, SHA256: dataSHA256}
+	if !reflect.DeepEqual(*result, want) {
+		t.Fatalf("expected %+v, got %+v", want, *result)
+	}
+}
+
+func TestDownloadRelease_Lock(t *testing.T) {
+	lock := func(sum string) *domain.Lock {
+		return &domain.Lock{ProjectID: 1, Tag: "v1", Files: []domain.LockedFile{{URL: "https://example.com/app.zip", Size: 4, SHA256: sum}}}
+	}
+
+	t.Run("pins tag without resolving", func(t *testing.T) {
+		gl := resumeRelease()
+		gl.listErr = errors.New("must not list releases")
+		service := newTestService(gl, &mockDownloader{}, &mockFS{})
+
+		req := domain.DownloadRequest{ProjectName: "p", ReleaseTag: "latest", OutputPath: "out.zip", Lock: lock(dataSHA256)}
+		if _, err := service.DownloadRelease(req); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if gl.lastTag != "v1" {
+			t.Fatalf("expected locked tag v1, got %q", gl.lastTag)
+		}
+	})
+
+	t.Run("checksum mismatch", func(t *testing.T) {
+		fs := &mockFS{}
+		service := newTestService(resumeRelease(), &mockDownloader{}, fs)
+
+		req := domain.DownloadRequest{ProjectName: "p", ReleaseTag: "v1", OutputPath: "out.zip", Lock: lock(strings.Repeat("0", 64))}
+		if _, err := service.DownloadRelease(req); err == nil || !strings.Contains(err.Error(), "checksum mismatch") {
+			t.Fatalf("expected checksum mismatch, got %v", err)
+		}
+		if _, ok := fs.files["out.zip"]; ok {
+			t.Fatalf("expected mismatching file to be removed")
+		}
+	})
+
+	t.Run("size mismatch", func(t *testing.T) {
+		l := lock(dataSHA256)
+		l.Files[0].Size = 5
+		fs := &mockFS{}
+		service := newTestService(resumeRelease(), &mockDownloader{}, fs)
+
+		req := domain.DownloadRequest{ProjectName: "p", ReleaseTag: "v1", OutputPath: "out.zip", Lock: l}
+		if _, err := service.DownloadRelease(req); err == nil || !strings.Contains(err.Error(), "size mismatch: expected 5 bytes, got 4") {
+			t.Fatalf("expected size mismatch, got %v", err)
+		}
+		if _, ok := fs.files["out.zip"]; ok {
+			t.Fatalf("expected mismatching file to be removed")
+		}
+	})
+
+	t.Run("url not locked", func(t *testing.T) {
+		l := lock(dataSHA256)
+		l.Files[0].URL = "https://example.com/other.zip"
+		service := newTestService(resumeRelease(), &mockDownloader{}, &mockFS{})
+
+		req := domain.DownloadRequest{ProjectName: "p", ReleaseTag: "v1", OutputPath: "out.zip", Lock: l}
+		if _, err := service.DownloadRelease(req); err == nil || !strings.Contains(err.Error(), "does not match the lockfile") {
+			t.Fatalf("expected lockfile mismatch, got %v", err)
+		}
+	})
+
+	t.Run("project id changed", func(t *testing.T) {
+		l := lock(dataSHA256)
+		l.ProjectID = 2
+		service := newTestService(resumeRelease(), &mockDownloader{}, &mockFS{})
+
+		req := domain.DownloadRequest{ProjectName: "p", ReleaseTag: "v1", OutputPath: "out.zip", Lock: l}
+		if _, err := service.DownloadRelease(req); err == nil || !strings.Contains(err.Error(), "project ID 1 does not match") {
+			t.Fatalf("expected project mismatch, got %v", err)
+		}
+	})
+}
+
+func TestDownloadAllAssets_LockRejectsChangedAssets(t *testing.T) {
+	gl := &mockGitLab{
+		release: &domain.Release{
+			ProjectID: 1,
+			Tag:       "v1",
+			Assets:    domain.Assets{Links: []domain.Link{{Name: "a", URL: "https://example.com/a"}, {Name: "b", URL: "https://example.com/b"}}},
+		},
+	}
+	fs := &mockFS{}
+	service := newTestService(gl, &mockDownloader{}, fs)
+
+	lock := &domain.Lock{ProjectID: 1, Tag: "v1", Files: []domain.LockedFile{{URL: "https://example.com/a", SHA256: dataSHA256}}}
+	_, err := service.DownloadAllAssets(domain.DownloadRequest{ProjectName: "p", ReleaseTag: "v1", OutputPath: "out", All: true, Lock: lock})
+	if err == nil || !strings.Contains(err.Error(), "asset b") {
+		t.Fatalf("expected lockfile mismatch for asset b, got %v", err)
+	}
+	if len(fs.files) != 0 {
+		t.Fatalf("expected nothing to be downloaded, got %v", fs.files)
+	}
+
+	lock.Files = append(lock.Files, domain.LockedFile{URL: "https://example.com/b", SHA256: dataSHA256})
+	results, err := service.DownloadAllAssets(domain.DownloadRequest{ProjectName: "p", ReleaseTag: "v1", OutputPath: "out", All: true, Lock: lock})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if results[1].SHA256 != dataSHA256 || results[1].Size != 4 || results[1].Tag != "v1" {
+		t.Fatalf("unexpected result: %+v", results[1])
+	}
+}
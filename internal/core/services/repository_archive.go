@@ -0,0 +1,31 @@
+package services
+
+import (
+	"fmt"
+	neturl "net/url"
+
+	"hufschlaeger.net/gitlab-downloader/internal/core/domain"
+)
+
+// repositoryArchiveFormats are the formats of GitLab's repository archive
+// endpoint, in the order of release sources so -ext selects the same format.
+var repositoryArchiveFormats = []string{"zip", "tar.gz", "tar.bz2", "tar"}
+
+// repositoryArchiveRelease stands in for the release of a tag that has none.
+// It has no asset links; its sources are the repository archives of ref,
+// limited to the directory subdir if set.
+func (s *ReleaseService) repositoryArchiveRelease(projectID int, ref, subdir string) *domain.Release {
+	query := neturl.Values{"sha": {ref}}
+	if subdir != "" {
+		query.Set("path", subdir)
+	}
+
+	release := &domain.Release{ProjectID: projectID, Tag: ref}
+	for _, format := range repositoryArchiveFormats {
+		release.Assets.Sources = append(release.Assets.Sources, domain.Source{
+			Format: format,
+			URL:    fmt.Sprintf("%s/api/v4/projects/%d/repository/archive.%s?%s", s.host(), projectID, format, query.Encode()),
+		})
+	}
+	return release
+}
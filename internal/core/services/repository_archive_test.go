@@ -0,0 +1,74 @@
+package services
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"hufschlaeger.net/gitlab-downloader/internal/core/domain"
+	"hufschlaeger.net/gitlab-downloader/internal/core/ports"
+)
+
+func legacyTagGitLab() *mockGitLab {
+	return &mockGitLab{
+		project: &domain.Project{ID: 7},
+		relErr:  fmt.Errorf("HTTP 404: %w", ports.ErrNotFound),
+		tags:    map[string]bool{"v0.9": true},
+	}
+}
+
+func TestDownloadRelease_RepositoryArchiveFallback(t *testing.T) {
+	dl := &mockDownloader{}
+	service := newTestService(legacyTagGitLab(), dl, &mockFS{})
+
+	req := domain.DownloadRequest{ProjectName: "g/p", ReleaseTag: "v0.9", OutputPath: "src.tar.gz", ExtIndex: 1, RepositoryPath: "docs/api"}
+	result, err := service.DownloadRelease(req)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want := "https://gitlab.example.com/api/v4/projects/7/repository/archive.tar.gz?path=docs%2Fapi&sha=v0.9"
+	if dl.lastURL != want || result.Tag != "v0.9" {
+		t.Fatalf("expected %s, got %s (%+v)", want, dl.lastURL, result)
+	}
+
+	req.ExtIndex, req.RepositoryPath, req.SourceFormat = 0, "", "tar.bz2"
+	if _, err := service.DownloadRelease(req); err != nil || !strings.HasSuffix(dl.lastURL, "/archive.tar.bz2?sha=v0.9") {
+		t.Fatalf("expected -format to select the archive, got %s, %v", dl.lastURL, err)
+	}
+}
+
+func TestDownloadRelease_RepositoryArchiveModes(t *testing.T) {
+	t.Run("never", func(t *testing.T) {
+		req := domain.DownloadRequest{ProjectName: "g/p", ReleaseTag: "v0.9", OutputPath: "out", RepositoryArchive: domain.RepositoryArchiveNever}
+		_, err := newTestService(legacyTagGitLab(), &mockDownloader{}, &mockFS{}).DownloadRelease(req)
+		if err == nil || !strings.Contains(err.Error(), "failed to get release: HTTP 404") {
+			t.Fatalf("expected release error, got %v", err)
+		}
+	})
+
+	t.Run("missing tag", func(t *testing.T) {
+		req := domain.DownloadRequest{ProjectName: "g/p", ReleaseTag: "v9", OutputPath: "out"}
+		_, err := newTestService(legacyTagGitLab(), &mockDownloader{}, &mockFS{}).DownloadRelease(req)
+		if err == nil || !strings.Contains(err.Error(), "failed to get release: HTTP 404") {
+			t.Fatalf("expected release error, got %v", err)
+		}
+	})
+
+	t.Run("always", func(t *testing.T) {
+		gl := &mockGitLab{project: &domain.Project{ID: 7}}
+		dl := &mockDownloader{}
+		req := domain.DownloadRequest{ProjectName: "g/p", ReleaseTag: "main", OutputPath: "out", RepositoryArchive: domain.RepositoryArchiveAlways}
+		if _, err := newTestService(gl, dl, &mockFS{}).DownloadRelease(req); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if gl.lastTag != "" || !strings.HasSuffix(dl.lastURL, "/archive.zip?sha=main") {
+			t.Fatalf("expected archive without release lookup, got tag %q url %s", gl.lastTag, dl.lastURL)
+		}
+	})
+}
+
+func TestGetRelease_NoRepositoryArchiveFallback(t *testing.T) {
+	if _, err := newTestService(legacyTagGitLab(), nil, nil).GetRelease("g/p", "v0.9"); err == nil {
+		t.Fatalf("expected release show to fail for a tag without release")
+	}
+}
@@ -0,0 +1,119 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"hufschlaeger.net/gitlab-downloader/internal/core/domain"
+	"hufschlaeger.net/gitlab-downloader/internal/core/ports"
+)
+
+// validatorSuffix names the sidecar file holding the ETag (or Last-Modified)
+// of an interrupted download, sent as If-Range when resuming.
+const validatorSuffix = ".etag"
+
+func validatorPath(path string) string {
+	return path + validatorSuffix
+}
+
+// partSuffix names the temporary file a download is written to before it is
+// renamed to the output path. Failed downloads kept for resuming stay there.
+const partSuffix = ".part"
+
+func partPath(path string) string {
+	return path + partSuffix
+}
+
+// resumeDownload continues the partial temporary file at path. It reports false without
+// error when there is nothing to resume, no validator guarding the range or
+// the server does not honor it, in which case the caller falls back to a
+// full download.
+func (s *ReleaseService) resumeDownload(url, path string, tap *downloadTap) (bool, error) {
+	// Without If-Range, newer content could be appended to an older prefix
+	ifRange := s.readValidator(path)
+	if ifRange == "" {
+		return false, nil
+	}
+
+	file, offset, err := s.filesystem.AppendFile(path)
+	if err != nil {
+		return false, fmt.Errorf("failed to open output file: %w", err)
+	}
+	if offset == 0 {
+		_ = file.Close()
+		return false, nil
+	}
+
+	if err := s.hashExisting(path, tap); err != nil {
+		_ = file.Close()
+		return false, err
+	}
+
+	_, err = s.downloader.DownloadRange(url, offset, ifRange, io.MultiWriter(file, tap), nil)
+	closeErr := file.Close()
+	if errors.Is(err, ports.ErrRangeNotHonored) {
+		return false, nil
+	}
+	if closeErr != nil && err == nil {
+		return false, s.discardPartial(path, closeErr)
+	}
+	if err != nil {
+		return false, fmt.Errorf("download failed: %w", err)
+	}
+
+	_ = s.filesystem.Remove(validatorPath(path))
+	return true, nil
+}
+
+// hashExisting feeds the already downloaded prefix into the tap so the final
+// size and digests cover the whole file.
+func (s *ReleaseService) hashExisting(path string, tap *downloadTap) error {
+	file, err := s.filesystem.OpenFile(path)
+	if err != nil {
+		return fmt.Errorf("failed to read %s: %w", path, err)
+	}
+	defer func(file io.ReadCloser) {
+		_ = file.Close()
+	}(file)
+
+	if _, err := io.Copy(tap, file); err != nil {
+		return fmt.Errorf("failed to read %s: %w", path, err)
+	}
+	return nil
+}
+
+func (s *ReleaseService) readValidator(path string) string {
+	file, err := s.filesystem.OpenFile(validatorPath(path))
+	if err != nil {
+		return ""
+	}
+	defer func(file io.ReadCloser) {
+		_ = file.Close()
+	}(file)
+
+	data, err := io.ReadAll(file)
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(data))
+}
+
+// saveValidator stores the validator of a download that may be resumed. A
+// response without one removes any older validator, so the next run starts
+// over instead of resuming unguarded.
+func (s *ReleaseService) saveValidator(path string, info *domain.DownloadInfo) {
+	if info.Validator == "" {
+		_ = s.filesystem.Remove(validatorPath(path))
+		return
+	}
+	_ = s.filesystem.WriteFile(validatorPath(path), []byte(info.Validator+"\n"))
+}
+
+// removeValidator deletes the validator stored next to path, if there is one.
+func (s *ReleaseService) removeValidator(path string) {
+	if ok, err := s.filesystem.Exists(validatorPath(path)); ok || err != nil {
+		_ = s.filesystem.Remove(validatorPath(path))
+	}
+}
@@ -0,0 +1,171 @@
+package services
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"hufschlaeger.net/gitlab-downloader/internal/core/domain"
+)
+
+func resumeRelease() *mockGitLab {
+	return &mockGitLab{release: &domain.Release{ProjectID: 1, Tag: "v1", Assets: domain.Assets{Links: []domain.Link{{URL: "https://example.com/app.zip"}}}}}
+}
+
+func TestDownloadRelease_ContinueResumesPartialFile(t *testing.T) {
+	fs := &mockFS{}
+	_ = fs.WriteFile("out.zip.part", []byte("DA"))
+	_ = fs.WriteFile("out.zip.part.etag", []byte(`"abc"`+"\n"))
+	dl := &mockDownloader{}
+	service := newTestService(resumeRelease(), dl, fs)
+
+	req := domain.DownloadRequest{ProjectName: "p", ReleaseTag: "v1", OutputPath: "out.zip", Continue: true, SHA256: dataSHA256}
+	if _, err := service.DownloadRelease(req); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if dl.lastOffset != 2 || dl.lastIfRange != `"abc"` {
+		t.Fatalf("expected range request from offset 2 with If-Range, got offset=%d if-range=%q", dl.lastOffset, dl.lastIfRange)
+	}
+	if got := fs.files["out.zip"].String(); got != "DATA" {
+		t.Fatalf("expected resumed content DATA, got %q", got)
+	}
+	if _, ok := fs.files["out.zip.part.etag"]; ok {
+		t.Fatalf("expected validator sidecar to be removed after success")
+	}
+}
+
+func TestDownloadRelease_ContinueFallsBackToFullDownload(t *testing.T) {
+	fs := &mockFS{}
+	_ = fs.WriteFile("out.zip.part", []byte("XX"))
+	dl := &mockDownloader{rangeIgnored: true}
+	service := newTestService(resumeRelease(), dl, fs)
+
+	req := domain.DownloadRequest{ProjectName: "p", ReleaseTag: "v1", OutputPath: "out.zip", Continue: true, SHA256: dataSHA256}
+	if _, err := service.DownloadRelease(req); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if dl.lastOffset != 0 {
+		t.Fatalf("expected full download, got offset %d", dl.lastOffset)
+	}
+	if got := fs.files["out.zip"].String(); got != "DATA" {
+		t.Fatalf("expected file to be rewritten, got %q", got)
+	}
+}
+
+func TestDownloadRelease_ContinueWithoutPartialFile(t *testing.T) {
+	fs := &mockFS{}
+	dl := &mockDownloader{}
+	service := newTestService(resumeRelease(), dl, fs)
+
+	req := domain.DownloadRequest{ProjectName: "p", ReleaseTag: "v1", OutputPath: "out.zip", Continue: true}
+	if _, err := service.DownloadRelease(req); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if dl.lastOffset != 0 || fs.files["out.zip"].String() != "DATA" {
+		t.Fatalf("expected plain download, got offset=%d", dl.lastOffset)
+	}
+}
+
+func TestDownloadRelease_ContinueStoresValidatorOnFailure(t *testing.T) {
+	fs := &mockFS{}
+	dl := &mockDownloader{downloadErr: errors.New("connection reset"), validator: `"etag-1"`}
+	service := newTestService(resumeRelease(), dl, fs)
+
+	req := domain.DownloadRequest{ProjectName: "p", ReleaseTag: "v1", OutputPath: "out.zip", Continue: true}
+	_, err := service.DownloadRelease(req)
+	if err == nil || !strings.Contains(err.Error(), "download failed") {
+		t.Fatalf("expected download failure, got %v", err)
+	}
+	sidecar, ok := fs.files["out.zip.part.etag"]
+	if !ok || strings.TrimSpace(sidecar.String()) != `"etag-1"` {
+		t.Fatalf("expected validator to be stored for the next run")
+	}
+	if _, ok := fs.files["out.zip.part"]; !ok {
+		t.Fatalf("expected partial file to be kept for the next run")
+	}
+	if _, ok := fs.files["out.zip"]; ok {
+		t.Fatalf("expected no file at the output path")
+	}
+}
+
+func TestDownloadRelease_ContinueStoresValidatorBeforeContent(t *testing.T) {
+	fs := &mockFS{}
+	dl := &mockDownloader{validator: `"etag-1"`}
+	// A killed process never reaches the error path, so the sidecar must
+	// exist before the first byte is written
+	dl.beforeWrite = func() {
+		if sidecar, ok := fs.files["out.zip.part.etag"]; !ok || strings.TrimSpace(sidecar.String()) != `"etag-1"` {
+			t.Fatalf("expected validator to be stored before the content")
+		}
+	}
+	service := newTestService(resumeRelease(), dl, fs)
+
+	req := domain.DownloadRequest{ProjectName: "p", ReleaseTag: "v1", OutputPath: "out.zip", Continue: true}
+	if _, err := service.DownloadRelease(req); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
+
+func TestDownloadRelease_ContinueRestartsWithoutValidator(t *testing.T) {
+	fs := &mockFS{}
+	_ = fs.WriteFile("out.zip.part", []byte("XX"))
+	dl := &mockDownloader{}
+	service := newTestService(resumeRelease(), dl, fs)
+
+	req := domain.DownloadRequest{ProjectName: "p", ReleaseTag: "v1", OutputPath: "out.zip", Continue: true}
+	if _, err := service.DownloadRelease(req); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if dl.lastOffset != 0 || dl.downloads != 1 || fs.files["out.zip"].String() != "DATA" {
+		t.Fatalf("expected a full download instead of an unguarded resume, got offset=%d downloads=%d", dl.lastOffset, dl.downloads)
+	}
+}
+
+func TestDownloadRelease_RestartsInterruptedDownloadWithoutValidator(t *testing.T) {
+	fs := &mockFS{}
+	dl := &mockDownloader{interrupted: "XX"}
+	service := newTestService(resumeRelease(), dl, fs)
+
+	req := domain.DownloadRequest{ProjectName: "p", ReleaseTag: "v1", OutputPath: "out.zip", SHA256: dataSHA256}
+	res, err := service.DownloadRelease(req)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got := fs.files["out.zip"].String(); got != "DATA" {
+		t.Fatalf("expected restarted content DATA, got %q", got)
+	}
+	if res.Size != 4 {
+		t.Fatalf("expected size of the restarted download, got %d", res.Size)
+	}
+}
+
+func TestDownloadRelease_RemovesStaleValidator(t *testing.T) {
+	fs := &mockFS{}
+	_ = fs.WriteFile("out.zip.part.etag", []byte(`"old"`+"\n"))
+	service := newTestService(resumeRelease(), &mockDownloader{}, fs)
+
+	req := domain.DownloadRequest{ProjectName: "p", ReleaseTag: "v1", OutputPath: "out.zip"}
+	if _, err := service.DownloadRelease(req); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, ok := fs.files["out.zip.part.etag"]; ok {
+		t.Fatalf("expected stale validator sidecar to be removed")
+	}
+}
+
+func TestDownloadRelease_ChecksumMismatchRemovesValidator(t *testing.T) {
+	fs := &mockFS{}
+	_ = fs.WriteFile("out.zip.part.etag", []byte(`"old"`+"\n"))
+	dl := &mockDownloader{content: map[string]string{"https://example.com/app.zip": "BAD!"}}
+	service := newTestService(resumeRelease(), dl, fs)
+
+	req := domain.DownloadRequest{ProjectName: "p", ReleaseTag: "v1", OutputPath: "out.zip", SHA256: dataSHA256}
+	if _, err := service.DownloadRelease(req); err == nil || !strings.Contains(err.Error(), "checksum mismatch") {
+		t.Fatalf("expected checksum mismatch, got %v", err)
+	}
+	for _, name := range []string{"out.zip.part", "out.zip.part.etag"} {
+		if _, ok := fs.files[name]; ok {
+			t.Fatalf("expected %s to be removed", name)
+		}
+	}
+}
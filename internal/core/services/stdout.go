@@ -0,0 +1,42 @@
+package services
+
+import (
+	"fmt"
+	"io"
+
+	"hufschlaeger.net/gitlab-downloader/internal/core/domain"
+	"hufschlaeger.net/gitlab-downloader/internal/core/ports"
+)
+
+// WithStdout sets the writer used for requests with domain.StdoutPath as
+// output path.
+func (s *ReleaseService) WithStdout(stdout io.Writer) *ReleaseService {
+	s.stdout = stdout
+	return s
+}
+
+// writeToStdout streams the download of result.URL to the stdout writer
+// without touching the file system or the cache. Written data cannot be
+// taken back, so a checksum mismatch is only reported after the transfer;
+// consumers have to check the exit status.
+func (s *ReleaseService) writeToStdout(result *domain.AssetResult, expected *domain.Checksum) error {
+	if s.stdout == nil {
+		return fmt.Errorf("writing to stdout is not available")
+	}
+	if s.offline {
+		return fmt.Errorf("cannot write to stdout: %w", ports.ErrOffline)
+	}
+
+	tap := newDownloadTap(expected)
+	if _, err := s.downloader.DownloadRange(result.URL, 0, "", io.MultiWriter(s.stdout, tap), nil); err != nil {
+		return fmt.Errorf("download failed: %w", err)
+	}
+	if tap.verifier != nil {
+		if err := tap.verifier.verify(); err != nil {
+			return err
+		}
+	}
+
+	result.Size, result.SHA256 = tap.size, tap.sum()
+	return nil
+}
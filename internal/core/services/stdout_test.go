@@ -0,0 +1,63 @@
+package services
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+
+	"hufschlaeger.net/gitlab-downloader/internal/core/domain"
+	"hufschlaeger.net/gitlab-downloader/internal/core/ports"
+)
+
+func TestDownloadRelease_Stdout(t *testing.T) {
+	var stdout bytes.Buffer
+	fs := &mockFS{}
+	service := newTestService(resumeRelease(), &mockDownloader{}, fs).WithStdout(&stdout)
+
+	result, err := service.DownloadRelease(domain.DownloadRequest{ProjectName: "p", ReleaseTag: "v1", OutputPath: "-", SHA256: dataSHA256})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if stdout.String() != "DATA" || result.Path != "-" || result.SHA256 != dataSHA256 {
+		t.Fatalf("expected download on stdout, got %q, %+v", stdout.String(), result)
+	}
+	if len(fs.files) != 0 || fs.lastDir != "" {
+		t.Fatalf("expected no file system access, got files=%v dir=%q", fs.files, fs.lastDir)
+	}
+}
+
+func TestDownloadRelease_StdoutErrors(t *testing.T) {
+	var stdout bytes.Buffer
+	wrongSum := strings.Repeat("0", 64)
+
+	for _, tc := range []struct {
+		name    string
+		service *ReleaseService
+		req     domain.DownloadRequest
+		want    string
+	}{
+		{"checksum mismatch", newTestService(resumeRelease(), &mockDownloader{}, &mockFS{}).WithStdout(&stdout),
+			domain.DownloadRequest{SHA256: wrongSum}, "checksum mismatch"},
+		{"continue", newTestService(resumeRelease(), &mockDownloader{}, &mockFS{}).WithStdout(&stdout),
+			domain.DownloadRequest{Continue: true}, "cannot be resumed or extracted"},
+		{"offline", newTestService(resumeRelease(), &mockDownloader{}, &mockFS{}).WithStdout(&stdout).WithOffline(true),
+			domain.DownloadRequest{}, ports.ErrOffline.Error()},
+		{"no writer", newTestService(resumeRelease(), &mockDownloader{}, &mockFS{}),
+			domain.DownloadRequest{}, "not available"},
+		{"download failure", newTestService(resumeRelease(), &mockDownloader{downloadErr: errors.New("net")}, &mockFS{}).WithStdout(&stdout),
+			domain.DownloadRequest{}, "download failed"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			tc.req.ProjectName, tc.req.ReleaseTag, tc.req.OutputPath = "p", "v1", "-"
+			if _, err := tc.service.DownloadRelease(tc.req); err == nil || !strings.Contains(err.Error(), tc.want) {
+				t.Fatalf("expected error containing %q, got %v", tc.want, err)
+			}
+		})
+	}
+
+	service := newTestService(resumeRelease(), &mockDownloader{}, &mockFS{}).WithStdout(&stdout)
+	if _, err := service.DownloadAllAssets(domain.DownloadRequest{ProjectName: "p", ReleaseTag: "v1", OutputPath: "-"}); err == nil {
+		t.Fatalf("expected error for several assets on stdout")
+	}
+}
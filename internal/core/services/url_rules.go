@@ -0,0 +1,121 @@
+package services
+
+import (
+	"fmt"
+	"path"
+	"strconv"
+	"strings"
+
+	"hufschlaeger.net/gitlab-downloader/internal/core/domain"
+)
+
+// WithRules configures project-specific URL resolution rules. The first rule
+// whose project pattern matches wins; projects without a matching rule use
+// the generic resolution.
+func (s *ReleaseService) WithRules(rules []domain.ResolutionRule) *ReleaseService {
+	s.rules = rules
+	return s
+}
+
+// matchRule returns the first rule whose glob matches the project path,
+// compared case-insensitively.
+func (s *ReleaseService) matchRule(projectName string) *domain.ResolutionRule {
+	projectLower := strings.ToLower(projectName)
+	for i := range s.rules {
+		if ok, _ := path.Match(strings.ToLower(s.rules[i].Project), projectLower); ok {
+			return &s.rules[i]
+		}
+	}
+	return nil
+}
+
+func (s *ReleaseService) applyRule(rule *domain.ResolutionRule, host, projectName string, release *domain.Release, extIndex int) string {
+	switch rule.Strategy {
+	case domain.StrategyGenericPackage:
+		return s.buildGenericPackageURL(host, projectName, release, rule.Package, rule.File)
+	case domain.StrategyBlobToRaw:
+		return s.buildBlobRawURL(host, projectName, release, rule.Asset)
+	case domain.StrategyArtifactRewrite:
+		if link := findLink(release, rule.Asset); link != nil {
+			return s.rewriteArtifactURL(host, projectName, release, link.URL)
+		}
+		return ""
+	case domain.StrategySourceArchive:
+		return findSourceURL(release, rule.Format, extIndex)
+	case domain.StrategyTemplate:
+		return expandTemplate(rule.Template, host, projectName, release)
+	}
+	return ""
+}
+
+func (s *ReleaseService) buildGenericPackageURL(host, projectName string, release *domain.Release, pkg, file string) string {
+	return fmt.Sprintf("%s/api/v4/projects/%d/packages/generic/%s/%s/%s",
+		host, release.ProjectID,
+		expandTemplate(pkg, host, projectName, release),
+		release.Tag,
+		expandTemplate(file, host, projectName, release))
+}
+
+// buildBlobRawURL converts a repository blob link ("/-/blob/<ref>/<path>")
+// into the raw file API URL.
+func (s *ReleaseService) buildBlobRawURL(host, projectName string, release *domain.Release, assetPattern string) string {
+	for _, link := range release.Assets.Links {
+		if assetPattern != "" && !matchFold(assetPattern, link.Name) {
+			continue
+		}
+
+		prefix := fmt.Sprintf("%s/%s/-/blob/", host, projectName)
+		if len(link.URL) < len(prefix) || !strings.EqualFold(link.URL[:len(prefix)], prefix) {
+			continue
+		}
+
+		parts := strings.SplitN(link.URL[len(prefix):], "/", 2)
+		if len(parts) == 2 {
+			return fmt.Sprintf("%s/api/v4/projects/%d/repository/files/%s/raw?ref=%s",
+				host, release.ProjectID, parts[1], parts[0])
+		}
+	}
+	return ""
+}
+
+// findLink returns the first link whose name matches the glob, or the first
+// link if no pattern is given.
+func findLink(release *domain.Release, pattern string) *domain.Link {
+	for i, link := range release.Assets.Links {
+		if pattern == "" || matchFold(pattern, link.Name) || matchFold(pattern, urlBaseName(link.URL)) {
+			return &release.Assets.Links[i]
+		}
+	}
+	return nil
+}
+
+// findSourceURL selects a source archive by format, falling back to the
+// -ext index.
+func findSourceURL(release *domain.Release, format string, extIndex int) string {
+	if format != "" {
+		for _, source := range release.Assets.Sources {
+			if strings.EqualFold(source.Format, format) {
+				return source.URL
+			}
+		}
+		return ""
+	}
+	if extIndex < len(release.Assets.Sources) {
+		return release.Assets.Sources[extIndex].URL
+	}
+	return ""
+}
+
+func expandTemplate(template, host, projectName string, release *domain.Release) string {
+	return strings.NewReplacer(
+		"{host}", host,
+		"{projectID}", strconv.Itoa(release.ProjectID),
+		"{project}", projectName,
+		"{tag}", release.Tag,
+	).Replace(template)
+}
+
+func matchFold(pattern, name string) bool {
+	ok, _ := path.Match(strings.ToLower(pattern), strings.ToLower(name))
+	return ok
+}
@@ -0,0 +1,80 @@
+package services
+
+import (
+	"testing"
+
+	"hufschlaeger.net/gitlab-downloader/internal/core/domain"
+)
+
+func rulesRelease() *domain.Release {
+	return &domain.Release{
+		ProjectID: 7,
+		Tag:       "v3.1.0",
+		Assets: domain.Assets{
+			Links: []domain.Link{
+				{Name: "docs", URL: "https://gitlab.example.com/team/app/-/uploads/abc/docs.pdf"},
+				{Name: "build", URL: "https://gitlab.example.com/team/app/-/jobs/99/artifacts/download"},
+			},
+			Sources: []domain.Source{
+				{Format: "zip", URL: "https://gitlab.example.com/src.zip"},
+				{Format: "tar.gz", URL: "https://gitlab.example.com/src.tar.gz"},
+			},
+		},
+	}
+}
+
+func TestDetermineDownloadURL_Rules(t *testing.T) {
+	cases := []struct {
+		name string
+		rule domain.ResolutionRule
+		want string
+	}{
+		{
+			"generic package",
+			domain.ResolutionRule{Project: "team/*", Strategy: domain.StrategyGenericPackage, Package: "app", File: "app-{tag}.tar.gz"},
+			"https://gitlab.example.com/api/v4/projects/7/packages/generic/app/v3.1.0/app-v3.1.0.tar.gz",
+		},
+		{
+			"artifact rewrite by asset",
+			domain.ResolutionRule{Project: "team/app", Strategy: domain.StrategyArtifactRewrite, Asset: "build"},
+			"https://gitlab.example.com/api/v4/projects/7/jobs/99/artifacts",
+		},
+		{
+			"source archive by format",
+			domain.ResolutionRule{Project: "TEAM/APP", Strategy: domain.StrategySourceArchive, Format: "tar.gz"},
+			"https://gitlab.example.com/src.tar.gz",
+		},
+		{
+			"template",
+			domain.ResolutionRule{Project: "*/app", Strategy: domain.StrategyTemplate, Template: "{host}/{project}/-/raw/{tag}/dist/app?id={projectID}"},
+			"https://gitlab.example.com/team/app/-/raw/v3.1.0/dist/app?id=7",
+		},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			service := newTestService(&mockGitLab{}, nil, nil).WithRules([]domain.ResolutionRule{tc.rule})
+			if got := service.determineDownloadURL("team/app", rulesRelease(), 0); got != tc.want {
+				t.Fatalf("expected %q, got %q", tc.want, got)
+			}
+		})
+	}
+}
+
+func TestDetermineDownloadURL_FirstMatchingRuleWins(t *testing.T) {
+	service := newTestService(&mockGitLab{}, nil, nil).WithRules([]domain.ResolutionRule{
+		{Project: "other/*", Strategy: domain.StrategyTemplate, Template: "wrong"},
+		{Project: "team/*", Strategy: domain.StrategySourceArchive},
+		{Project: "*", Strategy: domain.StrategyTemplate, Template: "fallback"},
+	})
+	if got := service.determineDownloadURL("team/app", rulesRelease(), 1); got != "https://gitlab.example.com/src.tar.gz" {
+		t.Fatalf("unexpected url %q", got)
+	}
+}
+
+func TestDetermineDownloadURL_NoRuleUsesGeneric(t *testing.T) {
+	service := newTestService(&mockGitLab{}, nil, nil).WithRules(dimagRules)
+	// first link is an upload, so the generic resolution picks the source by index
+	if got := service.determineDownloadURL("team/app", rulesRelease(), 0); got != "https://gitlab.example.com/src.zip" {
+		t.Fatalf("unexpected url %q", got)
+	}
+}
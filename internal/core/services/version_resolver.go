@@ -0,0 +1,306 @@
+package services
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"hufschlaeger.net/gitlab-downloader/internal/core/domain"
+)
+
+const (
+	ReleaseLatest       = "latest"
+	ReleaseLatestStable = "latest-stable"
+)
+
+// version is a parsed semantic version (MAJOR.MINOR.PATCH[-PRERELEASE][+BUILD]).
+type version struct {
+	major, minor, patch int
+	prerelease          string
+}
+
+// parseVersion parses a tag as semver. A leading "v" is tolerated and missing
+// minor/patch components default to zero, so "v2" and "2.3" are accepted.
+func parseVersion(tag string) (version, bool) {
+	s := strings.TrimPrefix(strings.TrimPrefix(strings.TrimSpace(tag), "v"), "V")
+	if s == "" {
+		return version{}, false
+	}
+
+	if i := strings.IndexByte(s, '+'); i >= 0 {
+		s = s[:i]
+	}
+
+	var v version
+	if i := strings.IndexByte(s, '-'); i >= 0 {
+		v.prerelease = s[i+1:]
+		s = s[:i]
+		if v.prerelease == "" {
+			return version{}, false
+		}
+	}
+
+	parts := strings.Split(s, ".")
+	if len(parts) > 3 {
+		return version{}, false
+	}
+
+	nums := [3]int{}
+	for i, part := range parts {
+		n, err := strconv.Atoi(part)
+		if err != nil || n < 0 {
+			return version{}, false
+		}
+		nums[i] = n
+	}
+	v.major, v.minor, v.patch = nums[0], nums[1], nums[2]
+
+	return v, true
+}
+
+// compare returns -1, 0 or 1. Prerelease identifiers are ordered according to
+// the semver precedence rules.
+func (v version) compare(o version) int {
+	if c := compareInt(v.major, o.major); c != 0 {
+		return c
+	}
+	if c := compareInt(v.minor, o.minor); c != 0 {
+		return c
+	}
+	if c := compareInt(v.patch, o.patch); c != 0 {
+		return c
+	}
+
+	switch {
+	case v.prerelease == o.prerelease:
+		return 0
+	case v.prerelease == "":
+		return 1
+	case o.prerelease == "":
+		return -1
+	}
+
+	a := strings.Split(v.prerelease, ".")
+	b := strings.Split(o.prerelease, ".")
+	for i := 0; i < len(a) && i < len(b); i++ {
+		an, aErr := strconv.Atoi(a[i])
+		bn, bErr := strconv.Atoi(b[i])
+		switch {
+		case aErr == nil && bErr == nil:
+			if c := compareInt(an, bn); c != 0 {
+				return c
+			}
+		case aErr == nil:
+			return -1
+		case bErr == nil:
+			return 1
+		default:
+			if c := strings.Compare(a[i], b[i]); c != 0 {
+				return c
+			}
+		}
+	}
+
+	return compareInt(len(a), len(b))
+}
+
+func compareInt(a, b int) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	}
+	return 0
+}
+
+// comparator is a single "<op><version>" term of a constraint.
+type comparator struct {
+	op string
+	v  version
+}
+
+func (c comparator) matches(v version) bool {
+	cmp := v.compare(c.v)
+	switch c.op {
+	case "=":
+		return cmp == 0
+	case "!=":
+		return cmp != 0
+	case ">":
+		return cmp > 0
+	case ">=":
+		return cmp >= 0
+	case "<":
+		return cmp < 0
+	case "<=":
+		return cmp <= 0
+	}
+	return false
+}
+
+// constraint is a set of comparators which must all match. Pre-releases
+// only match if a comparator names a pre-release of the same
+// MAJOR.MINOR.PATCH, so ">=2.0.0-rc.1" picks 2.0.0-rc.2 but never 2.1.0-rc.1.
+type constraint []comparator
+
+func (c constraint) matches(v version) bool {
+	if v.prerelease != "" && !c.allowsPrerelease(v) {
+		return false
+	}
+	for _, cmp := range c {
+		if !cmp.matches(v) {
+			return false
+		}
+	}
+	return true
+}
+
+// allowsPrerelease reports whether a comparator names a pre-release of the
+// same MAJOR.MINOR.PATCH as v.
+func (c constraint) allowsPrerelease(v version) bool {
+	for _, cmp := range c {
+		if cmp.v.prerelease != "" && cmp.v.major == v.major && cmp.v.minor == v.minor && cmp.v.patch == v.patch {
+			return true
+		}
+	}
+	return false
+}
+
+// isConstraint reports whether a release spec should be resolved against the
+// release list instead of being used as an exact tag.
+func isConstraint(spec string) bool {
+	spec = strings.TrimSpace(spec)
+	if spec == "" {
+		return false
+	}
+	return strings.ContainsAny(spec[:1], "^~<>=!") || strings.Contains(spec, ",")
+}
+
+// parseConstraint parses comma or space separated terms like "^2.3",
+// "~1.2.0" or ">=1.4,<2".
+func parseConstraint(spec string) (constraint, error) {
+	fields := strings.FieldsFunc(spec, func(r rune) bool { return r == ',' || r == ' ' })
+	if len(fields) == 0 {
+		return nil, fmt.Errorf("empty version constraint")
+	}
+
+	var c constraint
+	for _, field := range fields {
+		terms, err := parseTerm(field)
+		if err != nil {
+			return nil, fmt.Errorf("invalid version constraint %q: %w", spec, err)
+		}
+		c = append(c, terms...)
+	}
+	return c, nil
+}
+
+func parseTerm(term string) ([]comparator, error) {
+	op := ""
+	for _, candidate := range []string{">=", "<=", "!=", ">", "<", "=", "^", "~"} {
+		if strings.HasPrefix(term, candidate) {
+			op = candidate
+			break
+		}
+	}
+
+	raw := strings.TrimPrefix(term, op)
+	v, ok := parseVersion(raw)
+	if !ok {
+		return nil, fmt.Errorf("%q is not a version", raw)
+	}
+	precision := strings.Count(strings.TrimLeft(raw, "vV"), ".") + 1
+
+	switch op {
+	case "^":
+		upper := version{major: v.major + 1}
+		if v.major == 0 && precision > 1 {
+			upper = version{minor: v.minor + 1}
+			if v.minor == 0 && precision > 2 {
+				upper = version{patch: v.patch + 1}
+			}
+		}
+		return []comparator{{">=", v}, {"<", upper}}, nil
+	case "~":
+		upper := version{major: v.major, minor: v.minor + 1}
+		if precision == 1 {
+			upper = version{major: v.major + 1}
+		}
+		return []comparator{{">=", v}, {"<", upper}}, nil
+	case "":
+		return []comparator{{"=", v}}, nil
+	}
+
+	return []comparator{{op, v}}, nil
+}
+
+// resolveReleaseTag turns a release spec into a concrete tag. Exact tags are
+// returned unchanged; "latest", "latest-stable" and version constraints are
+// resolved by listing the project's releases and picking the highest match.
+func (s *ReleaseService) resolveReleaseTag(projectID int, spec string) (string, error) {
+	spec = strings.TrimSpace(spec)
+	lower := strings.ToLower(spec)
+
+	var match func(version) bool
+	switch {
+	case lower == ReleaseLatest:
+		match = func(version) bool { return true }
+	case lower == ReleaseLatestStable:
+		match = func(v version) bool { return v.prerelease == "" }
+	case isConstraint(spec):
+		c, err := parseConstraint(spec)
+		if err != nil {
+			return "", err
+		}
+		match = c.matches
+	default:
+		return spec, nil
+	}
+
+	releases, err := s.allReleases(projectID)
+	if err != nil {
+		return "", fmt.Errorf("failed to list releases: %w", err)
+	}
+
+	tag, ok := highestMatchingTag(releases, match)
+	if !ok {
+		return "", fmt.Errorf("no release matches %q", spec)
+	}
+	return tag, nil
+}
+
+func highestMatchingTag(releases []domain.Release, match func(version) bool) (string, bool) {
+	type candidate struct {
+		tag string
+		v   version
+	}
+
+	var candidates []candidate
+	for _, release := range releases {
+		v, ok := parseVersion(release.Tag)
+		if ok && match(v) {
+			candidates = append(candidates, candidate{tag: release.Tag, v: v})
+		}
+	}
+	if len(candidates) == 0 {
+		return "", false
+	}
+
+	sort.SliceStable(candidates, func(i, j int) bool {
+		return candidates[i].v.compare(candidates[j].v) > 0
+	})
+	return candidates[0].tag, true
+}
+
+// allReleases returns every release of a project. The highest matching
+// version can be on any page, so the walk never stops early.
+func (s *ReleaseService) allReleases(projectID int) ([]domain.Release, error) {
+	var releases []domain.Release
+	err := s.gitlab.ListReleases(projectID, func(release domain.Release) bool {
+		releases = append(releases, release)
+		return true
+	})
+	return releases, err
+}
@@ -0,0 +1,173 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+
+	"hufschlaeger.net/gitlab-downloader/internal/core/domain"
+)
+
+func releasesWithTags(tags ...string) []domain.Release {
+	releases := make([]domain.Release, 0, len(tags))
+	for _, tag := range tags {
+		releases = append(releases, domain.Release{ProjectID: 1, Tag: tag})
+	}
+	return releases
+}
+
+func TestParseVersion(t *testing.T) {
+	cases := []struct {
+		in   string
+		want version
+		ok   bool
+	}{
+		{"1.2.3", version{major: 1, minor: 2, patch: 3}, true},
+		{"v1.2.3", version{major: 1, minor: 2, patch: 3}, true},
+		{"v2", version{major: 2}, true},
+		{"2.3", version{major: 2, minor: 3}, true},
+		{"1.0.0-rc.1+build5", version{major: 1, prerelease: "rc.1"}, true},
+		{"release-1", version{}, false},
+		{"1.2.3.4", version{}, false},
+		{"", version{}, false},
+	}
+	for _, tc := range cases {
+		got, ok := parseVersion(tc.in)
+		if ok != tc.ok || got != tc.want {
+			t.Fatalf("parseVersion(%q) = %+v, %v; want %+v, %v", tc.in, got, ok, tc.want, tc.ok)
+		}
+	}
+}
+
+func TestVersionCompare_Prerelease(t *testing.T) {
+	ordered := []string{"1.0.0-alpha", "1.0.0-alpha.1", "1.0.0-alpha.beta", "1.0.0-beta.2", "1.0.0-beta.11", "1.0.0-rc.1", "1.0.0"}
+	for i := 0; i+1 < len(ordered); i++ {
+		a, _ := parseVersion(ordered[i])
+		b, _ := parseVersion(ordered[i+1])
+		if a.compare(b) >= 0 {
+			t.Fatalf("expected %s < %s", ordered[i], ordered[i+1])
+		}
+	}
+}
+
+func TestConstraintMatches(t *testing.T) {
+	cases := []struct {
+		constraint string
+		version    string
+		want       bool
+	}{
+		{"^2.3", "2.3.0", true},
+		{"^2.3", "2.9.1", true},
+		{"^2.3", "3.0.0", false},
+		{"^2.3", "2.2.9", false},
+		{"^0.3", "0.3.5", true},
+		{"^0.3", "0.4.0", false},
+		{"~1.2", "1.2.9", true},
+		{"~1.2", "1.3.0", false},
+		{">=1.4,<2", "1.4.0", true},
+		{">=1.4,<2", "1.9.9", true},
+		{">=1.4,<2", "2.0.0", false},
+		{">=1.4 <2", "1.3.0", false},
+		{"!=1.0.0", "1.0.0", false},
+		{"=v1.0.0", "1.0.0", true},
+	}
+	for _, tc := range cases {
+		c, err := parseConstraint(tc.constraint)
+		if err != nil {
+			t.Fatalf("parseConstraint(%q) failed: %v", tc.constraint, err)
+		}
+		v, _ := parseVersion(tc.version)
+		if got := c.matches(v); got != tc.want {
+			t.Fatalf("%q matches %q = %v, want %v", tc.constraint, tc.version, got, tc.want)
+		}
+	}
+}
+
+func TestParseConstraint_Invalid(t *testing.T) {
+	if _, err := parseConstraint(">=foo"); err == nil {
+		t.Fatalf("expected error for invalid constraint")
+	}
+}
+
+func TestResolveReleaseTag(t *testing.T) {
+	gl := &mockGitLab{releases: releasesWithTags("v1.3.0", "v1.4.2", "v2.3.1", "v2.4.0-rc.1", "nightly", "v1.10.0")}
+	service := newTestService(gl, nil, nil)
+
+	cases := map[string]string{
+		"latest":        "v2.4.0-rc.1",
+		"latest-stable": "v2.3.1",
+		"^2.3":          "v2.3.1",
+		">=1.4,<2":      "v1.10.0",
+		"~1.4":          "v1.4.2",
+		"=2.4.0-rc.1":   "v2.4.0-rc.1",
+		">=2.4.0-rc.1":  "v2.4.0-rc.1",
+		"^2.4.0-rc.1":   "v2.4.0-rc.1",
+		">=2.3.0-rc.1":  "v2.3.1",
+		"v1.3.0":        "v1.3.0",
+		"nightly":       "nightly",
+	}
+	for spec, want := range cases {
+		got, err := service.resolveReleaseTag(1, spec)
+		if err != nil {
+			t.Fatalf("resolveReleaseTag(%q) failed: %v", spec, err)
+		}
+		if got != want {
+			t.Fatalf("resolveReleaseTag(%q) = %q, want %q", spec, got, want)
+		}
+	}
+}
+
+func TestResolveReleaseTag_SeesEveryPage(t *testing.T) {
+	// Three pages of 100 releases; the matches for every spec are the
+	// oldest entries, on the last page
+	var tags []string
+	for i := 250; i > 0; i-- {
+		tags = append(tags, fmt.Sprintf("v2.0.%d-rc.1", i))
+	}
+	tags = append(tags, "v3.0.0", "v1.2.7")
+	gl := &mockGitLab{releases: releasesWithTags(tags...), pageSize: 100}
+	service := newTestService(gl, nil, nil)
+
+	for spec, want := range map[string]string{"latest": "v3.0.0", "latest-stable": "v3.0.0", "^1.2": "v1.2.7"} {
+		got, err := service.resolveReleaseTag(1, spec)
+		if err != nil || got != want {
+			t.Fatalf("resolveReleaseTag(%q) = %q, %v, want %q", spec, got, err, want)
+		}
+		if gl.pages != 3 || gl.visited != len(tags) {
+			t.Fatalf("resolveReleaseTag(%q) saw %d of %d releases on %d of 3 pages", spec, gl.visited, len(tags), gl.pages)
+		}
+	}
+}
+
+func TestResolveReleaseTag_Errors(t *testing.T) {
+	service := newTestService(&mockGitLab{releases: releasesWithTags("v1.0.0")}, nil, nil)
+	if _, err := service.resolveReleaseTag(1, "^2"); err == nil || !strings.Contains(err.Error(), "no release matches") {
+		t.Fatalf("expected no match error, got %v", err)
+	}
+
+	service = newTestService(&mockGitLab{listErr: errors.New("boom")}, nil, nil)
+	if _, err := service.resolveReleaseTag(1, "latest"); err == nil || !strings.Contains(err.Error(), "failed to list releases") {
+		t.Fatalf("expected list error, got %v", err)
+	}
+}
+
+func TestDownloadRelease_ResolvesLatest(t *testing.T) {
+	gl := &mockGitLab{
+		releases: releasesWithTags("v1.0.0", "v1.1.0"),
+		release: &domain.Release{
+			ProjectID: 1,
+			Tag:       "v1.1.0",
+			Assets:    domain.Assets{Links: []domain.Link{{URL: "https://example.com/app.zip"}}},
+		},
+	}
+	service := newTestService(gl, &mockDownloader{}, &mockFS{})
+
+	req := domain.DownloadRequest{ProjectName: "group/proj", ReleaseTag: "latest", OutputPath: "out.zip"}
+	if _, err := service.DownloadRelease(req); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if gl.lastTag != "v1.1.0" {
+		t.Fatalf("expected resolved tag v1.1.0, got %q", gl.lastTag)
+	}
+}